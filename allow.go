@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// readPatterns reads key globs from the file at name, one per line. Blank
+// lines and lines starting with '#' are ignored.
+func readPatterns(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %+q: %v", line, err)
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, sc.Err()
+}
+
+// matchAny returns whether key matches any of the given globs.
+func matchAny(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recordedKeys returns the sorted keys recorded in values.
+func recordedKeys(values ini.Recorder) []string {
+	doc := document(values)
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkAllowed returns an error naming every key in values that does not
+// match one of the allowed patterns.
+func checkAllowed(values ini.Recorder, allowed []string) error {
+	var extra []string
+	for _, k := range recordedKeys(values) {
+		if !matchAny(allowed, k) {
+			extra = append(extra, fmt.Sprintf("%+q", k))
+		}
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unexpected keys: %s", strings.Join(extra, ", "))
+}
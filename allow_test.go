@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestCheckAllowed(t *testing.T) {
+	const in = "[db]\nhost = x\nport = 1\n[log]\nlevel = debug\n"
+	tests := []struct {
+		name    string
+		allowed []string
+		want    string
+	}{
+		{"all allowed", []string{"db.*", "log.level"}, ""},
+		{"unexpected key", []string{"db.host", "log.*"}, `unexpected keys: "db.port"`},
+		{"several", []string{"db.host"}, `unexpected keys: "db.port", "log.level"`},
+		{"none allowed", nil, `unexpected keys: "db.host", "db.port", "log.level"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := readValues(t, &source{}, &valueParser{raw: true}, in)
+			err := checkAllowed(values, tt.allowed)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("checkAllowed(%q) = %q, want %q", tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// memberSep separates the path of an archive from the name of a member
+// in it in input names, as in "configs.zip!app/db.ini".
+const memberSep = "!"
+
+// isArchive returns whether path names an archive by its extension.
+func isArchive(path string) bool {
+	switch p := strings.ToLower(path); {
+	case strings.HasSuffix(p, ".zip"), strings.HasSuffix(p, ".tar"):
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+	default:
+		return false
+	}
+	return true
+}
+
+// splitMember returns the archive path and member name of path, if it
+// names a member of an archive.
+func splitMember(path string) (archive, name string, ok bool) {
+	i := strings.Index(path, memberSep)
+	if i <= 0 || !isArchive(path[:i]) {
+		return "", "", false
+	}
+	return path[:i], path[i+len(memberSep):], true
+}
+
+// expandArchives returns paths with each archive replaced by the names of
+// the files it contains, in order.
+func expandArchives(paths []string) ([]string, error) {
+	var expanded []string
+	for _, path := range paths {
+		if path == "-" || isURL(path) || !isArchive(path) {
+			expanded = append(expanded, path)
+			continue
+		}
+		var names []string
+		err := eachMember(path, func(name string, _ io.Reader) (bool, error) {
+			names = append(names, path+memberSep+name)
+			return true, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to read archive %v: %v", path, err)
+		}
+		expanded = append(expanded, names...)
+	}
+	return expanded, nil
+}
+
+// eachMember calls fn with the name and content of each file in the
+// archive named by path until fn returns false or an error.
+func eachMember(path string, fn func(name string, r io.Reader) (bool, error)) error {
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return err
+			}
+			more, err := fn(f.Name, r)
+			r.Close()
+			if err != nil || !more {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := decompress(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if more, err := fn(hdr.Name, tr); err != nil || !more {
+			return err
+		}
+	}
+}
+
+// archives keeps the archives that members have been opened from open, so
+// that opening each member of an archive in turn reads it once. Zip
+// members are opened from the archive's directory. Tar members are read
+// from a cursor that moves forward through the archive; a member before
+// the cursor, or one opened while another member of the same archive is
+// being read, is read from a new pass over the archive.
+var archives = &openArchives{zips: map[string]*zip.ReadCloser{}, tars: map[string]*tarCursor{}}
+
+type openArchives struct {
+	mu   sync.Mutex
+	zips map[string]*zip.ReadCloser
+	tars map[string]*tarCursor
+}
+
+// tarCursor is a tar archive being read from start to end.
+type tarCursor struct {
+	f    *os.File
+	tr   *tar.Reader
+	busy bool // Whether a member is being read from tr.
+}
+
+func openTar(path string) (*tarCursor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &tarCursor{f: f, tr: tar.NewReader(r)}, nil
+}
+
+// seek moves c to the regular file name, returning false if c reaches the
+// end of the archive first.
+func (c *tarCursor) seek(name string) (bool, error) {
+	for {
+		hdr, err := c.tr.Next()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if (hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA) && hdr.Name == name {
+			return true, nil
+		}
+	}
+}
+
+// openMember opens the member name of the archive named by path. The
+// member is read from the archive as it is read from the returned reader.
+func openMember(path, name string) (io.ReadCloser, error) {
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		return archives.openZip(path, name)
+	}
+	return archives.openTar(path, name)
+}
+
+func (a *openArchives) openZip(path, name string) (io.ReadCloser, error) {
+	a.mu.Lock()
+	zr, ok := a.zips[path]
+	if !ok {
+		var err error
+		if zr, err = zip.OpenReader(path); err != nil {
+			a.mu.Unlock()
+			return nil, err
+		}
+		a.zips[path] = zr
+	}
+	a.mu.Unlock()
+
+	for _, f := range zr.File {
+		if f.Name == name && !f.FileInfo().IsDir() {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("open %s: no such file in archive", path+memberSep+name)
+}
+
+func (a *openArchives) openTar(path, name string) (io.ReadCloser, error) {
+	a.mu.Lock()
+	c, cached := a.tars[path]
+	if cached && c.busy {
+		c, cached = nil, false
+	} else if cached {
+		c.busy = true
+	}
+	a.mu.Unlock()
+
+	found := false
+	if cached {
+		var err error
+		if found, err = c.seek(name); err != nil || !found {
+			a.drop(path, c)
+			cached = false
+		}
+	}
+	if !found {
+		var err error
+		if c, err = openTar(path); err != nil {
+			return nil, err
+		}
+		if found, err = c.seek(name); err != nil || !found {
+			c.f.Close()
+			if err == nil {
+				err = fmt.Errorf("open %s: no such file in archive", path+memberSep+name)
+			}
+			return nil, err
+		}
+		a.mu.Lock()
+		if _, ok := a.tars[path]; !ok {
+			c.busy, cached = true, true
+			a.tars[path] = c
+		}
+		a.mu.Unlock()
+	}
+	return &tarMember{a: a, path: path, c: c, cached: cached}, nil
+}
+
+// drop closes c and forgets it if it is the cursor kept for path.
+func (a *openArchives) drop(path string, c *tarCursor) {
+	a.mu.Lock()
+	if a.tars[path] == c {
+		delete(a.tars, path)
+	}
+	a.mu.Unlock()
+	c.f.Close()
+}
+
+// tarMember reads a member from a tarCursor. Closing it releases a kept
+// cursor for the next member, unless the member was not read to its end:
+// skipping the rest of a member may mean decompressing it, so the cursor
+// is closed instead.
+type tarMember struct {
+	a      *openArchives
+	path   string
+	c      *tarCursor
+	cached bool // Whether c is kept in a.
+	eof    bool
+}
+
+func (m *tarMember) Read(p []byte) (int, error) {
+	n, err := m.c.tr.Read(p)
+	if err == io.EOF {
+		m.eof = true
+	}
+	return n, err
+}
+
+func (m *tarMember) Close() error {
+	if !m.cached || !m.eof {
+		m.a.drop(m.path, m.c)
+		return nil
+	}
+	m.a.mu.Lock()
+	m.c.busy = false
+	m.a.mu.Unlock()
+	return nil
+}
+
+// decompress returns a reader of the content of r, decompressed if it is
+// compressed with gzip.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
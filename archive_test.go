@@ -0,0 +1,196 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+var archiveMembers = []struct{ name, text string }{
+	{"a.ini", "a = 1\n"},
+	{"dir/b.ini", "b = 2\n"},
+	{"c.ini", "c = 3\n"},
+}
+
+// writeArchives writes archiveMembers to a zip, a tar, and a gzipped tar
+// archive in dir and returns their paths.
+func writeArchives(t *testing.T, dir string) []string {
+	t.Helper()
+	var zbuf, tbuf, gbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	tw := tar.NewWriter(&tbuf)
+	for _, m := range archiveMembers {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(m.text))
+		tw.WriteHeader(&tar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.text)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(m.text))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(&gbuf)
+	gw.Write(tbuf.Bytes())
+	gw.Close()
+
+	var paths []string
+	for name, data := range map[string][]byte{"m.zip": zbuf.Bytes(), "m.tar": tbuf.Bytes(), "m.tar.gz": gbuf.Bytes()} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func readMember(t *testing.T, path, name string) string {
+	t.Helper()
+	rc, err := openMember(path, name)
+	if err != nil {
+		t.Fatalf("openMember(%s, %s) = %v", path, name, err)
+	}
+	defer rc.Close()
+	p, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading %s!%s: %v", path, name, err)
+	}
+	return string(p)
+}
+
+func TestOpenMember(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ini2json-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range writeArchives(t, dir) {
+		names, err := expandArchives([]string{path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != len(archiveMembers) {
+			t.Fatalf("expandArchives(%s) = %q", path, names)
+		}
+
+		// In order, then out of order, then concurrently with another
+		// member of the same archive.
+		for _, i := range []int{0, 1, 2, 1, 0, 2} {
+			m := archiveMembers[i]
+			if got := readMember(t, path, m.name); got != m.text {
+				t.Errorf("%s!%s = %q, want %q", path, m.name, got, m.text)
+			}
+		}
+		first, err := openMember(path, archiveMembers[0].name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readMember(t, path, archiveMembers[2].name); got != archiveMembers[2].text {
+			t.Errorf("%s!%s while another member is open = %q", path, archiveMembers[2].name, got)
+		}
+		p, _ := ioutil.ReadAll(first)
+		first.Close()
+		if string(p) != archiveMembers[0].text {
+			t.Errorf("%s!%s = %q, want %q", path, archiveMembers[0].name, p, archiveMembers[0].text)
+		}
+
+		if _, err := openMember(path, "missing.ini"); err == nil || !strings.Contains(err.Error(), "no such file") {
+			t.Errorf("openMember(%s, missing.ini) = %v, want no such file", path, err)
+		}
+	}
+}
+
+func TestOpenMemberSinglePass(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ini2json-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range writeArchives(t, dir) {
+		if strings.HasSuffix(path, ".zip") {
+			continue
+		}
+		readMember(t, path, archiveMembers[0].name)
+		c := archives.tars[path]
+		for _, m := range archiveMembers[1:] {
+			readMember(t, path, m.name)
+			if archives.tars[path] != c {
+				t.Errorf("%s!%s was read from a new pass over the archive", path, m.name)
+			}
+		}
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestLimitBytesMember(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ini2json-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A member that compresses well and is far larger than the limit.
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	w, err := zw.Create("big.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := []byte("k = " + strings.Repeat("0", 1<<10) + "\n")
+	for i := 0; i < 1<<16; i++ {
+		w.Write(line)
+	}
+	zw.Close()
+	path := filepath.Join(dir, "big.zip")
+	if err := ioutil.WriteFile(path, zbuf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := openMember(path, "big.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	cr := &countingReader{r: rc}
+	const limit = 1 << 16
+	err = limitBytes(limit)(ioutil.Discard, cr)
+	if err == nil || !strings.Contains(err.Error(), "larger than the limit") {
+		t.Errorf("limitBytes(%d) = %v, want a limit error", limit, err)
+	}
+	if cr.n > 2*limit {
+		t.Errorf("read %d bytes of the member to enforce a limit of %d", cr.n, limit)
+	}
+
+	src := &source{rd: &ini.Reader{Separator: ".", True: "true"}, limit: limit, detect: true}
+	err = src.read(newParsedValues(&valueParser{raw: true}), path+memberSep+"big.ini")
+	if err == nil || !strings.Contains(err.Error(), "larger than the limit") {
+		t.Errorf("read with -limit-bytes %d = %v, want a limit error", limit, err)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// msgpackEncoder encodes values as MessagePack, writing documents one
+// after another. Integers are written in the smallest form that holds
+// them and other numbers as 64-bit floats. Integers that do not fit in 64
+// bits and numbers outside the range of a float are rejected.
+type msgpackEncoder struct {
+	w io.Writer
+}
+
+func (e *msgpackEncoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := writeMsgpack(&buf, g); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// writeMsgpack writes the MessagePack encoding of the ordered value v.
+func writeMsgpack(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case *object:
+		writeMsgpackHead(buf, 0x80, 0xde, v.Len())
+		for _, k := range v.Keys() {
+			elem, _ := v.Get(k)
+			writeMsgpack(buf, k)
+			if err := writeMsgpack(buf, elem); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		writeMsgpackHead(buf, 0x90, 0xdc, len(v))
+		for _, elem := range v {
+			if err := writeMsgpack(buf, elem); err != nil {
+				return err
+			}
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			writeMsgpackInt(buf, i)
+		} else if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			writeUint(buf, u, 8)
+		} else if _, ok := new(big.Int).SetString(string(v), 10); ok {
+			return fmt.Errorf("cannot encode %s as MessagePack: integer does not fit in 64 bits", v)
+		} else {
+			f, err := parseFloat64(string(v))
+			if err != nil {
+				return fmt.Errorf("cannot encode %s as MessagePack: %v", v, err)
+			}
+			buf.WriteByte(0xcb)
+			writeUint(buf, math.Float64bits(f), 8)
+		}
+	case string:
+		switch n := len(v); {
+		case n < 32:
+			buf.WriteByte(0xa0 | byte(n))
+		case n <= math.MaxUint8:
+			buf.WriteByte(0xd9)
+			buf.WriteByte(byte(n))
+		case n <= math.MaxUint16:
+			buf.WriteByte(0xda)
+			writeUint(buf, uint64(n), 2)
+		default:
+			buf.WriteByte(0xdb)
+			writeUint(buf, uint64(n), 4)
+		}
+		buf.WriteString(v)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case nil:
+		buf.WriteByte(0xc0)
+	}
+	return nil
+}
+
+// parseFloat64 parses the JSON number s as a 64-bit float, failing if it
+// is outside the range of one.
+func parseFloat64(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("number is outside the range of a 64-bit float")
+	}
+	return f, nil
+}
+
+// writeMsgpackHead writes the header of a map or array of n elements,
+// where fix is its one-byte form and ext is the marker of its 16-bit form,
+// followed by the marker of its 32-bit form.
+func writeMsgpackHead(buf *bytes.Buffer, fix, ext byte, n int) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(ext)
+		writeUint(buf, uint64(n), 2)
+	default:
+		buf.WriteByte(ext + 1)
+		writeUint(buf, uint64(n), 4)
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i < 128, i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		writeUint(buf, uint64(i), 2)
+	case i >= 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		writeUint(buf, uint64(i), 4)
+	case i >= 0:
+		buf.WriteByte(0xcf)
+		writeUint(buf, uint64(i), 8)
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		writeUint(buf, uint64(i), 2)
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		writeUint(buf, uint64(i), 4)
+	default:
+		buf.WriteByte(0xd3)
+		writeUint(buf, uint64(i), 8)
+	}
+}
+
+// cborEncoder encodes values as CBOR, writing documents one after another
+// as a CBOR sequence. Integers are written in the smallest form that holds
+// them, as bignums if they do not fit in 64 bits, and other numbers as
+// 64-bit floats. Numbers outside the range of a float are rejected.
+type cborEncoder struct {
+	w io.Writer
+}
+
+func (e *cborEncoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := writeCBOR(&buf, g); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// CBOR major types.
+const (
+	cborUint = iota << 5
+	cborNegint
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// writeCBOR writes the CBOR encoding of the ordered value v.
+func writeCBOR(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case *object:
+		writeCBORHead(buf, cborMap, uint64(v.Len()))
+		for _, k := range v.Keys() {
+			elem, _ := v.Get(k)
+			writeCBOR(buf, k)
+			if err := writeCBOR(buf, elem); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		writeCBORHead(buf, cborArray, uint64(len(v)))
+		for _, elem := range v {
+			if err := writeCBOR(buf, elem); err != nil {
+				return err
+			}
+		}
+	case json.Number:
+		return writeCBORNumber(buf, string(v))
+	case string:
+		writeCBORHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case bool:
+		if v {
+			buf.WriteByte(cborSimple | 21)
+		} else {
+			buf.WriteByte(cborSimple | 20)
+		}
+	case nil:
+		buf.WriteByte(cborSimple | 22)
+	}
+	return nil
+}
+
+func writeCBORNumber(buf *bytes.Buffer, s string) error {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		f, err := parseFloat64(s)
+		if err != nil {
+			return fmt.Errorf("cannot encode %s as CBOR: %v", s, err)
+		}
+		buf.WriteByte(cborSimple | 27)
+		writeUint(buf, math.Float64bits(f), 8)
+		return nil
+	}
+	major, tag := byte(cborUint), uint64(2)
+	if n.Sign() < 0 {
+		// Negative integers are encoded as -1 - n.
+		major, tag = cborNegint, 3
+		n.Neg(n).Sub(n, big.NewInt(1))
+	}
+	if n.IsUint64() {
+		writeCBORHead(buf, major, n.Uint64())
+		return nil
+	}
+	p := n.Bytes()
+	writeCBORHead(buf, cborTag, tag)
+	writeCBORHead(buf, cborBytes, uint64(len(p)))
+	buf.Write(p)
+	return nil
+}
+
+// writeCBORHead writes the initial bytes of an item of the major type
+// with the argument n.
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		writeUint(buf, n, 2)
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		writeUint(buf, n, 4)
+	default:
+		buf.WriteByte(major | 27)
+		writeUint(buf, n, 8)
+	}
+}
+
+// writeUint writes the low size bytes of n in big-endian order.
+func writeUint(buf *bytes.Buffer, n uint64, size int) {
+	var p [8]byte
+	binary.BigEndian.PutUint64(p[:], n)
+	buf.Write(p[8-size:])
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBinaryEncoders(t *testing.T) {
+	tests := []struct {
+		in            string
+		msgpack, cbor string // Hex encodings, or errors.
+	}{
+		{`{"a":[1,-1,300]}`, "81a1619301ffcd012c", "a1616183012019012c"},
+		{`[1.5,true,null,"x"]`, "94cb3ff8000000000000c3c0a178", "84fb3ff8000000000000f5f66178"},
+		{`[18446744073709551615]`, "91cfffffffffffffffff", "811bffffffffffffffff"},
+		{`[18446744073709551616]`, "error: integer does not fit in 64 bits", "81c249010000000000000000"},
+		{`[1e400]`, "error: outside the range", "error: outside the range"},
+		{`[-1e400]`, "error: outside the range", "error: outside the range"},
+	}
+	for _, tt := range tests {
+		v := json.RawMessage(tt.in)
+		for _, enc := range []struct {
+			name string
+			e    func(*bytes.Buffer) encoder
+			want string
+		}{
+			{"msgpack", func(b *bytes.Buffer) encoder { return &msgpackEncoder{w: b} }, tt.msgpack},
+			{"cbor", func(b *bytes.Buffer) encoder { return &cborEncoder{w: b} }, tt.cbor},
+		} {
+			want := enc.want
+			var buf bytes.Buffer
+			err := enc.e(&buf).Encode(v)
+			switch {
+			case strings.HasPrefix(want, "error:"):
+				msg := strings.TrimPrefix(enc.want, "error: ")
+				if err == nil || !strings.Contains(err.Error(), msg) {
+					t.Errorf("%s(%s) = %v, want an error containing %q", enc.name, tt.in, err, msg)
+				}
+			case err != nil:
+				t.Errorf("%s(%s) = %v", enc.name, tt.in, err)
+			case hex.EncodeToString(buf.Bytes()) != want:
+				t.Errorf("%s(%s) = %x, want %s", enc.name, tt.in, buf.Bytes(), want)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// canonicalEncoder encodes values as canonical JSON, so that equal values
+// are always encoded to the same bytes: compact, with keys sorted, HTML
+// characters not escaped, numbers written in canonical form, and each
+// document followed by a single newline.
+type canonicalEncoder struct {
+	w io.Writer
+}
+
+func (e *canonicalEncoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	writeCanonical(&buf, g)
+	buf.WriteByte('\n')
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// writeCanonical writes the canonical JSON encoding of the ordered value v.
+func writeCanonical(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case *object:
+		v.Sort()
+		buf.WriteByte('{')
+		for i, k := range v.Keys() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			elem, _ := v.Get(k)
+			buf.WriteString(quoteString(k))
+			buf.WriteByte(':')
+			writeCanonical(buf, elem)
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonical(buf, elem)
+		}
+		buf.WriteByte(']')
+	case json.Number:
+		buf.WriteString(canonicalNumber(string(v)))
+	case string:
+		buf.WriteString(quoteString(v))
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case nil:
+		buf.WriteString("null")
+	}
+}
+
+// canonicalNumber returns the JSON number s in the form JavaScript writes
+// numbers in, using every significant digit of s: without an exponent if
+// the decimal point is at most 21 digits after or 6 digits before the
+// first significant digit, and with one otherwise (e.g., 1e+21 or 1e-7).
+// Integral values have no fractional part, and zero is 0.
+func canonicalNumber(s string) string {
+	neg := strings.HasPrefix(s, "-")
+	mant, exp := strings.TrimPrefix(s, "-"), 0
+	if i := strings.IndexAny(mant, "eE"); i >= 0 {
+		var err error
+		if exp, err = strconv.Atoi(mant[i+1:]); err != nil {
+			return s
+		}
+		mant = mant[:i]
+	}
+	digits := mant
+	if i := strings.IndexByte(mant, '.'); i >= 0 {
+		digits = mant[:i] + mant[i+1:]
+		exp -= len(mant) - i - 1
+	}
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0"
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exp += len(digits) - len(trimmed)
+	digits = trimmed
+
+	// The value is 0.DIGITS * 10^n.
+	k, n := len(digits), len(digits)+exp
+	var out string
+	switch {
+	case k <= n && n <= 21:
+		out = digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		out = digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		out = "0." + strings.Repeat("0", -n) + digits
+	default:
+		out = digits[:1]
+		if k > 1 {
+			out += "." + digits[1:]
+		}
+		if n-1 < 0 {
+			out += "e-" + strconv.Itoa(1-n)
+		} else {
+			out += "e+" + strconv.Itoa(n-1)
+		}
+	}
+	if neg {
+		out = "-" + out
+	}
+	return out
+}
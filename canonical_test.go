@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalNumber(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"0", "0"},
+		{"-0", "0"},
+		{"0.000", "0"},
+		{"1", "1"},
+		{"1.0", "1"},
+		{"10e-1", "1"},
+		{"-1.50", "-1.5"},
+		{"100", "100"},
+		{"1e2", "100"},
+		{"0.001", "0.001"},
+		{"1e-6", "0.000001"},
+		{"1e-7", "1e-7"},
+		{"1.25E-7", "1.25e-7"},
+		{"1e20", "100000000000000000000"},
+		{"1e21", "1e+21"},
+		{"123456789012345678901234567890", "1.2345678901234567890123456789e+29"},
+		{"0.1000000000000000000001", "0.1000000000000000000001"},
+		{"12.5e+1", "125"},
+		{"1ex", "1ex"},
+	}
+	for _, tt := range tests {
+		if got := canonicalNumber(tt.in); got != tt.want {
+			t.Errorf("canonicalNumber(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalEncoder(t *testing.T) {
+	values := readValues(t, &source{}, &valueParser{}, "z = 1.0\na = <&>\n[s]\nb = {\"y\": 10e-1, \"x\": [true, null]}\n")
+	var buf bytes.Buffer
+	enc := &canonicalEncoder{w: &buf}
+	out := &outputOptions{single: true, nested: true, sep: "."}
+	for i := 0; i < 2; i++ {
+		if err := enc.Encode(out.output(values)); err != nil {
+			t.Fatalf("Encode = %v", err)
+		}
+	}
+	doc := `{"a":"<&>","s":{"b":{"x":[true,null],"y":1}},"z":1}` + "\n"
+	if got, want := buf.String(), strings.Repeat(doc, 2); got != want {
+		t.Errorf("canonical output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// checker reports the problems found by -check, one per line.
+type checker struct {
+	w      io.Writer
+	parser *valueParser
+	count  int
+}
+
+// problem reports a single problem found at the location named by at.
+func (c *checker) problem(at, msg string) {
+	c.count++
+	fmt.Fprintf(c.w, "%s: %s\n", at, msg)
+}
+
+// report reports err, found in the input named by name. Errors listing
+// several problems, one per indented line, are reported as a problem for
+// each line. Lines that do not already name the input are prefixed with
+// its name.
+func (c *checker) report(name string, err error) {
+	lines := strings.Split(err.Error(), "\n  ")
+	if len(lines) > 1 {
+		lines = lines[1:]
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, name+":") {
+			c.count++
+			fmt.Fprintln(c.w, line)
+		} else {
+			c.problem(name, line)
+		}
+	}
+}
+
+// wrap returns a recorder that reports values that c's parser rejects,
+// at their location, and passes other values on to dest.
+func (c *checker) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return checkedValues{Recorder: dest, c: c, at: at}
+}
+
+type checkedValues struct {
+	ini.Recorder
+	c  *checker
+	at *cursor
+}
+
+func (v checkedValues) Add(key, value string) {
+	if name, _, _, err := v.c.parser.parseList(key, value); err != nil {
+		v.c.problem(v.at.Location().String(), fmt.Sprintf("%s: %v", name, err))
+		return
+	}
+	v.Recorder.Add(key, value)
+}
+
+// status returns the exit status for the problems reported so far: their
+// number, limited to 125 to keep clear of statuses shells reserve.
+func (c *checker) status() int {
+	if c.count > 125 {
+		return 125
+	}
+	return c.count
+}
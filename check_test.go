@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestChecker(t *testing.T) {
+	paths, done := tempFiles(t, "a:int = 1\nb:int = x\n[s]\nc:bool = maybe\nd = ok\n")
+	defer done()
+	path := paths[0]
+
+	var buf bytes.Buffer
+	parser := &valueParser{typedKeys: true}
+	c := &checker{w: &buf, parser: parser}
+	src := &source{
+		rd:     &ini.Reader{Separator: ".", True: "true"},
+		wrap:   []wrapper{c.wrap},
+		locate: true,
+	}
+	values := newParsedValues(parser)
+	if err := src.read(values, path); err != nil {
+		t.Fatalf("read(%s) = %v", path, err)
+	}
+	if err := values.Err(); err != nil {
+		t.Errorf("rejected values were recorded: %v", err)
+	}
+	if got, want := outputString(t, &outputOptions{single: true}, values), `{"a":1,"s.d":"ok"}`; got != want {
+		t.Errorf("values = %s, want %s", got, want)
+	}
+
+	c.report(path, errors.New("duplicate keys:\n  "+path+":5: duplicate key d\n  missing file and line"))
+	c.report(path, errors.New("not an INI file"))
+	want := path + `:2: b: "x" is not a valid int` + "\n" +
+		path + `:4: s.c: "maybe" is not a valid bool` + "\n" +
+		path + ":5: duplicate key d\n" +
+		path + ": missing file and line\n" +
+		path + ": not an INI file\n"
+	if got := buf.String(); got != want {
+		t.Errorf("reported:\n%s\nwant:\n%s", got, want)
+	}
+	if got := c.status(); got != 5 {
+		t.Errorf("status() = %d, want 5", got)
+	}
+	c.count = 300
+	if got := c.status(); got != 125 {
+		t.Errorf("status() with 300 problems = %d, want 125", got)
+	}
+}
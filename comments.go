@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// sectionComments collects the comment block immediately preceding each
+// section header of its input, keyed by the section of the keys recorded
+// from it once they are renamed. Sections with no keys recorded are not
+// described.
+type sectionComments struct {
+	sep     string
+	casing  ini.Casing
+	bareKey string // Key that bare lines are recorded under, if any.
+	desc    map[string]string
+}
+
+func newSectionComments(sep string, casing ini.Casing, bareKey string) *sectionComments {
+	return &sectionComments{sep: sep, casing: casing, bareKey: bareKey, desc: map[string]string{}}
+}
+
+// wrap is a wrapper that records the description of the section of each
+// key. It must follow any wrapper that renames keys, and its source must
+// keep lines. A blank line or any other line between a comment block and
+// a section header detaches the block from the section.
+func (c *sectionComments) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return observed{Recorder: dest, see: func(key string) {
+		h := at.header()
+		desc, ok := at.note(h)
+		if h == 0 || !ok {
+			return
+		}
+		if c.sep == "" {
+			c.desc[applyCasing(c.casing, sectionName(strings.TrimSpace(at.line(h))))] = desc
+			return
+		}
+		// The section of key is what remains once the components of
+		// the key as written in its section are removed.
+		name := lineKey(at.line(at.Location().Line), c.bareKey)
+		parts := strings.Split(key, c.sep)
+		n := len(parts) - strings.Count(name, c.sep) - 1
+		if n > 0 {
+			c.desc[strings.Join(parts[:n], c.sep)] = desc
+		}
+	}}
+}
+
+// lineKey returns the key of the assignment line as written, or key if
+// line is a bare line recorded under key.
+func lineKey(line, key string) string {
+	if i := strings.IndexByte(line, '='); i >= 0 {
+		return strings.TrimSpace(line[:i])
+	}
+	if key != "" {
+		return key
+	}
+	return strings.TrimSpace(line)
+}
+
+// reset discards all recorded descriptions.
+func (c *sectionComments) reset() {
+	c.desc = map[string]string{}
+}
+
+// isSectionHeader returns whether the trimmed line t is a section header.
+func isSectionHeader(t string) bool {
+	return strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]")
+}
+
+// sectionName returns the name of the section header t.
+func sectionName(t string) string {
+	return strings.TrimSpace(t[1 : len(t)-1])
+}
+
+// applyCasing transforms s the same way the ini.Reader transforms keys.
+func applyCasing(casing ini.Casing, s string) string {
+	switch casing {
+	case ini.LowerCase:
+		return strings.ToLower(s)
+	case ini.UpperCase:
+		return strings.ToUpper(s)
+	}
+	return s
+}
+
+// keyComments collects the comment block immediately preceding each
+// assignment of its input, keyed the way the value of the assignment is
+// recorded once its key is renamed. The nth comment of a key is the block
+// that preceded its nth value, or empty if there was none. Keys that were
+// never preceded by a comment are not recorded.
+type keyComments struct {
+	counts   map[string]int
+	comments map[string][]string
+}
+
+func newKeyComments() *keyComments {
+	c := &keyComments{}
+	c.reset()
+	return c
+}
+
+// wrap is a wrapper that records the comment preceding the line of each
+// value. It must follow any wrapper that renames or discards keys, and its
+// source must keep lines. Comments preceding a section header are left to
+// sectionComments.
+func (c *keyComments) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return observed{Recorder: dest, see: func(key string) {
+		n := c.counts[key]
+		c.counts[key] = n + 1
+		note, ok := at.note(at.Location().Line)
+		if !ok {
+			return
+		}
+		list := c.comments[key]
+		for len(list) < n {
+			list = append(list, "")
+		}
+		c.comments[key] = append(list, note)
+	}}
+}
+
+// reset discards all recorded comments.
+func (c *keyComments) reset() {
+	c.counts = map[string]int{}
+	c.comments = map[string][]string{}
+}
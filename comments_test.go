@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+const describedINI = `; global
+x = 1
+; Database settings
+; second line
+[db]
+host = a
+sub.port = 2
+
+; detached
+
+[log]
+level = 1
+; Cache
+[cache]
+size = 1
+`
+
+func TestSectionDescriptions(t *testing.T) {
+	rule, err := parseRenameRule("db=database", ".", ini.CaseSensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		nested bool
+		wrap   []wrapper
+		want   string
+	}{
+		{"flat", false, nil,
+			`{"x":[1],"db.host":["a"],"db.sub.port":[2],"log.level":[1],"cache.size":[1],"_descriptions":{"cache":"Cache","db":"Database settings\nsecond line"}}`},
+		{"nested", true, nil,
+			`{"x":[1],"db":{"host":["a"],"sub":{"port":[2]},"_description":"Database settings\nsecond line"},"log":{"level":[1]},"cache":{"size":[1],"_description":"Cache"}}`},
+		{"renamed", false, []wrapper{renameKeys([]renameRule{rule}), prefixKeys("app", ".", ini.CaseSensitive)},
+			`{"app.x":[1],"app.database.host":["a"],"app.database.sub.port":[2],"app.log.level":[1],"app.cache.size":[1],"_descriptions":{"app.cache":"Cache","app.database":"Database settings\nsecond line"}}`},
+		{"renamed nested", true, []wrapper{renameKeys([]renameRule{rule})},
+			`{"x":[1],"database":{"host":["a"],"sub":{"port":[2]},"_description":"Database settings\nsecond line"},"log":{"level":[1]},"cache":{"size":[1],"_description":"Cache"}}`},
+		{"excluded", false, []wrapper{filterSections(".", nil, []string{"cache"})},
+			`{"x":[1],"db.host":["a"],"db.sub.port":[2],"log.level":[1],"_descriptions":{"db":"Database settings\nsecond line"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSectionComments(".", ini.CaseSensitive, "")
+			src := &source{wrap: append(tt.wrap, c.wrap), keep: true, locate: true}
+			values := readValues(t, src, &valueParser{}, describedINI)
+			out := &outputOptions{dup: "append", nested: tt.nested, sep: ".", comments: c}
+			p, err := marshalJSON(out.output(values), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(p); got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyComments(t *testing.T) {
+	const in = "; top\nx = 1\n[db]\n; the host\nhost = a\nport = 2\n; again\nhost = b\n[cache]\n; size\nsize = 1\n"
+	rule, err := parseRenameRule("db=database", ".", ini.CaseSensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		wrap []wrapper
+		want string
+	}{
+		{"plain", nil, `{"cache.size":["size"],"db.host":["the host","again"],"x":["top"]}`},
+		{"renamed", []wrapper{renameKeys([]renameRule{rule})}, `{"cache.size":["size"],"database.host":["the host","again"],"x":["top"]}`},
+		{"excluded", []wrapper{filterSections(".", nil, []string{"cache"})}, `{"db.host":["the host","again"],"x":["top"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newKeyComments()
+			src := &source{wrap: append(tt.wrap, c.wrap), keep: true, locate: true}
+			readValues(t, src, &valueParser{}, in)
+			p, err := marshalJSON(c.comments, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(p); got != tt.want {
+				t.Errorf("_comments = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyCommentsPadding(t *testing.T) {
+	c := newKeyComments()
+	src := &source{wrap: []wrapper{c.wrap}, keep: true, locate: true}
+	readValues(t, src, &valueParser{}, "k = 1\nk = 2\n; third\nk = 3\n")
+	p, err := marshalJSON(c.comments, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(p), `{"k":["","","third"]}`; got != want {
+		t.Errorf("_comments = %s, want %s", got, want)
+	}
+}
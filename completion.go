@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// writeCompletion writes a script completing the flags of fs, and file
+// names otherwise, for shell, which is one of bash, zsh, or fish.
+func writeCompletion(w io.Writer, shell string, fs *flag.FlagSet) error {
+	var flags []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	var buf strings.Builder
+	switch shell {
+	case "bash":
+		var words []string
+		for _, f := range flags {
+			words = append(words, "-"+f.Name)
+			if len(f.Name) > 1 {
+				words = append(words, "--"+f.Name)
+			}
+		}
+		fmt.Fprintf(&buf, `_ini2json() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	case $cur in
+	-*) COMPREPLY=($(compgen -W '%s' -- "$cur")) ;;
+	*) COMPREPLY=($(compgen -f -- "$cur")) ;;
+	esac
+}
+complete -o filenames -F _ini2json ini2json
+`, strings.Join(words, " "))
+	case "zsh":
+		buf.WriteString("#compdef ini2json\n\n_arguments \\\n")
+		for _, f := range flags {
+			name, desc := flag.UnquoteUsage(f)
+			spec := "[" + zshEscape(desc) + "]"
+			if !isBoolFlag(f) {
+				if name == "" {
+					name = "value"
+				}
+				action := "_files"
+				if choices := choicesOf(fs, f); choices != nil {
+					for i, c := range choices {
+						choices[i] = zshEscape(c)
+					}
+					action = "(" + strings.Join(choices, " ") + ")"
+				}
+				spec += ":" + zshEscape(name) + ":" + action
+			}
+			fmt.Fprintf(&buf, "\t%s \\\n", shellQuote("-"+f.Name+spec))
+			if len(f.Name) > 1 {
+				fmt.Fprintf(&buf, "\t%s \\\n", shellQuote("--"+f.Name+spec))
+			}
+		}
+		buf.WriteString("\t'*:file:_files'\n")
+	case "fish":
+		for _, f := range flags {
+			_, desc := flag.UnquoteUsage(f)
+			opt := "-o " + f.Name + " -l " + f.Name
+			if len(f.Name) == 1 {
+				opt = "-s " + f.Name
+			}
+			if choices := choicesOf(fs, f); choices != nil {
+				opt += " -x -a " + shellQuote(strings.Join(choices, " "))
+			} else if !isBoolFlag(f) {
+				opt += " -r"
+			}
+			fmt.Fprintf(&buf, "complete -c ini2json %s -d %s\n", opt, shellQuote(desc))
+		}
+	default:
+		return fmt.Errorf("invalid shell %+q: must be one of bash, zsh, or fish", shell)
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// choicesOf returns a copy of the values allowed for f, if it takes one of
+// a fixed set, or nil. Short names share the value of their long name.
+func choicesOf(fs *flag.FlagSet, f *flag.Flag) []string {
+	for name, choices := range flagChoices {
+		if g := fs.Lookup(name); g != nil && g.Value == f.Value {
+			return append([]string(nil), choices...)
+		}
+	}
+	return nil
+}
+
+// isBoolFlag returns whether f may be given without a value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// zshEscape escapes the characters of s that are special in _arguments
+// specs.
+func zshEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+// shellQuote returns s quoted in single quotes for the shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWriteCompletion(t *testing.T) {
+	fs := flag.NewFlagSet("ini2json", flag.ContinueOnError)
+	fs.Bool("s", false, "write `single` values")
+	fs.String("out", "", "write to `file` [default: stdout]")
+	fs.Bool("x", false, "it's a: b")
+	fs.String("dup", "append", "duplicate key `POLICY`")
+
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{
+			`compgen -W '-dup --dup -out --out -s -x' -- "$cur"`,
+			"complete -o filenames -F _ini2json ini2json\n",
+		}},
+		{"zsh", []string{
+			"#compdef ini2json\n",
+			`'-dup[duplicate key POLICY]:POLICY:(append first last error)' \`,
+			`'--dup[duplicate key POLICY]:POLICY:(append first last error)' \`,
+			`'-out[write to file \[default\: stdout\]]:file:_files' \`,
+			`'--out[write to file \[default\: stdout\]]:file:_files' \`,
+			`'-s[write single values]' \`,
+			`'-x[it'\''s a\: b]' \`,
+			"\t'*:file:_files'\n",
+		}},
+		{"fish", []string{
+			"complete -c ini2json -o dup -l dup -x -a 'append first last error' -d 'duplicate key POLICY'\n",
+			"complete -c ini2json -o out -l out -r -d 'write to file [default: stdout]'\n",
+			"complete -c ini2json -s s -d 'write single values'\n",
+			"complete -c ini2json -s x -d 'it'\\''s a: b'\n",
+		}},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		if err := writeCompletion(&buf, tt.shell, fs); err != nil {
+			t.Errorf("writeCompletion(%s) = %v", tt.shell, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("writeCompletion(%s) = %s\nwant it to contain %s", tt.shell, buf.String(), want)
+			}
+		}
+	}
+
+	want := `invalid shell "csh": must be one of bash, zsh, or fish`
+	if err := writeCompletion(ioutil.Discard, "csh", fs); errString(err) != want {
+		t.Errorf("writeCompletion(csh) = %v, want %s", err, want)
+	}
+}
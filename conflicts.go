@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// conflicts records which input files define each key when merging.
+type conflicts struct {
+	defs map[string][]definition
+}
+
+// definition is the set of values a single file assigned to a key.
+type definition struct {
+	File   string      `json:"file"`
+	Values interface{} `json:"values"`
+}
+
+// conflict is a key defined by more than one file.
+type conflict struct {
+	Key         string       `json:"key"`
+	Definitions []definition `json:"definitions"`
+}
+
+func newConflicts() *conflicts {
+	return &conflicts{defs: map[string][]definition{}}
+}
+
+// add records the keys and values that the file at path defined.
+func (c *conflicts) add(path string, values ini.Recorder) {
+	for k, vs := range document(values) {
+		c.defs[k] = append(c.defs[k], definition{File: path, Values: vs})
+	}
+}
+
+// list returns every key defined by more than one file, sorted by key.
+func (c *conflicts) list() []conflict {
+	var list []conflict
+	for k, defs := range c.defs {
+		if len(defs) > 1 {
+			list = append(list, conflict{Key: k, Definitions: defs})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+// report writes the conflict list to w as a JSON object.
+func (c *conflicts) report(w io.Writer) error {
+	list := c.list()
+	if list == nil {
+		list = []conflict{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{"conflicts": list})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestConflicts(t *testing.T) {
+	c := newConflicts()
+	c.add("a.ini", readValues(t, &source{}, &valueParser{}, "[db]\nhost = a\nport = 1\n"))
+	c.add("b.ini", readValues(t, &source{}, &valueParser{}, "[db]\nhost = b\nname = x\n"))
+
+	list := c.list()
+	if len(list) != 1 || list[0].Key != "db.host" {
+		t.Fatalf("list() = %+v, want only db.host", list)
+	}
+	var buf bytes.Buffer
+	if err := c.report(&buf); err != nil {
+		t.Fatal(err)
+	}
+	const want = `{
+  "conflicts": [
+    {
+      "key": "db.host",
+      "definitions": [
+        {
+          "file": "a.ini",
+          "values": [
+            "a"
+          ]
+        },
+        {
+          "file": "b.ini",
+          "values": [
+            "b"
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("report() = %s, want %s", got, want)
+	}
+}
+
+func TestNoConflicts(t *testing.T) {
+	c := newConflicts()
+	c.add("a.ini", readValues(t, &source{}, &valueParser{}, "a = 1\n"))
+	c.add("b.ini", readValues(t, &source{}, &valueParser{}, "b = 1\n"))
+	var buf bytes.Buffer
+	if err := c.report(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "{\n  \"conflicts\": []\n}\n"; got != want {
+		t.Errorf("report() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+// Package convert converts INI documents to generic JSON values using the
+// same value semantics as the ini2json command.
+package convert
+
+import (
+	"io"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// Options controls how Convert reads and records an INI document.
+type Options struct {
+	// Separator joins section names and keys. If empty, "." is used.
+	Separator string
+	// Casing is the case transformation applied to keys.
+	Casing ini.Casing
+	// True is the value of keys that are not assigned a value. If empty,
+	// "true" is used.
+	True string
+	// Raw records all values as strings instead of parsing them with
+	// ParseValue.
+	Raw bool
+	// Single records keys that have a single value as that value instead
+	// of an array.
+	Single bool
+}
+
+// Convert reads an INI document from r and returns its keys mapped to
+// their values. Each key maps to an array of its values, in the order
+// they appear in r, unless opts.Single is set and the key has only one
+// value. Tabs and spaces padding keys are trimmed, and keys assigned an
+// empty value are assigned opts.True, as the ini2json command does by
+// default.
+//
+// Convert applies none of the command's other options, such as its
+// dialects, -key-tabs, -collapse-spaces, or -empty, and since the result
+// is a map, the order of keys in r is not kept.
+func Convert(r io.Reader, opts Options) (map[string]interface{}, error) {
+	rd := ini.Reader{
+		Separator: opts.Separator,
+		Casing:    opts.Casing,
+		True:      opts.True,
+	}
+	if rd.Separator == "" {
+		rd.Separator = "."
+	}
+	if rd.True == "" {
+		rd.True = "true"
+	}
+
+	values := Values{}
+	var dest ini.Recorder = values
+	if opts.Raw {
+		dest = rawValues{values}
+	}
+	if err := rd.Read(r, trimmedKeys{dest}); err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{}, len(values))
+	for k, vs := range values {
+		if opts.Single && len(vs) == 1 {
+			doc[k] = vs[0]
+		} else {
+			doc[k] = vs
+		}
+	}
+	return doc, nil
+}
+
+// Values is an ini.Recorder that records values parsed by ParseValue.
+type Values map[string][]interface{}
+
+// Add appends value, parsed by ParseValue, to the values of key.
+func (v Values) Add(key, value string) {
+	v[key] = append(v[key], ParseValue(value))
+}
+
+// trimmedKeys trims tabs and spaces from the end of each key before
+// passing it on.
+type trimmedKeys struct {
+	ini.Recorder
+}
+
+func (t trimmedKeys) Add(key, value string) {
+	t.Recorder.Add(strings.TrimRight(key, " \t"), value)
+}
+
+// rawValues records values in Values as strings.
+type rawValues struct {
+	Values
+}
+
+func (v rawValues) Add(key, value string) {
+	v.Values[key] = append(v.Values[key], value)
+}
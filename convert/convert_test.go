@@ -0,0 +1,36 @@
+package convert
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	const in = "top = 1\n[db]\nhost\t= \"a b\"\nport = 5432\nport = 5433\nflag\nratio = 0.5\n"
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"default", Options{}, `{"db.flag":[true],"db.host":["a b"],"db.port":[5432,5433],"db.ratio":[0.5],"top":[1]}`},
+		{"single", Options{Single: true}, `{"db.flag":true,"db.host":"a b","db.port":[5432,5433],"db.ratio":0.5,"top":1}`},
+		{"raw", Options{Raw: true, True: "yes"}, `{"db.flag":["yes"],"db.host":["a b"],"db.port":["5432","5433"],"db.ratio":["0.5"],"top":["1"]}`},
+		{"separator", Options{Separator: "/", Single: true}, `{"db/flag":true,"db/host":"a b","db/port":[5432,5433],"db/ratio":0.5,"top":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Convert(strings.NewReader(in), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(p); got != tt.want {
+				t.Errorf("Convert() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
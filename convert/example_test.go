@@ -0,0 +1,29 @@
+package convert_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.spiff.io/ini2json/convert"
+)
+
+func ExampleConvert() {
+	const config = `
+[server]
+host = example.com
+port = 8080
+tls
+`
+	doc, err := convert.Convert(strings.NewReader(config), convert.Options{Single: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	p, err := json.Marshal(doc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(p))
+	// Output: {"server.host":"example.com","server.port":8080,"server.tls":true}
+}
@@ -0,0 +1,63 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Float is a floating point value parsed by ParseValue.
+type Float big.Float
+
+// Float returns b as a *big.Float.
+func (b *Float) Float() *big.Float {
+	return (*big.Float)(b)
+}
+
+// MarshalJSON encodes b as a JSON number that always carries a decimal
+// point, so that it is never read back as an integer. Numbers are written
+// in plain decimal form unless their exponent is large, in which case the
+// shortest exponent form is used. (*big.Int values are written as plain
+// decimal digits by their own MarshalJSON.)
+func (b *Float) MarshalJSON() ([]byte, error) {
+	f := b.Float()
+	if f.IsInf() {
+		return nil, fmt.Errorf("cannot encode %v as JSON", f)
+	}
+
+	text := f.Text('g', -1)
+	if i := strings.IndexByte(text, 'e'); i >= 0 {
+		if exp, err := strconv.Atoi(text[i+1:]); err == nil && exp > -7 && exp < 21 {
+			text = f.Text('f', -1)
+		}
+	}
+
+	mant, exp := text, ""
+	if i := strings.IndexByte(text, 'e'); i >= 0 {
+		mant, exp = text[:i], text[i:]
+	}
+	if !strings.Contains(mant, ".") {
+		mant += ".0"
+	}
+	return json.RawMessage(mant + exp), nil
+}
+
+// ParseValue returns value parsed as an integer, float, bool, or JSON, in
+// that order. Integers are returned as *big.Int and floats as *Float. If
+// value cannot be parsed as any of these, it is returned as a string.
+func ParseValue(value string) interface{} {
+	var jsval interface{}
+	if ival, ok := new(big.Int).SetString(value, 10); ok {
+		jsval = ival
+	} else if fval, _, err := big.ParseFloat(value, 10, 256, big.ToNearestEven); err == nil && !fval.IsInf() {
+		jsval = (*Float)(fval)
+	} else if bval, err := strconv.ParseBool(value); err == nil {
+		jsval = bval
+	} else if b := []byte(value); json.Valid(b) && json.Unmarshal(b, &jsval) == nil {
+	} else {
+		jsval = value
+	}
+	return jsval
+}
@@ -0,0 +1,44 @@
+package convert
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParseValueJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1234567890123456789012345678901234567890", "1234567890123456789012345678901234567890"},
+		{"1234567890123456789012345678901234567890.0", "1.23456789012345678901234567890123456789e+39"},
+		{"1.5", "1.5"},
+		{"-0.25", "-0.25"},
+		{"2.", "2.0"},
+		{"1e20", "100000000000000000000.0"},
+		{"1e21", "1.0e+21"},
+		{"1.5e21", "1.5e+21"},
+		{"1e-6", "0.000001"},
+		{"1e-7", "1.0e-07"},
+	}
+	for _, tt := range tests {
+		p, err := json.Marshal(ParseValue(tt.in))
+		if err != nil {
+			t.Errorf("Marshal(%s) = %v", tt.in, err)
+			continue
+		}
+		if string(p) != tt.want {
+			t.Errorf("Marshal(%s) = %s, want %s", tt.in, p, tt.want)
+		}
+	}
+}
+
+func TestFloatMarshalJSONInf(t *testing.T) {
+	for _, f := range []*big.Float{big.NewFloat(math.Inf(1)), big.NewFloat(math.Inf(-1))} {
+		if p, err := json.Marshal((*Float)(f)); err == nil {
+			t.Errorf("Marshal(%v) = %s, want an error", f, p)
+		}
+	}
+}
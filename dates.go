@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"go.spiff.io/ini2json/convert"
+)
+
+// dateLayouts are the layouts -dates recognizes if no -date-format is
+// given. Times without a zone are in UTC.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parseDate parses s using the first of layouts that accepts it and
+// returns it as an RFC 3339 string or, if epoch is set, as a number of
+// seconds since the Unix epoch. Times in a zone abbreviation whose offset
+// is unknown are rejected, since they would be read as UTC.
+func parseDate(s string, layouts []string, epoch bool) (interface{}, bool) {
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, s)
+		if err != nil || unknownZone(t) {
+			continue
+		}
+		if !epoch {
+			return t.Format(time.RFC3339Nano), true
+		}
+		if t.Nanosecond() == 0 {
+			return big.NewInt(t.Unix()), true
+		}
+		f := new(big.Float).SetPrec(256).SetInt64(t.UnixNano())
+		f.Quo(f, big.NewFloat(1e9))
+		return (*convert.Float)(f), true
+	}
+	return nil, false
+}
+
+// unknownZone returns whether t was parsed with a zone abbreviation that
+// is not UTC, GMT, or one of the local time zone, which time.Parse records
+// with a zero offset.
+func unknownZone(t time.Time) bool {
+	name, offset := t.Zone()
+	loc := t.Location()
+	return offset == 0 && loc != time.UTC && loc != time.Local && name != "" && !strings.HasPrefix(name, "GMT")
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2006-01-02T15:04:05Z", "2006-01-02T15:04:05Z"},
+		{"2006-01-02 15:04:05", "2006-01-02T15:04:05Z"},
+		{"2006-01-02", "2006-01-02T00:00:00Z"},
+		{"Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T15:04:05-07:00"},
+		{"Mon, 02 Jan 2006 15:04:05 UTC", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 XYZ", ""},
+		{"not a date", ""},
+	}
+	for _, tt := range tests {
+		v, ok := parseDate(tt.in, dateLayouts, false)
+		if got, _ := v.(string); ok != (tt.want != "") || got != tt.want {
+			t.Errorf("parseDate(%q) = %v, %v; want %q", tt.in, v, ok, tt.want)
+		}
+	}
+}
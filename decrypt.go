@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// decryptValues replaces encrypted values, written as ENC[...], with the
+// output of a command given the text between the brackets.
+type decryptValues struct {
+	ini.Recorder
+	cmd  string
+	errs *[]string
+}
+
+// decryptWith returns a wrapper that decrypts values using the shell
+// command cmd. The command is run once per encrypted value with the
+// ciphertext on its standard input and the value's key in the
+// environment variable INI2JSON_KEY, and must write the plaintext to its
+// standard output. A single trailing newline is removed from the output.
+func decryptWith(cmd string) wrapper {
+	return func(dest ini.Recorder, _ *cursor) ini.Recorder {
+		return decryptValues{Recorder: dest, cmd: cmd, errs: new([]string)}
+	}
+}
+
+func (v decryptValues) Add(key, value string) {
+	if !strings.HasPrefix(value, "ENC[") || !strings.HasSuffix(value, "]") {
+		v.Recorder.Add(key, value)
+		return
+	}
+	plain, err := v.decrypt(key, value[len("ENC["):len(value)-1])
+	if err != nil {
+		*v.errs = append(*v.errs, fmt.Sprintf("%s: %v", key, err))
+		return
+	}
+	v.Recorder.Add(key, plain)
+}
+
+func (v decryptValues) decrypt(key, ciphertext string) (string, error) {
+	cmd := shellCommand(v.cmd)
+	var out, stderr bytes.Buffer
+	cmd.Env = append(os.Environ(), "INI2JSON_KEY="+key)
+	cmd.Stdin = strings.NewReader(ciphertext)
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	plain := strings.TrimSuffix(out.String(), "\n")
+	return strings.TrimSuffix(plain, "\r"), nil
+}
+
+// Err returns an error listing every value that could not be decrypted.
+func (v decryptValues) Err() error {
+	if len(*v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unable to decrypt values:\n  %s", strings.Join(*v.errs, "\n  "))
+}
+
+// shellCommand returns a command running cmd with the system shell.
+func shellCommand(cmd string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", cmd)
+	}
+	return exec.Command("sh", "-c", cmd)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestDecrypt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("decryption commands are written for sh")
+	}
+	const in = "plain = abc\n[db]\npass = ENC[secret]\nport = ENC[8080]\nodd = ENC[x\n"
+	src := &source{wrap: []wrapper{decryptWith(`printf '%s=' "$INI2JSON_KEY"; tr a-z A-Z; echo`)}}
+	got := readString(t, src, &valueParser{}, in)
+	want := `{"plain":["abc"],"db.pass":["db.pass=SECRET"],"db.port":["db.port=8080"],"db.odd":["ENC[x"]}`
+	if got != want {
+		t.Errorf("decryptWith(...) = %s, want %s", got, want)
+	}
+
+	// Decrypted values are parsed like any other.
+	src = &source{wrap: []wrapper{decryptWith("cat")}}
+	if got, want := readString(t, src, &valueParser{}, "n = ENC[42]\n"), `{"n":[42]}`; got != want {
+		t.Errorf("decryptWith(cat) = %s, want %s", got, want)
+	}
+
+	paths, done := tempFiles(t, "a = ENC[1]\nb = 2\nc = ENC[3]\n")
+	defer done()
+	src = &source{
+		rd:   &ini.Reader{Separator: ".", True: "true"},
+		wrap: []wrapper{decryptWith("echo no key >&2; exit 3")},
+	}
+	err := src.read(newParsedValues(&valueParser{}), paths[0])
+	want = "unable to decrypt values:\n  a: exit status 3: no key\n  c: exit status 3: no key"
+	if errString(err) != want {
+		t.Errorf("read with a failing command = %v, want %s", err, want)
+	}
+}
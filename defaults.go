@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// globalKeys returns a wrapper for keys assigned before any section
+// header. If reject is true, they are rejected, and otherwise, if section
+// is not empty, they are recorded in section, using sep.
+func globalKeys(section, sep string, reject bool) wrapper {
+	prefix := ""
+	if section != "" {
+		prefix = section + sep
+	}
+	return func(dest ini.Recorder, at *cursor) ini.Recorder {
+		return &globals{dest: dest, at: at, prefix: prefix, reject: reject}
+	}
+}
+
+type globals struct {
+	dest   ini.Recorder
+	at     *cursor
+	prefix string
+	reject bool
+	errs   []string
+}
+
+func (g *globals) Add(key, value string) {
+	switch {
+	case g.at.section > 0:
+	case g.reject:
+		g.errs = append(g.errs, fmt.Sprintf("%v: %s", g.at.Location(), key))
+		return
+	case g.prefix != "":
+		key = g.prefix + key
+	}
+	g.dest.Add(key, value)
+}
+
+// Err returns an error listing every key rejected so far.
+func (g *globals) Err() error {
+	if len(g.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("keys outside of any section:\n  %s", strings.Join(g.errs, "\n  "))
+}
+
+// inheritDefaults returns a wrapper that records the values of keys in the
+// section named defaults in every other section that has keys, unless the
+// section assigns the key itself. Since a section may assign a key after
+// its values would be inherited, values are held until the input has been
+// read and then passed on in order, with the inherited values of each
+// section following its own.
+func inheritDefaults(defaults, sep string) wrapper {
+	return func(dest ini.Recorder, at *cursor) ini.Recorder {
+		return &inheritor{dest: dest, at: at, prefix: defaults + sep, sep: sep}
+	}
+}
+
+type inheritor struct {
+	dest   ini.Recorder
+	at     *cursor
+	prefix string // Prefix of keys in the defaults section.
+	sep    string
+	held   []heldValue
+	done   bool
+}
+
+func (in *inheritor) Add(key, value string) {
+	in.held = append(in.held, heldValue{
+		key:     key,
+		value:   value,
+		loc:     in.at.loc,
+		section: in.at.section,
+	})
+}
+
+// section returns the name of the section of the held value h, if any.
+func (in *inheritor) section(h heldValue) (string, bool) {
+	i := strings.LastIndex(h.key, in.sep)
+	if h.section == 0 || i < 0 {
+		return "", false
+	}
+	return h.key[:i], true
+}
+
+// Err passes each held value, and the values inherited by each section,
+// on to dest. It must be called once the input has been read.
+func (in *inheritor) Err() error {
+	if in.done {
+		return nil
+	}
+	in.done = true
+
+	var defaults []heldValue
+	assigned := map[string]bool{}
+	for _, h := range in.held {
+		if strings.HasPrefix(h.key, in.prefix) {
+			defaults = append(defaults, h)
+		}
+		assigned[h.key] = true
+	}
+
+	for i, h := range in.held {
+		in.at.loc, in.at.section = h.loc, h.section
+		in.dest.Add(h.key, h.value)
+
+		section, ok := in.section(h)
+		if !ok || section+in.sep == in.prefix {
+			continue
+		}
+		if i+1 < len(in.held) {
+			if next, _ := in.section(in.held[i+1]); next == section && in.held[i+1].section == h.section {
+				continue
+			}
+		}
+		// Inherited values are recorded at the location of their default,
+		// once for each section, even if it appears more than once.
+		var inherited []string
+		for _, d := range defaults {
+			key := section + in.sep + strings.TrimPrefix(d.key, in.prefix)
+			if !assigned[key] {
+				in.at.loc = d.loc
+				in.dest.Add(key, d.value)
+				inherited = append(inherited, key)
+			}
+		}
+		for _, key := range inherited {
+			assigned[key] = true
+		}
+	}
+	return nil
+}
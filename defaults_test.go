@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestGlobalKeys(t *testing.T) {
+	const in = "a = 1\nb = 2\n[s]\nc = 3\n"
+	tests := []struct {
+		section string
+		want    string
+	}{
+		{"", `{"a":[1],"b":[2],"s.c":[3]}`},
+		{"main", `{"main.a":[1],"main.b":[2],"s.c":[3]}`},
+	}
+	for _, tt := range tests {
+		src := &source{wrap: []wrapper{globalKeys(tt.section, ".", false)}, locate: true}
+		if got := readString(t, src, &valueParser{}, in); got != tt.want {
+			t.Errorf("-default-section %q = %s, want %s", tt.section, got, tt.want)
+		}
+	}
+
+	paths, done := tempFiles(t, in)
+	defer done()
+	src := &source{
+		rd:     &ini.Reader{Separator: ".", True: "true"},
+		wrap:   []wrapper{globalKeys("", ".", true)},
+		locate: true,
+	}
+	err := src.read(newParsedValues(&valueParser{}), paths[0])
+	want := "keys outside of any section:\n  " + paths[0] + ":1: a\n  " + paths[0] + ":2: b"
+	if errString(err) != want {
+		t.Errorf("-require-sections = %v, want %s", err, want)
+	}
+}
+
+func TestInheritDefaults(t *testing.T) {
+	const in = "top = 0\n[DEFAULT]\nport = 80\nhost = localhost\ntag = a\ntag = b\n" +
+		"[web]\nport = 8080\n[empty]\n[db]\nname = x\n[web]\nlog = on\n"
+	src := &source{wrap: []wrapper{inheritDefaults("DEFAULT", ".")}, locate: true}
+	got := readString(t, src, &valueParser{}, in)
+	want := `{"top":[0],"DEFAULT.port":[80],"DEFAULT.host":["localhost"],"DEFAULT.tag":["a","b"],` +
+		`"web.port":[8080],"web.host":["localhost"],"web.tag":["a","b"],` +
+		`"db.name":["x"],"db.port":[80],"db.host":["localhost"],"db.tag":["a","b"],"web.log":["on"]}`
+	if got != want {
+		t.Errorf("-inherit-defaults:\ngot  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// sniffSize is the number of bytes at the start of an input that are read
+// to detect its dialect.
+const sniffSize = 64 << 10
+
+// systemdSections are the names of sections that identify systemd unit
+// files and other systemd configuration.
+var systemdSections = map[string]bool{
+	"Unit": true, "Install": true, "Service": true, "Socket": true,
+	"Timer": true, "Mount": true, "Automount": true, "Swap": true,
+	"Path": true, "Slice": true, "Scope": true, "Match": true,
+	"Network": true, "NetDev": true, "Link": true, "Journal": true,
+	"Login": true, "Resolve": true,
+}
+
+// detect returns a filter for inputs read with -f auto that detects the
+// dialect of its input from its start and applies the filters of that
+// dialect to it. The dialect detected is recorded in c.
+func (c *cursor) detect(sep string) filter {
+	return func(w io.Writer, r io.Reader) error {
+		br := bufio.NewReaderSize(r, sniffSize)
+		head, err := br.Peek(sniffSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return err
+		}
+		name := sniffDialect(head)
+		c.mu.Lock()
+		c.dialect = name
+		c.mu.Unlock()
+
+		filters, _ := dialectFilters(name, sep)
+		var in io.Reader = br
+		for _, fn := range filters {
+			rc := fn.apply(in)
+			defer rc.Close()
+			in = rc
+		}
+		_, err = io.Copy(w, in)
+		return err
+	}
+}
+
+// detected returns the dialect detected for the input, if any.
+func (c *cursor) detected() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dialect
+}
+
+// sniffDialect returns the name of the dialect that the start of an input,
+// head, is most likely written in. Byte order marks and CRLF line endings
+// select windows, headers with quoted subsections (e.g., [remote
+// "origin"]) select gitconfig, and headers of systemd sections (e.g.,
+// [Unit] or [Service]) select systemd. Inputs without section headers
+// that have '!' comments or keys separated from values by ':' select
+// properties. Other inputs are read in the default dialect.
+func sniffDialect(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}),
+		bytes.HasPrefix(head, []byte{0xFF, 0xFE}),
+		bytes.HasPrefix(head, []byte{0xFE, 0xFF}),
+		bytes.Contains(head, []byte("\r\n")):
+		return "windows"
+	}
+
+	headers, systemd, properties := 0, false, false
+	for _, line := range strings.Split(string(head), "\n") {
+		t := strings.TrimSpace(line)
+		switch {
+		case t == "" || t[0] == ';' || t[0] == '#':
+		case t[0] == '!':
+			properties = true
+		case t[0] == '[':
+			headers++
+			if strings.Contains(t, `"`) {
+				return "gitconfig"
+			} else if isSectionHeader(t) && systemdSections[sectionName(t)] {
+				systemd = true
+			}
+		default:
+			if i := strings.IndexAny(t, "=:"); i >= 0 && t[i] == ':' {
+				properties = true
+			}
+		}
+	}
+	switch {
+	case systemd:
+		return "systemd"
+	case headers == 0 && properties:
+		return "properties"
+	}
+	return "default"
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestSniffDialect(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "default"},
+		{"a = 1\n[s]\nb: c\n", "default"},
+		{"\xef\xbb\xbfa = 1\n", "windows"},
+		{"\xff\xfea\x00", "windows"},
+		{"a = 1\r\nb = 2\r\n", "windows"},
+		{"[core]\n\tbare = false\n[remote \"origin\"]\n\turl = x\n", "gitconfig"},
+		{"# unit\n[Unit]\nDescription = x\n[Service]\nExecStart=/bin/true\n", "systemd"},
+		{"[Unitx]\na = 1\n", "default"},
+		{"! comment\nkey = value\n", "properties"},
+		{"# comment\nkey: value\nother = x:y\n", "properties"},
+		{"key = value:x\n", "default"},
+	}
+	for _, tt := range tests {
+		if got := sniffDialect([]byte(tt.in)); got != tt.want {
+			t.Errorf("sniffDialect(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"[remote \"origin\"]\nurl = x\n", `{"remote.origin.url":["x"]}`},
+		{"! comment\nkey: value\n", `{"key":["value"]}`},
+		{"a = 1\r\n", `{"a":[1]}`},
+		{"a = 1\n", `{"a":[1]}`},
+	}
+	for _, tt := range tests {
+		src := &source{detect: true}
+		if got := readString(t, src, &valueParser{}, tt.in); got != tt.want {
+			t.Errorf("-dialect auto: read(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// dialectFilters returns the filters that rewrite inputs written in the
+// named INI dialect into the form the reader expects. They are applied
+// before any other filter. sep is the separator for section and key
+// names. The dialect of inputs read with the auto dialect is detected as
+// they are read, so it has no filters of its own.
+func dialectFilters(name, sep string) ([]filter, bool) {
+	switch name {
+	case "default", "auto":
+		return nil, true
+	case "windows":
+		return []filter{decodeBOM, windowsLines}, true
+	case "gitconfig":
+		return []filter{gitconfigLines(sep)}, true
+	case "systemd":
+		return []filter{systemdLines}, true
+	case "properties":
+		return []filter{propertiesLines}, true
+	}
+	return nil, false
+}
+
+// decodeBOM is a filter that removes a UTF-8 byte order mark from the
+// start of its input, or decodes input starting with a UTF-16 byte order
+// mark to UTF-8.
+func decodeBOM(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(3)
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		br.Discard(3)
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	}
+	if order == nil {
+		_, err := io.Copy(w, br)
+		return err
+	}
+
+	br.Discard(2)
+	p, err := ioutil.ReadAll(br)
+	if err != nil {
+		return err
+	}
+	units := make([]uint16, len(p)/2)
+	for i := range units {
+		units[i] = order.Uint16(p[2*i:])
+	}
+	_, err = io.WriteString(w, string(utf16.Decode(units)))
+	return err
+}
+
+// windowsLines is a filter that rewrites lines written in the style of
+// Windows INI files. Carriage returns before line endings are dropped,
+// comments following a ';' or '#' after whitespace are removed, and
+// double-quoted values are decoded, allowing \n, \t, \r, \", and \\
+// escapes, and quoted again for the reader.
+var windowsLines = lineFilter(func(line string) string {
+	line = strings.TrimRight(line, "\r")
+	t := strings.TrimSpace(line)
+	if t == "" || t[0] == ';' || t[0] == '#' {
+		return line
+	}
+	i := strings.IndexByte(line, '=')
+	if t[0] == '[' || i < 0 {
+		return stripInlineComment(line)
+	}
+
+	key, value := line[:i], strings.TrimSpace(line[i+1:])
+	if !strings.HasPrefix(value, `"`) {
+		return key + "= " + stripInlineComment(value)
+	}
+	s, rest, ok := unquoteWindows(value)
+	if rest = strings.TrimSpace(rest); !ok || rest != "" && rest[0] != ';' && rest[0] != '#' {
+		// Leave values that are not just a quoted string to the reader.
+		return line
+	}
+	return key + "= " + quoteValue(s)
+})
+
+// stripInlineComment returns s without a comment starting with a ';' or
+// '#' that follows a space or tab, and without trailing whitespace.
+func stripInlineComment(s string) string {
+	for i := 1; i < len(s); i++ {
+		if (s[i] == ';' || s[i] == '#') && (s[i-1] == ' ' || s[i-1] == '\t') {
+			s = s[:i]
+			break
+		}
+	}
+	return strings.TrimRight(s, " \t")
+}
+
+// unquoteWindows decodes the double-quoted string at the start of s and
+// returns it and the text following the closing quote. ok is false if
+// the string is not terminated. Unknown escapes are kept as written.
+func unquoteWindows(s string) (value, rest string, ok bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			return b.String(), s[i+1:], true
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
+
+// gitconfigLines returns a filter that rewrites lines written in the style
+// of git-config files. Headers of the form [section "subsection"] are
+// rewritten as [section SEP subsection], values ending in a backslash are
+// continued on the next line, and values are decoded as git does: double
+// quotes may enclose any part of them, \n, \t, \b, \", and \\ escapes
+// are allowed, and a ';' or '#' outside quotes starts a comment.
+func gitconfigLines(sep string) filter {
+	return continuedLines("", false, func(line string) string {
+		return gitconfigLine(line, sep)
+	})
+}
+
+// systemdLines is a filter that rewrites lines written in the style of
+// systemd unit files. Lines ending in a backslash are continued on the
+// next line, joined by a space, skipping comment lines in between and
+// leading whitespace, and
+// empty assignments are rewritten to assign an empty string, which
+// resetEmpty takes to clear the values of the key.
+var systemdLines = continuedLines(" ", true, func(line string) string {
+	t := strings.TrimSpace(line)
+	if t == "" || t[0] == ';' || t[0] == '#' || isSectionHeader(t) {
+		return line
+	}
+	if i := strings.IndexByte(line, '='); i >= 0 && strings.TrimSpace(line[i+1:]) == "" {
+		return line[:i] + `= ""`
+	}
+	return line
+})
+
+// continuedLines returns a filter that joins each line ending in a
+// backslash to the line after it with join in place of the backslash,
+// passing each joined line through fn. If trim is true, comment lines
+// within a continued line are dropped and the leading whitespace of each
+// line joined is removed. Joined lines are replaced
+// with blank lines, so the lines of values are kept.
+func continuedLines(join string, trim bool, fn func(line string) string) filter {
+	return func(w io.Writer, r io.Reader) error {
+		sc := lineScanner(r)
+		for sc.Scan() {
+			line, joined := sc.Text(), 0
+			for continued(line) && sc.Scan() {
+				joined++
+				next := sc.Text()
+				if trim {
+					next = strings.TrimLeft(next, " \t")
+					if next != "" && (next[0] == ';' || next[0] == '#') {
+						continue
+					}
+				}
+				line = line[:len(line)-1] + join + next
+			}
+			line = fn(line) + "\n" + strings.Repeat("\n", joined)
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+		return sc.Err()
+	}
+}
+
+// propertiesLines is a filter that rewrites lines written in the style of
+// Java properties files, where keys are separated from values by '=',
+// ':', or whitespace, comments start with '#' or '!', lines ending in a
+// backslash are continued on the next line without its leading
+// whitespace, and keys and values may contain \uXXXX escapes and the
+// escapes \t, \n, \r, and \f. Other escaped characters stand for
+// themselves. Keys without a value are assigned an empty string.
+func propertiesLines(w io.Writer, r io.Reader) error {
+	sc := lineScanner(r)
+	for sc.Scan() {
+		line, joined := sc.Text(), 0
+		if t := strings.TrimLeft(line, " \t\f"); t == "" || t[0] == '#' || t[0] == '!' {
+			line = ""
+		} else {
+			for continued(line) && sc.Scan() {
+				line = line[:len(line)-1] + strings.TrimLeft(sc.Text(), " \t\f")
+				joined++
+			}
+			line = propertiesLine(line)
+		}
+		if _, err := io.WriteString(w, line+"\n"+strings.Repeat("\n", joined)); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func propertiesLine(line string) string {
+	line = strings.TrimLeft(line, " \t\f")
+	end := 0
+	for ; end < len(line); end++ {
+		if c := line[end]; c == '\\' {
+			end++
+		} else if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+	key, rest := unescapeProperty(line[:end]), strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	// Values that need no decoding are left unquoted to be parsed as any
+	// other value.
+	value := unescapeProperty(rest)
+	if value == "" || value != rest || strings.HasPrefix(value, `"`) {
+		value = quoteValue(value)
+	}
+	return key + " = " + value
+}
+
+// unescapeProperty returns s with the escapes of properties files decoded.
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, ok := hexRune(s[i+1:])
+			if !ok {
+				b.WriteByte(c)
+				break
+			}
+			i += 4
+			// Characters outside the BMP are written as surrogate pairs.
+			if r2, ok := hexRune(strings.TrimPrefix(s[i+1:], `\u`)); ok && strings.HasPrefix(s[i+1:], `\u`) && utf16.IsSurrogate(r) {
+				if dec := utf16.DecodeRune(r, r2); dec != unicode.ReplacementChar {
+					r = dec
+					i += 6
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// hexRune returns the rune written as the four hexadecimal digits at the
+// start of s.
+func hexRune(s string) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[:4], 16, 16)
+	return rune(n), err == nil
+}
+
+// continued reports whether line ends in a backslash that is not itself
+// escaped.
+func continued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+func gitconfigLine(line, sep string) string {
+	t := strings.TrimSpace(line)
+	if t == "" || t[0] == ';' || t[0] == '#' {
+		return line
+	}
+	if t[0] == '[' {
+		return gitconfigHeader(t, sep)
+	}
+	i := strings.IndexByte(line, '=')
+	if i < 0 {
+		return stripGitComment(line)
+	}
+	// Values that need no decoding are left unquoted to be parsed as any
+	// other value. Empty values are empty strings, as in git, not TRUE.
+	value := gitconfigValue(line[i+1:])
+	if value == "" || value != stripGitComment(strings.TrimLeft(line[i+1:], " \t")) {
+		value = quoteValue(value)
+	}
+	return line[:i] + "= " + value
+}
+
+// gitconfigHeader returns the section header t with a quoted subsection
+// name joined to the section name by sep. Headers without a subsection
+// are returned without a trailing comment.
+func gitconfigHeader(t, sep string) string {
+	i := strings.IndexAny(t, "\"]")
+	if i < 0 || t[i] == ']' {
+		return stripGitComment(t)
+	}
+	name := strings.TrimSpace(t[1:i])
+	var b strings.Builder
+	for i++; i < len(t) && t[i] != '"'; i++ {
+		if t[i] == '\\' && i+1 < len(t) {
+			i++
+		}
+		b.WriteByte(t[i])
+	}
+	return "[" + name + sep + b.String() + "]"
+}
+
+// stripGitComment returns s without a comment starting with a ';' or '#'
+// and without trailing whitespace.
+func stripGitComment(s string) string {
+	if i := strings.IndexAny(s, ";#"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, " \t")
+}
+
+// gitconfigValue returns the decoded value text s. As in git, runs of
+// whitespace outside quotes are replaced with a single space.
+func gitconfigValue(s string) string {
+	var (
+		b      strings.Builder
+		quoted = false
+		space  = false
+	)
+	s = strings.TrimLeft(s, " \t")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !quoted && (c == ' ' || c == '\t') {
+			space = true
+			continue
+		} else if !quoted && (c == ';' || c == '#') {
+			break
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		switch {
+		case c == '"':
+			quoted = !quoted
+			continue
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch c = s[i]; c {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// resetEmpty is a wrapper for systemd unit files, where assigning a key
+// an empty value clears the values assigned to it before. Since values
+// are passed on as recorded, they are held until the input has been read
+// and then passed on in order, without those cleared.
+func resetEmpty(dest ini.Recorder, at *cursor) ini.Recorder {
+	return &resetter{dest: dest, at: at}
+}
+
+// resetDetected is resetEmpty for inputs read with -f auto, which only
+// clears values in inputs detected as systemd unit files.
+func resetDetected(dest ini.Recorder, at *cursor) ini.Recorder {
+	return &resetter{dest: dest, at: at, detected: true}
+}
+
+type resetter struct {
+	dest     ini.Recorder
+	at       *cursor
+	held     []heldValue
+	detected bool // Whether the input must be detected as systemd.
+	done     bool
+}
+
+func (r *resetter) Add(key, value string) {
+	if value == "" && (!r.detected || r.at.detected() == "systemd") {
+		r.clear(key)
+		return
+	}
+	r.hold(key, value)
+}
+
+// clear discards the held values of key.
+func (r *resetter) clear(key string) {
+	kept := r.held[:0]
+	for _, h := range r.held {
+		if h.key != key {
+			kept = append(kept, h)
+		}
+	}
+	r.held = kept
+}
+
+// hold holds value until the input has been read.
+func (r *resetter) hold(key, value string) {
+	r.held = append(r.held, heldValue{
+		key:     key,
+		value:   value,
+		loc:     r.at.loc,
+		section: r.at.section,
+	})
+}
+
+// Err passes each held value on to dest. It must be called once the input
+// has been read.
+func (r *resetter) Err() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	for _, h := range r.held {
+		r.at.loc, r.at.section = h.loc, h.section
+		r.dest.Add(h.key, h.value)
+	}
+	return nil
+}
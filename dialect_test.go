@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestDecodeBOM(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"\xEF\xBB\xBFa = 1\n", "a = 1\n"},
+		{"\xFF\xFEa\x00=\x00\xE9\x00\n\x00", "a=é\n"},
+		{"\xFE\xFF\x00a\x00=\x00\xE9\x00\n", "a=é\n"},
+		{"a = \xEF\xBB\xBF\n", "a = \xEF\xBB\xBF\n"},
+	}
+	for _, tt := range tests {
+		if got := runFilter(t, decodeBOM, tt.in); got != tt.want {
+			t.Errorf("decodeBOM(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWindowsLines(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a = 1 ; note\r", "a = 1"},
+		{"a = 1\t# note", "a = 1"},
+		{"a = x;y#z", "a = x;y#z"},
+		{"[sec] ; note\r", "[sec]"},
+		{"; comment\r", "; comment"},
+		{`a = "x ; y" ; note`, `a = "x ; y"`},
+		{`a = "tab\there \"q\" \\ \z"`, `a = "tab\there \"q\" \\ \\z"`},
+		{`a = "line\r\nbreak"`, `a = "line\r\nbreak"`},
+		{`a = "open`, `a = "open`},
+		{`a = "x" y`, `a = "x" y`},
+		{"a =", "a = "},
+	}
+	for _, tt := range tests {
+		if got := runFilter(t, windowsLines, tt.in+"\n"); got != tt.want+"\n" {
+			t.Errorf("windowsLines(%q) = %q; want %q", tt.in, got, tt.want+"\n")
+		}
+	}
+}
+
+func TestWindowsDialect(t *testing.T) {
+	filters, _ := dialectFilters("windows", ".")
+	in := "\xEF\xBB\xBF[s]\r\nk = \"a ; b\" ; note\r\nn = 1 ; note\r\n"
+	want := `{"s.k":["a ; b"],"s.n":["1"]}`
+	if got := readString(t, &source{dialect: filters}, &valueParser{raw: true}, in); got != want {
+		t.Errorf("-dialect windows: got %s; want %s", got, want)
+	}
+}
+
+func TestGitconfigLines(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`[remote "origin"]`, `[remote.origin]`},
+		{`[a "x\"y\\z"] ; note`, `[a.x"y\z]`},
+		{`[core] # note`, `[core]`},
+		{"\tname = value ; note", "\tname = value"},
+		{`a = "quoted ; not a comment" ; note`, `a = "quoted ; not a comment"`},
+		{`a = x  "y  z"  w`, `a = "x y  z w"`},
+		{`a = tab\tnew\nline\bslash\\`, `a = "tab\tnew\nline` + "\b" + `slash\\"`},
+		{`a =`, `a = ""`},
+		{"flag # note", "flag"},
+		{"a = one \\\n   two\nb = 2", "a = \"one two\"\n\nb = 2"},
+		{"a = \"x\\\ny\"", "a = \"xy\"\n"},
+	}
+	for _, tt := range tests {
+		if got := runFilter(t, gitconfigLines("."), tt.in+"\n"); got != tt.want+"\n" {
+			t.Errorf("gitconfigLines(%q) = %q; want %q", tt.in, got, tt.want+"\n")
+		}
+	}
+}
+
+func TestGitconfigDialect(t *testing.T) {
+	filters, _ := dialectFilters("gitconfig", ".")
+	in := "[remote \"origin\"]\n\turl = git@host:x.git ; note\n\tfetch = \"+refs/*\"\n[core]\n\tbare\n"
+	want := `{"remote.origin.url":["git@host:x.git"],"remote.origin.fetch":["+refs/*"],"core.bare":["true"]}`
+	src := &source{dialect: filters, rd: &ini.Reader{Separator: ".", True: "true"}}
+	if got := readString(t, src, &valueParser{raw: true}, in); got != want {
+		t.Errorf("-dialect gitconfig: got %s; want %s", got, want)
+	}
+}
+
+func TestSystemdLines(t *testing.T) {
+	long := strings.Repeat("x", 100000)
+	tests := []struct {
+		in, want string
+	}{
+		{"[Unit]", "[Unit]"},
+		{"; note", "; note"},
+		{"Environment=", `Environment= ""`},
+		{"Environment =  ", `Environment = ""`},
+		{"ExecStart=/bin/a \\\n    --flag \\\n# note\n    --other\nX=1", "ExecStart=/bin/a  --flag  --other\n\n\n\nX=1"},
+		{"A=" + long + "\\\n" + long, "A=" + long + " " + long + "\n"},
+	}
+	for _, tt := range tests {
+		if got := runFilter(t, systemdLines, tt.in+"\n"); got != tt.want+"\n" {
+			t.Errorf("systemdLines(%.40q) = %.40q; want %.40q", tt.in, got, tt.want+"\n")
+		}
+	}
+}
+
+func TestSystemdDialect(t *testing.T) {
+	filters, _ := dialectFilters("systemd", ".")
+	in := "[Service]\nEnvironment=A=1\nEnvironment=\nEnvironment=B=2\\\n  C=3\nUser=app\n"
+	want := `{"Service.Environment":["B=2 C=3"],"Service.User":["app"]}`
+	src := &source{dialect: filters, wrap: []wrapper{resetEmpty}}
+	if got := readString(t, src, &valueParser{raw: true}, in); got != want {
+		t.Errorf("-dialect systemd: got %s; want %s", got, want)
+	}
+}
+
+func TestPropertiesLines(t *testing.T) {
+	long := strings.Repeat("x", 100000)
+	tests := []struct {
+		in, want string
+	}{
+		{"# note", ""},
+		{"  ! note", ""},
+		{"a=1", "a = 1"},
+		{"a:1", "a = 1"},
+		{"a 1", "a = 1"},
+		{"a = b = c", "a = b = c"},
+		{`key\ with\ spaces = v`, "key with spaces = v"},
+		{`k\:x = v`, "k:x = v"},
+		{`a = tab\tx`, `a = "tab\tx"`},
+		{`u = \u00e9\uD83D\uDE00`, `u = "é😀"`},
+		{`a = \u12`, `a = "u12"`},
+		{"a", `a = ""`},
+		{`q = "x"`, `q = "\"x\""`},
+		{`a = x\\`, `a = "x\\"`},
+		{"a = one, \\\n    two\nb=2", "a = one, two\n\nb = 2"},
+		{"a = " + long + "\\\n" + long, "a = " + long + long + "\n"},
+	}
+	for _, tt := range tests {
+		if got := runFilter(t, propertiesLines, tt.in+"\n"); got != tt.want+"\n" {
+			t.Errorf("propertiesLines(%.40q) = %.40q; want %.40q", tt.in, got, tt.want+"\n")
+		}
+	}
+}
+
+func TestPropertiesDialect(t *testing.T) {
+	filters, _ := dialectFilters("properties", ".")
+	in := "# app\ndb.host: localhost\ndb.port 5432\ngreeting = caf\\u00e9\npath = C:\\\\dir\\\n    \\\\sub\n"
+	want := `{"db.host":["localhost"],"db.port":["5432"],"greeting":["café"],"path":["C:\\dir\\sub"]}`
+	if got := readString(t, &source{dialect: filters}, &valueParser{raw: true}, in); got != want {
+		t.Errorf("-dialect properties: got %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// diff returns a report of the differences between the outputs a and b:
+// an object with the keys only in b under "added", the keys only in a
+// under "removed", and the keys whose values differ under "changed", each
+// with its "old" and "new" value. Objects in both outputs are compared
+// key by key, with the keys of their members joined to theirs by sep.
+func diff(a, b interface{}, sep string) (*object, error) {
+	oa, err := ordered(a)
+	if err != nil {
+		return nil, err
+	}
+	ob, err := ordered(b)
+	if err != nil {
+		return nil, err
+	}
+	d := &differ{sep: sep, added: newObject(), removed: newObject(), changed: newObject()}
+	if err := d.compare("", oa, ob); err != nil {
+		return nil, err
+	}
+	report := newObject()
+	report.Set("added", d.added)
+	report.Set("removed", d.removed)
+	report.Set("changed", d.changed)
+	return report, nil
+}
+
+type differ struct {
+	sep                     string
+	added, removed, changed *object
+}
+
+// compare records the differences between the values a and b of key.
+func (d *differ) compare(key string, a, b interface{}) error {
+	oa, aok := a.(*object)
+	ob, bok := b.(*object)
+	if aok && bok {
+		for _, k := range oa.Keys() {
+			va, _ := oa.Get(k)
+			vb, ok := ob.Get(k)
+			if !ok {
+				d.removed.Set(d.join(key, k), va)
+				continue
+			}
+			if err := d.compare(d.join(key, k), va, vb); err != nil {
+				return err
+			}
+		}
+		for _, k := range ob.Keys() {
+			if _, ok := oa.Get(k); !ok {
+				vb, _ := ob.Get(k)
+				d.added.Set(d.join(key, k), vb)
+			}
+		}
+		return nil
+	}
+
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(ja, jb) {
+		change := newObject()
+		change.Set("old", a)
+		change.Set("new", b)
+		d.changed.Set(key, change)
+	}
+	return nil
+}
+
+func (d *differ) join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + d.sep + key
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	decode := func(s string) interface{} {
+		var v interface{}
+		if err := readOrdered(strings.NewReader(s), func(d interface{}) error {
+			v = d
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		a, b, want string
+	}{
+		{`{"a":1}`, `{"a":1}`, `{"added":{},"removed":{},"changed":{}}`},
+		{
+			`{"a":1,"db":{"host":"x","port":1,"opts":{"tls":true}},"gone":[1]}`,
+			`{"new":null,"db":{"port":2,"host":"x","user":"u","opts":{"tls":false}},"a":1}`,
+			`{"added":{"db.user":"u","new":null},"removed":{"gone":[1]},` +
+				`"changed":{"db.port":{"old":1,"new":2},"db.opts.tls":{"old":true,"new":false}}}`,
+		},
+		{`{"a":{"b":1}}`, `{"a":[1]}`, `{"added":{},"removed":{},"changed":{"a":{"old":{"b":1},"new":[1]}}}`},
+		{`{"a":[1,2]}`, `{"a":[2,1]}`, `{"added":{},"removed":{},"changed":{"a":{"old":[1,2],"new":[2,1]}}}`},
+	}
+	for _, tt := range tests {
+		d, err := diff(decode(tt.a), decode(tt.b), ".")
+		if err != nil {
+			t.Fatalf("diff(%s, %s) = %v", tt.a, tt.b, err)
+		}
+		p, err := marshalJSON(d, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(p); got != tt.want {
+			t.Errorf("diff(%s, %s):\ngot  %s\nwant %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// dupChecker rejects values of keys that have already been assigned. It
+// is shared by all inputs that are merged, so that keys assigned in more
+// than one of them are also rejected.
+type dupChecker struct {
+	mu   sync.Mutex
+	seen map[string]location
+	errs []string
+}
+
+func newDupChecker() *dupChecker {
+	return &dupChecker{seen: map[string]location{}}
+}
+
+func (d *dupChecker) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return dupCheck{Recorder: dest, d: d, at: at}
+}
+
+// reset forgets all assigned keys.
+func (d *dupChecker) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seen = map[string]location{}
+	d.errs = nil
+}
+
+type dupCheck struct {
+	ini.Recorder
+	d  *dupChecker
+	at *cursor
+}
+
+func (c dupCheck) Add(key, value string) {
+	loc := c.at.Location()
+	c.d.mu.Lock()
+	first, dup := c.d.seen[key]
+	switch {
+	case dup && c.at.operator() == opAppend:
+		// Values assigned with += are always appended.
+		dup = false
+	case dup:
+		c.d.errs = append(c.d.errs, fmt.Sprintf("%v: duplicate key %s (first assigned at %v)", loc, key, first))
+	default:
+		c.d.seen[key] = loc
+	}
+	c.d.mu.Unlock()
+
+	if !dup {
+		c.Recorder.Add(key, value)
+	}
+}
+
+// Err returns an error listing every duplicate assignment so far.
+func (c dupCheck) Err() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	if len(c.d.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("duplicate keys:\n  %s", strings.Join(c.d.errs, "\n  "))
+}
+
+// keepOne replaces the values of each key in doc with only its first or,
+// if last is true, its last value.
+func keepOne(doc map[string]interface{}, last bool) {
+	for k, v := range doc {
+		switch vs := v.(type) {
+		case []interface{}:
+			if len(vs) > 1 && last {
+				doc[k] = vs[len(vs)-1:]
+			} else if len(vs) > 1 {
+				doc[k] = vs[:1]
+			}
+		case []string:
+			if len(vs) > 1 && last {
+				doc[k] = vs[len(vs)-1:]
+			} else if len(vs) > 1 {
+				doc[k] = vs[:1]
+			}
+		}
+	}
+}
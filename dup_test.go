@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestDupCheck(t *testing.T) {
+	d := newDupChecker()
+	src := &source{
+		rd:     &ini.Reader{Separator: ".", True: "true"},
+		wrap:   []wrapper{d.wrap},
+		locate: true,
+		ops:    true,
+	}
+	paths, done := tempFiles(t, "a = 1\nb = 2\nl = x\n", "a = 3\nc = 4\nc = 5\nl += y\n")
+	defer done()
+
+	values := newParsedValues(&valueParser{})
+	if err := src.read(values, paths[0]); err != nil {
+		t.Fatalf("read(%s) = %v", paths[0], err)
+	}
+	err := src.read(values, paths[1])
+	if err == nil {
+		t.Fatalf("read(%s) = nil, want an error", paths[1])
+	}
+	want := "duplicate keys:\n" +
+		"  " + paths[1] + ":1: duplicate key a (first assigned at " + paths[0] + ":1)\n" +
+		"  " + paths[1] + ":3: duplicate key c (first assigned at " + paths[1] + ":2)"
+	if got := err.Error(); got != want {
+		t.Errorf("read(%s) = %q, want %q", paths[1], got, want)
+	}
+
+	// Duplicates are not recorded, but values appended with += are.
+	p, err := marshalJSON(orderedDocument(values), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(p), `{"a":[1],"b":[2],"l":["x","y"],"c":[4]}`; got != want {
+		t.Errorf("values = %s, want %s", got, want)
+	}
+
+	d.reset()
+	values = newParsedValues(&valueParser{})
+	if err := src.read(values, paths[0]); err != nil {
+		t.Errorf("read(%s) after reset = %v", paths[0], err)
+	}
+}
+
+func TestKeepOne(t *testing.T) {
+	doc := func() map[string]interface{} {
+		return map[string]interface{}{
+			"a": []interface{}{1, 2, 3},
+			"b": []string{"x", "y"},
+			"c": []interface{}{true},
+			"d": "scalar",
+		}
+	}
+	first := doc()
+	keepOne(first, false)
+	want := map[string]interface{}{
+		"a": []interface{}{1},
+		"b": []string{"x"},
+		"c": []interface{}{true},
+		"d": "scalar",
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("keepOne(first) = %v, want %v", first, want)
+	}
+
+	last := doc()
+	keepOne(last, true)
+	want = map[string]interface{}{
+		"a": []interface{}{3},
+		"b": []string{"y"},
+		"c": []interface{}{true},
+		"d": "scalar",
+	}
+	if !reflect.DeepEqual(last, want) {
+		t.Errorf("keepOne(last) = %v, want %v", last, want)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// encoder writes values to an output stream.
+type encoder interface {
+	Encode(v interface{}) error
+}
+
+// sectionLinesEncoder encodes JSON objects with each top-level member
+// written compactly on its own line. Values that do not encode to an
+// object are written compactly.
+type sectionLinesEncoder struct {
+	w          io.Writer
+	escapeHTML bool
+}
+
+func (e *sectionLinesEncoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+
+	obj, ok := g.(*object)
+	if !ok {
+		p, err := marshalJSON(g, e.escapeHTML)
+		if err != nil {
+			return err
+		}
+		_, err = e.w.Write(append(p, '\n'))
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range obj.Keys() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := marshalJSON(k, e.escapeHTML)
+		if err != nil {
+			return err
+		}
+		member, _ := obj.Get(k)
+		value, err := marshalJSON(member, e.escapeHTML)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("\n  ")
+		buf.Write(name)
+		buf.WriteString(": ")
+		buf.Write(value)
+	}
+	if obj.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("}\n")
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// jsonOptions controls the formatting of JSON output.
+type jsonOptions struct {
+	indent     string // Indentation of nested values.
+	escapeHTML bool   // Whether to escape '<', '>', and '&' in strings.
+}
+
+// asciiWriter writes JSON with every non-ASCII character escaped as \uXXXX,
+// using surrogate pairs for characters outside the Basic Multilingual
+// Plane. Each write must end on a character boundary.
+type asciiWriter struct {
+	w io.Writer
+}
+
+func (a asciiWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	var buf bytes.Buffer
+	for len(p) > 0 {
+		r, size := utf8.DecodeRune(p)
+		switch {
+		case r < utf8.RuneSelf || r == utf8.RuneError && size == 1:
+			buf.WriteByte(p[0])
+		case r > 0xFFFF:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&buf, "\\u%04x\\u%04x", r1, r2)
+		default:
+			fmt.Fprintf(&buf, "\\u%04x", r)
+		}
+		p = p[size:]
+	}
+	if _, err := buf.WriteTo(a.w); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// encoderFor returns a function that creates encoders for the given
+// output format. lines and compact select the layout of JSON output, and
+// jopts and gopts control JSON and gostruct output.
+func encoderFor(format string, lines, compact bool, jopts jsonOptions, gopts goOptions) (func(io.Writer) encoder, error) {
+	switch {
+	case format == "yaml":
+		return func(w io.Writer) encoder { return &yamlEncoder{w: w} }, nil
+	case format == "toml":
+		return func(w io.Writer) encoder { return &tomlEncoder{w: w} }, nil
+	case format == "gostruct":
+		return func(w io.Writer) encoder { return &goStructEncoder{w: w, opts: gopts} }, nil
+	case format == "json5":
+		return func(w io.Writer) encoder { return &json5Encoder{w: w} }, nil
+	case format == "msgpack":
+		return func(w io.Writer) encoder { return &msgpackEncoder{w: w} }, nil
+	case format == "cbor":
+		return func(w io.Writer) encoder { return &cborEncoder{w: w} }, nil
+	case format == "env", format == "env=export":
+		export := format == "env=export"
+		return func(w io.Writer) encoder { return &envEncoder{w: w, export: export} }, nil
+	case format != "json":
+		return nil, fmt.Errorf("invalid output format %+q: must be one of json, json5, yaml, toml, gostruct, env, env=export, msgpack, or cbor", format)
+	case lines:
+		return func(w io.Writer) encoder { return &sectionLinesEncoder{w: w, escapeHTML: jopts.escapeHTML} }, nil
+	case compact:
+		return func(w io.Writer) encoder {
+			enc := json.NewEncoder(w)
+			enc.SetEscapeHTML(jopts.escapeHTML)
+			return enc
+		}, nil
+	}
+	return func(w io.Writer) encoder {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", jopts.indent)
+		enc.SetEscapeHTML(jopts.escapeHTML)
+		return enc
+	}, nil
+}
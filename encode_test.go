@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSectionLinesEncoder(t *testing.T) {
+	const in = "[a]\nx = 1\ny = 2\n[b]\nz = <3>\n"
+	tests := []struct {
+		name   string
+		nested bool
+		in     string
+		want   string
+	}{
+		{"flat", false, in, "{\n  \"a.x\": [1],\n  \"a.y\": [2],\n  \"b.z\": [\"\\u003c3\\u003e\"]\n}\n"},
+		{"nested", true, in, "{\n  \"a\": {\"x\":[1],\"y\":[2]},\n  \"b\": {\"z\":[\"\\u003c3\\u003e\"]}\n}\n"},
+		{"empty", false, "", "{}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := orderedDocument(readValues(t, &source{}, &valueParser{}, tt.in))
+			var v interface{} = doc
+			if tt.nested {
+				v = nest(doc, ".")
+			}
+
+			var buf bytes.Buffer
+			if err := (&sectionLinesEncoder{w: &buf, escapeHTML: true}).Encode(v); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			if got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+			if !json.Valid(buf.Bytes()) {
+				t.Errorf("Encode() = %s, which is not valid JSON", got)
+			}
+			lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+			if len(lines) < 2 {
+				return
+			}
+			for _, line := range lines[1 : len(lines)-1] {
+				member := strings.TrimSuffix(strings.TrimPrefix(line, "  "), ",")
+				if !json.Valid([]byte("{" + member + "}")) {
+					t.Errorf("line %q is not a single compact member", line)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONOptions(t *testing.T) {
+	v := newObject()
+	v.Set("a", []interface{}{"<&>"})
+	tests := []struct {
+		compact bool
+		jopts   jsonOptions
+		want    string
+	}{
+		{false, jsonOptions{indent: "  "}, "{\n  \"a\": [\n    \"<&>\"\n  ]\n}\n"},
+		{false, jsonOptions{indent: "\t", escapeHTML: true}, "{\n\t\"a\": [\n\t\t\"\\u003c\\u0026\\u003e\"\n\t]\n}\n"},
+		{true, jsonOptions{indent: "  "}, "{\"a\":[\"<&>\"]}\n"},
+		{true, jsonOptions{escapeHTML: true}, "{\"a\":[\"\\u003c\\u0026\\u003e\"]}\n"},
+	}
+	for _, tt := range tests {
+		newEncoder, err := encoderFor("json", false, tt.compact, tt.jopts, goOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := newEncoder(&buf).Encode(v); err != nil {
+			t.Fatalf("Encode = %v", err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("compact %t, %+v: got %q, want %q", tt.compact, tt.jopts, got, tt.want)
+		}
+	}
+}
+
+func TestASCIIWriter(t *testing.T) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(asciiWriter{&buf})
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode([]string{"café", "☕ \U0001F600", "�", "a\xffb", "<>"}); err != nil {
+		t.Fatalf("Encode = %v", err)
+	}
+	want := `["caf\u00e9","\u2615 \ud83d\ude00","\ufffd","a\ufffdb","<>"]` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("asciiWriter wrote %s, want %s", got, want)
+	}
+	var back []string
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil || back[1] != "☕ \U0001F600" {
+		t.Errorf("asciiWriter output decodes to %q, %v", back, err)
+	}
+}
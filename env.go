@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// envValues expands environment variable references in values before
+// recording them.
+type envValues struct {
+	ini.Recorder
+}
+
+func expandValues(dest ini.Recorder, _ *cursor) ini.Recorder {
+	return envValues{dest}
+}
+
+func (v envValues) Add(key, value string) {
+	v.Recorder.Add(key, expandEnv(value))
+}
+
+// expandEnv replaces ${NAME} and $NAME in s with the value of the
+// environment variable NAME, or the empty string if it is unset. A '$'
+// preceded by a backslash, or not followed by a name, is kept as is
+// (without the backslash).
+func expandEnv(s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '$':
+			buf.WriteByte('$')
+			i++
+			continue
+		case c != '$':
+			buf.WriteByte(c)
+			continue
+		}
+
+		if i+1 < len(s) && s[i+1] == '{' {
+			if end := strings.IndexByte(s[i+2:], '}'); end > 0 {
+				buf.WriteString(os.Getenv(s[i+2 : i+2+end]))
+				i += end + 2
+				continue
+			}
+		}
+		n := nameLen(s[i+1:])
+		if n == 0 {
+			buf.WriteByte(c)
+			continue
+		}
+		buf.WriteString(os.Getenv(s[i+1 : i+1+n]))
+		i += n
+	}
+	return buf.String()
+}
+
+// nameLen returns the length of the environment variable name at the
+// start of s.
+func nameLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && c >= '0' && c <= '9':
+		default:
+			return i
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("INI2JSON_TEST_A", "one")
+	os.Setenv("INI2JSON_TEST_B", "two words")
+	os.Unsetenv("INI2JSON_TEST_UNSET")
+	defer os.Unsetenv("INI2JSON_TEST_A")
+	defer os.Unsetenv("INI2JSON_TEST_B")
+
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"$INI2JSON_TEST_A", "one"},
+		{"${INI2JSON_TEST_B}!", "two words!"},
+		{"$INI2JSON_TEST_A/$INI2JSON_TEST_B", "one/two words"},
+		{"${INI2JSON_TEST_A}x", "onex"},
+		{"[$INI2JSON_TEST_UNSET]", "[]"},
+		{`\$INI2JSON_TEST_A`, "$INI2JSON_TEST_A"},
+		{"cost: $5, $", "cost: $5, $"},
+		{"${unclosed", "${unclosed"},
+		{"${}", "${}"},
+	}
+	for _, tt := range tests {
+		if got := expandEnv(tt.in); got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	src := &source{wrap: []wrapper{expandValues}}
+	got := readString(t, src, &valueParser{}, "[db]\nhost = $INI2JSON_TEST_A.local\nn = ${INI2JSON_TEST_UNSET}1\n")
+	if want := `{"db.host":["one.local"],"db.n":[1]}`; got != want {
+		t.Errorf("-E: got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// metaKey is the top-level key of the structure recorded by -fidelity.
+const metaKey = "__meta__"
+
+// fidelity records the lines of its input, so that -reverse can write the
+// input again as it was, with only values that changed rewritten. Lines
+// that assign values are recorded as the key and index of the value they
+// assign, with the text around the value, and other lines as they are.
+type fidelity struct {
+	sep     string
+	casing  ini.Casing
+	bareKey string // Key that bare lines are recorded under, if any.
+
+	lines        []*fidelityLine
+	newline      string
+	finalNewline bool
+	counts       map[string]int // Number of values of each key so far.
+}
+
+// fidelityLine is a line recorded by fidelity. Lines that assign a value
+// have a key, and section headers have a section.
+type fidelityLine struct {
+	text    string
+	key     string
+	index   int
+	before  string // Text of the line before the value.
+	after   string // Text of the line after the value.
+	section string
+	header  bool
+}
+
+func newFidelity(sep string, casing ini.Casing, bareKey string) *fidelity {
+	f := &fidelity{sep: sep, casing: casing, bareKey: bareKey}
+	f.reset()
+	return f
+}
+
+// filter returns a filter that records each line of its input without
+// modifying it.
+func (f *fidelity) filter() filter {
+	return func(w io.Writer, r io.Reader) error {
+		br := bufio.NewReader(r)
+		prefix := ""
+		for {
+			line, err := br.ReadString('\n')
+			if line != "" {
+				if _, err := io.WriteString(w, line); err != nil {
+					return err
+				}
+				prefix = f.record(line, prefix)
+			}
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// record records line, read in the section named by prefix, and returns
+// the section of the lines after it.
+func (f *fidelity) record(line, prefix string) string {
+	text := strings.TrimSuffix(line, "\n")
+	f.finalNewline = len(text) < len(line)
+	if f.finalNewline && f.newline == "" {
+		f.newline = "\n"
+		if strings.HasSuffix(text, "\r") {
+			f.newline = "\r\n"
+		}
+	}
+	text = strings.TrimSuffix(text, "\r")
+
+	l := &fidelityLine{text: text}
+	f.lines = append(f.lines, l)
+	t := strings.TrimSpace(text)
+	switch {
+	case t == "", t[0] == ';', t[0] == '#':
+		return prefix
+	case isSectionHeader(t):
+		l.header = true
+		l.section = applyCasing(f.casing, sectionName(t))
+		return l.section
+	}
+
+	key := t
+	l.before = text
+	if f.bareKey != "" && isBareLine(text) {
+		key, l.before = f.bareKey, ""
+	} else if i := strings.IndexByte(text, '='); i >= 0 {
+		key = strings.TrimSpace(text[:i])
+		value := strings.TrimLeft(text[i+1:], " \t")
+		l.before = text[:len(text)-len(value)]
+	}
+	value := text[len(l.before):]
+	l.after = value[len(strings.TrimRight(value, " \t")):]
+
+	key = applyCasing(f.casing, key)
+	if prefix != "" {
+		key = prefix + f.sep + key
+	}
+	l.key, l.index = key, f.counts[key]
+	f.counts[key]++
+	return prefix
+}
+
+// meta returns the structure recorded for the values in doc, which must
+// have every value of a key in an array: an object with the "lines" of
+// the input, its "newline", and whether it ends in one.
+func (f *fidelity) meta(doc *object) *object {
+	lines := make([]interface{}, len(f.lines))
+	for i, l := range f.lines {
+		switch {
+		case l.header:
+			h := newObject()
+			h.Set("section", l.section)
+			h.Set("text", l.text)
+			lines[i] = h
+		case l.key != "":
+			e := newObject()
+			e.Set("key", l.key)
+			e.Set("index", l.index)
+			e.Set("before", l.before)
+			e.Set("text", l.text[len(l.before):len(l.text)-len(l.after)])
+			e.Set("after", l.after)
+			switch vals := doc.values[l.key].(type) {
+			case []interface{}:
+				if l.index < len(vals) {
+					e.Set("value", vals[l.index])
+				}
+			case []string:
+				if l.index < len(vals) {
+					e.Set("value", vals[l.index])
+				}
+			}
+			lines[i] = e
+		default:
+			lines[i] = l.text
+		}
+	}
+	meta := newObject()
+	meta.Set("lines", lines)
+	meta.Set("newline", f.newline)
+	meta.Set("final_newline", f.finalNewline)
+	return meta
+}
+
+// reset discards all recorded lines.
+func (f *fidelity) reset() {
+	f.lines = nil
+	f.newline = ""
+	f.finalNewline = false
+	f.counts = map[string]int{}
+}
+
+// writeFidelity writes doc, decoded by readOrdered, as the INI input whose
+// lines are recorded in meta. Lines are written as they were, except that
+// lines assigning a value that changed are written with the new value,
+// and lines assigning a value that was removed are dropped. Values added
+// to a key are written after its last line, keys added to a section after
+// its last line, and keys in new sections at the end.
+func writeFidelity(w io.Writer, doc, meta *object, sep string) error {
+	values := map[string][]interface{}{}
+	var order []string
+	flattenValues(doc, "", sep, values, &order)
+
+	linesValue, _ := meta.Get("lines")
+	lines, _ := linesValue.([]interface{})
+	nl, _ := stringMember(meta, "newline")
+	if nl == "" {
+		nl = "\n"
+	}
+
+	var (
+		out      []string
+		after    = map[int][]string{} // Lines to write after each line of out.
+		used     = map[string]int{}   // Number of values of each key written.
+		last     = map[string]int{}   // Index in out of each key's last line.
+		prefixes = map[string]string{}
+		sections = map[string]int{"": -1} // Index in out of each section's last line.
+		section  string
+	)
+	for _, line := range lines {
+		l, ok := line.(*object)
+		if !ok {
+			text, _ := line.(string)
+			out = append(out, text)
+			continue
+		}
+		if name, ok := stringMember(l, "section"); ok {
+			text, _ := stringMember(l, "text")
+			out = append(out, text)
+			section = name
+			sections[section] = len(out) - 1
+			continue
+		}
+
+		key, _ := stringMember(l, "key")
+		before, _ := stringMember(l, "before")
+		text, _ := stringMember(l, "text")
+		rest, _ := stringMember(l, "after")
+		index := 0
+		if n, ok := l.values["index"].(json.Number); ok {
+			i, _ := n.Int64()
+			index = int(i)
+		}
+		prefixes[key] = before
+		vals := values[key]
+		if index >= len(vals) {
+			continue
+		}
+		if index >= used[key] {
+			used[key] = index + 1
+		}
+		if orig, ok := l.Get("value"); !ok || !sameJSON(orig, vals[index]) {
+			v, err := iniValue(vals[index])
+			if err != nil {
+				return fmt.Errorf("cannot write %s: %v", key, err)
+			}
+			if before != "" && !strings.Contains(before, "=") {
+				before = strings.TrimRight(before, " \t") + " = "
+			}
+			text = v
+		}
+		out = append(out, before+text+rest)
+		last[key] = len(out) - 1
+		sections[section] = len(out) - 1
+	}
+
+	added := newObject()
+	for _, key := range order {
+		vals := values[key]
+		if used[key] >= len(vals) {
+			continue
+		}
+		at, ok := last[key]
+		before := prefixes[key]
+		if !ok {
+			name := ""
+			for s := range sections {
+				if s != "" && strings.HasPrefix(key, s+sep) && len(s) > len(name) {
+					name = s
+				}
+			}
+			if name == "" && strings.Contains(key, sep) {
+				added.Set(key, vals[used[key]:])
+				continue
+			}
+			at, before = sections[name], strings.TrimPrefix(key, name+sep)+" = "
+		}
+		for _, v := range vals[used[key]:] {
+			text, err := iniValue(v)
+			if err != nil {
+				return fmt.Errorf("cannot write %s: %v", key, err)
+			}
+			after[at] = append(after[at], before+text)
+		}
+	}
+
+	written := after[-1]
+	for i, line := range out {
+		written = append(written, line)
+		written = append(written, after[i]...)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(strings.Join(written, nl))
+	if final, _ := meta.Get("final_newline"); len(written) > 0 && (final == true || added.Len() > 0) {
+		buf.WriteString(nl)
+	}
+	if _, err := buf.WriteTo(w); err != nil || added.Len() == 0 {
+		return err
+	}
+	iw := &iniWriter{w: w, sep: sep, written: len(written) > 0}
+	iw.section("", nest(added, sep))
+	return iw.err
+}
+
+// flattenValues adds the values of each key in obj, with its name prefixed
+// by prefix and sep, to values, and each new key to order. Objects are
+// read as nested sections, with the values of a key that has nested keys
+// under leafKey.
+func flattenValues(obj *object, prefix, sep string, values map[string][]interface{}, order *[]string) {
+	for _, k := range obj.Keys() {
+		v, _ := obj.Get(k)
+		name := k
+		switch {
+		case k == leafKey && prefix != "":
+			name = prefix
+		case prefix != "":
+			name = prefix + sep + k
+		case k == metaKey || k == "_comments" || k == "_raw" || k == "_descriptions":
+			continue
+		}
+		switch v := v.(type) {
+		case *object:
+			flattenValues(v, name, sep, values, order)
+			continue
+		case []interface{}:
+			if _, ok := values[name]; !ok {
+				*order = append(*order, name)
+			}
+			values[name] = append(values[name], v...)
+			continue
+		}
+		if _, ok := values[name]; !ok {
+			*order = append(*order, name)
+		}
+		values[name] = append(values[name], v)
+	}
+}
+
+// stringMember returns the string value of key in obj.
+func stringMember(obj *object, key string) (string, bool) {
+	v, _ := obj.Get(key)
+	s, ok := v.(string)
+	return s, ok
+}
+
+// sameJSON returns whether a and b have the same JSON encoding.
+func sameJSON(a, b interface{}) bool {
+	p, err := marshalJSON(a, false)
+	if err != nil {
+		return false
+	}
+	q, err := marshalJSON(b, false)
+	return err == nil && bytes.Equal(p, q)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// readFidelity reads text with -fidelity and returns its output decoded as
+// -reverse decodes it.
+func readFidelity(t *testing.T, text string) *object {
+	t.Helper()
+	f := newFidelity(".", 0, "")
+	src := &source{filters: []filter{f.filter()}}
+	values := readValues(t, src, &valueParser{}, text)
+	p, err := marshalJSON((&outputOptions{layout: f}).output(values), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc *object
+	if err := readOrdered(bytes.NewReader(p), func(v interface{}) error {
+		doc = v.(*object)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestFidelity(t *testing.T) {
+	const in = "; app config\nname = app   ; trailing\n\n[db]\n  host=localhost\nport = 5432\nport = 5433\n# end\n[web]\nroot = /srv"
+	tests := []struct {
+		name   string
+		change func(doc *object)
+		want   string
+	}{
+		{"unchanged", func(*object) {}, in},
+		{"changed", func(doc *object) {
+			doc.Set("db.host", []interface{}{"db.internal"})
+			doc.Set("db.port", []interface{}{json.Number("5432"), json.Number("6000")})
+		}, "; app config\nname = app   ; trailing\n\n[db]\n  host=\"db.internal\"\nport = 5432\nport = 6000\n# end\n[web]\nroot = /srv"},
+		{"removed", func(doc *object) {
+			doc.Set("db.port", []interface{}{json.Number("5432")})
+			delete(doc.values, "name")
+			doc.keys = doc.keys[1:] // name is the first key.
+		}, "; app config\n\n[db]\n  host=localhost\nport = 5432\n# end\n[web]\nroot = /srv"},
+		{"added", func(doc *object) {
+			doc.Set("db.port", []interface{}{json.Number("5432"), json.Number("5433"), json.Number("5434")})
+			doc.Set("db.user", []interface{}{"u"})
+			doc.Set("top", []interface{}{true})
+			doc.Set("cache.size", []interface{}{json.Number("10")})
+		}, "; app config\nname = app   ; trailing\ntop = true\n\n[db]\n  host=localhost\nport = 5432\nport = 5433\nport = 5434\nuser = \"u\"\n# end\n[web]\nroot = /srv\n\n[cache]\nsize = 10\n"},
+	}
+	for _, tt := range tests {
+		doc := readFidelity(t, in)
+		tt.change(doc)
+		var buf bytes.Buffer
+		if err := writeINI(&buf, doc, "."); err != nil {
+			t.Fatalf("%s: writeINI = %v", tt.name, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s:\ngot\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// filter rewrites an INI input stream before it is parsed.
+type filter func(w io.Writer, r io.Reader) error
+
+// apply returns a reader that yields the output of f for r. The returned
+// reader must be closed once parsing is done to release the filter.
+func (f filter) apply(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(f(pw, r))
+	}()
+	return pr
+}
+
+// maxLineSize is the length of the longest line filters read.
+const maxLineSize = 1 << 30
+
+// lineScanner returns a scanner reading the lines of r, up to maxLineSize
+// bytes long.
+func lineScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxLineSize)
+	return sc
+}
+
+// lineFilter returns a filter that passes each line of its input through fn.
+func lineFilter(fn func(line string) string) filter {
+	return func(w io.Writer, r io.Reader) error {
+		sc := lineScanner(r)
+		for sc.Scan() {
+			if _, err := io.WriteString(w, fn(sc.Text())+"\n"); err != nil {
+				return err
+			}
+		}
+		return sc.Err()
+	}
+}
+
+// bareLines returns a filter that rewrites lines without a key as
+// assignments to key. A line is bare if it is not blank, a comment, or
+// a section header and does not contain an '=' delimiter, so the order
+// of bare lines is kept as the order of values of key in their section.
+func bareLines(key string) filter {
+	return lineFilter(func(line string) string {
+		if !isBareLine(line) {
+			return line
+		}
+		return key + " = " + quoteValue(strings.TrimSpace(line))
+	})
+}
+
+func isBareLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	switch line[0] {
+	case ';', '#', '[':
+		return false
+	}
+	return !strings.Contains(line, "=")
+}
+
+// quoteValue returns s as a double-quoted INI value.
+func quoteValue(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	s = strings.Replace(s, "\r", `\r`, -1)
+	s = strings.Replace(s, "\t", `\t`, -1)
+	return `"` + s + `"`
+}
+
+// assignments returns a filter that rewrites the key and value of each
+// assignment line using key and value. The key and value passed to each
+// have tabs and spaces around the '=' trimmed; quoted values are passed
+// with their quotes.
+func assignments(key, value func(string) string) filter {
+	return lineFilter(func(line string) string {
+		t := strings.TrimSpace(line)
+		if t == "" || t[0] == ';' || t[0] == '#' || isSectionHeader(t) {
+			return line
+		}
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return line
+		}
+		k := strings.Trim(line[:i], " \t")
+		v := strings.Trim(line[i+1:], " \t")
+		return key(k) + " = " + value(v)
+	})
+}
+
+// trimmedKeys trims spaces and tabs from the end of each key recorded in
+// the Recorder it wraps, so that tabs padding an '=' are never part of a
+// key.
+type trimmedKeys struct {
+	ini.Recorder
+}
+
+func (t trimmedKeys) Add(key, value string) {
+	t.Recorder.Add(strings.TrimRight(key, " \t"), value)
+}
+
+// spaceTabs returns s with each tab replaced by a space.
+func spaceTabs(s string) string {
+	return strings.Replace(s, "\t", " ", -1)
+}
+
+// collapseSpaces returns s with each run of spaces and tabs replaced by a
+// single space.
+func collapseSpaces(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t'
+	}), " ")
+}
+
+func identity(s string) string {
+	return s
+}
+
+// emptyValues returns a filter that rewrites assignments with an empty
+// value, such as "key =", according to mode: "true" leaves only the key,
+// which the reader assigns TRUE, "null" assigns null, "empty-string"
+// assigns an empty string, and "omit" removes the line. Quoted empty
+// values are not affected.
+func emptyValues(mode string) filter {
+	return lineFilter(func(line string) string {
+		t := strings.TrimSpace(line)
+		if t == "" || t[0] == ';' || t[0] == '#' || isSectionHeader(t) {
+			return line
+		}
+		i := strings.IndexByte(line, '=')
+		if i < 0 || strings.TrimSpace(line[i+1:]) != "" {
+			return line
+		}
+		key := strings.TrimSpace(line[:i])
+		switch mode {
+		case "true":
+			return key
+		case "null":
+			return key + " = null"
+		case "empty-string":
+			return key + ` = ""`
+		case "omit":
+			return ""
+		}
+		return line
+	})
+}
+
+// syntax returns a filter that rewrites assignments delimited by any of
+// the characters in delims to be delimited by '=', and comments starting
+// with any of the characters in comments to start with a single ';'. An
+// assignment is delimited by the first delimiter in its line, so values
+// may contain delimiters.
+func syntax(delims, comments string) filter {
+	return lineFilter(func(line string) string {
+		t := strings.TrimSpace(line)
+		if t == "" || isSectionHeader(t) {
+			return line
+		}
+		if strings.IndexByte(comments, t[0]) >= 0 {
+			i := strings.Index(line, t)
+			return line[:i] + ";" + strings.TrimLeft(t, comments)
+		}
+		i := strings.IndexAny(line, "="+delims)
+		if i < 0 || line[i] == '=' {
+			return line
+		}
+		return line[:i] + "=" + line[i+1:]
+	})
+}
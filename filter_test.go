@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// tempFiles writes each of texts to a file in a new temporary directory,
+// named test.ini for the first and test2.ini, test3.ini, and so on for
+// the others, and returns their paths and a function that removes them.
+func tempFiles(t testing.TB, texts ...string) ([]string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "ini2json-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, len(texts))
+	for i, text := range texts {
+		name := "test.ini"
+		if i > 0 {
+			name = fmt.Sprintf("test%d.ini", i+1)
+		}
+		paths[i] = filepath.Join(dir, name)
+		if err := ioutil.WriteFile(paths[i], []byte(text), 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return paths, func() { os.RemoveAll(dir) }
+}
+
+// readValues reads text with src, as the contents of a file named
+// test.ini, into a recorder using parser and returns it.
+func readValues(t *testing.T, src *source, parser *valueParser, text string) parsedValues {
+	t.Helper()
+	paths, done := tempFiles(t, text)
+	defer done()
+
+	if src.rd == nil {
+		src.rd = &ini.Reader{Separator: ".", True: "true"}
+	}
+	values := newParsedValues(parser)
+	if err := src.read(values, paths[0]); err != nil {
+		t.Fatalf("read(%q) = %v", text, err)
+	}
+	return values
+}
+
+// readString returns the values read from text by readValues as JSON.
+func readString(t *testing.T, src *source, parser *valueParser, text string) string {
+	t.Helper()
+	p, err := marshalJSON(orderedDocument(readValues(t, src, parser, text)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(p)
+}
+
+// runFilter returns the output of f for in.
+func runFilter(t *testing.T, f filter, in string) string {
+	t.Helper()
+	rc := f.apply(strings.NewReader(in))
+	defer rc.Close()
+	p, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("filter(%q) = %v", in, err)
+	}
+	return string(p)
+}
+
+func TestLineFilterLongLines(t *testing.T) {
+	line := "k = " + strings.Repeat("x", 100000)
+	if got := runFilter(t, lineFilter(identity), line+"\n"); got != line+"\n" {
+		t.Errorf("lineFilter changed a %d-byte line to %d bytes", len(line)+1, len(got))
+	}
+}
+
+func TestAssignmentsTabs(t *testing.T) {
+	tests := []struct {
+		name       string
+		key, value func(string) string
+		in, want   string
+	}{
+		{"padded", identity, identity, "key\t=\tvalue\n", "key = value\n"},
+		{"value tabs kept", identity, identity, "key = a\tb\n", "key = a\tb\n"},
+		{"key tabs kept", identity, identity, "a\tb = 1\n", "a\tb = 1\n"},
+		{"key tabs spaced", spaceTabs, identity, "a\tb\t= 1\n", "a b = 1\n"},
+		{"collapsed", identity, collapseSpaces, "key = a \t\tb  c\n", "key = a b c\n"},
+		{"other lines", spaceTabs, collapseSpaces, "[a\tb]\n; x\t y\nbare\tline\n", "[a\tb]\n; x\t y\nbare\tline\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runFilter(t, assignments(tt.key, tt.value), tt.in); got != tt.want {
+				t.Errorf("assignments(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTabPaddedKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		filters  []filter
+		in, want string
+	}{
+		{"padded", nil, "[s]\nkey\t=\tvalue\n", `{"s.key":["value"]}`},
+		{"key tabs kept", nil, "a\tb\t= 1\n", `{"a\tb":["1"]}`},
+		{"key tabs spaced", []filter{assignments(spaceTabs, identity)}, "a\tb\t= 1\n", `{"a b":["1"]}`},
+		{"value tabs kept", nil, "key = \"a\tb\"\n", `{"key":["a\tb"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := readString(t, &source{filters: tt.filters}, &valueParser{raw: true}, tt.in)
+			if got != tt.want {
+				t.Errorf("read(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyValues(t *testing.T) {
+	in := "[s]\nkey =\nquoted = \"\"\nset = 1\n"
+	tests := []struct {
+		mode, want string
+	}{
+		{"null", "[s]\nkey = null\nquoted = \"\"\nset = 1\n"},
+		{"empty-string", "[s]\nkey = \"\"\nquoted = \"\"\nset = 1\n"},
+		{"omit", "[s]\n\nquoted = \"\"\nset = 1\n"},
+	}
+	for _, tt := range tests {
+		if got := runFilter(t, emptyValues(tt.mode), in); got != tt.want {
+			t.Errorf("emptyValues(%q)(%q) = %q, want %q", tt.mode, in, got, tt.want)
+		}
+	}
+}
+
+func TestBareLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		in, want string
+	}{
+		{"array", "[hosts]\nalpha\nbeta\ngamma\n", `{"hosts.line":["alpha","beta","gamma"]}`},
+		{"quoted", "say \"hi\"\\now\n", `{"line":["say \"hi\"\\now"]}`},
+		{"assignments kept", "[s]\nfirst\nkey = value\nsecond\n", `{"s.line":["first","second"],"s.key":["value"]}`},
+		{"equals is an assignment", "[s]\nbare\nurl?a=b\n", `{"s.line":["bare"],"s.url?a":["b"]}`},
+		{"comments and blanks", "; note\n\n# note\nonly\n", `{"line":["only"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &source{filters: []filter{bareLines("line")}}
+			got := readString(t, src, &valueParser{raw: true}, tt.in)
+			if got != tt.want {
+				t.Errorf("read(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// outputString returns the output of out for values as JSON.
+func outputString(t *testing.T, out *outputOptions, values ini.Recorder) string {
+	t.Helper()
+	p, err := marshalJSON(out.output(values), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(p)
+}
+
+func TestSyntax(t *testing.T) {
+	const in = "[a:b]\nkey: value\nurl = http://x\nother : a=b\n  // note\n/ one\n# hash\nbare\n"
+	const want = "[a:b]\nkey= value\nurl = http://x\nother = a=b\n  ; note\n; one\n# hash\nbare\n"
+	if got := runFilter(t, syntax(":", "/"), in); got != want {
+		t.Errorf("syntax(:, /)(%q) = %q, want %q", in, got, want)
+	}
+
+	src := &source{filters: []filter{syntax(":|", "/!")}}
+	got := readString(t, src, &valueParser{}, "a: 1\nb | 2\n! skip: 3\n[s]\nc = x:y\n")
+	if want := `{"a":[1],"b":[2],"s.c":["x:y"]}`; got != want {
+		t.Errorf("read with -delim :| -comment-chars /! = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// flatArrays returns v with each array and object in an object replaced by
+// a member for each of its elements, so that every member is a scalar.
+// Keys of object members are joined by sep, and keys of array elements
+// are written in style: index (key.0, using sep), brackets (key[0]), or
+// repeat, which repeats key for each element. Values that are not objects
+// are returned as they are.
+func flatArrays(v interface{}, style, sep string) (interface{}, error) {
+	g, err := ordered(v)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := g.(*object)
+	if !ok {
+		return g, nil
+	}
+	var flat repeatedObject
+	flat.add("", obj, style, sep)
+	if style == "repeat" {
+		return flat, nil
+	}
+	out := newObject()
+	for _, m := range flat {
+		out.Set(m.key, m.value)
+	}
+	return out, nil
+}
+
+// repeatedObject is a JSON object whose members may have the same key.
+type repeatedObject []member
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+// add adds the members for v, named by key, to o.
+func (o *repeatedObject) add(key string, v interface{}, style, sep string) {
+	switch c := v.(type) {
+	case *object:
+		if c.Len() == 0 && key != "" {
+			break
+		}
+		for _, k := range c.Keys() {
+			elem, _ := c.Get(k)
+			if key != "" {
+				k = key + sep + k
+			}
+			o.add(k, elem, style, sep)
+		}
+		return
+	case []interface{}:
+		if len(c) == 0 {
+			break
+		}
+		for i, elem := range c {
+			switch style {
+			case "index":
+				o.add(key+sep+strconv.Itoa(i), elem, style, sep)
+			case "brackets":
+				o.add(key+"["+strconv.Itoa(i)+"]", elem, style, sep)
+			default:
+				o.add(key, elem, style, sep)
+			}
+		}
+		return
+	}
+	*o = append(*o, member{key: key, value: v})
+}
+
+func (o repeatedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := marshalJSON(m.key, false)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(m.value, false)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
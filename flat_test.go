@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlatArrays(t *testing.T) {
+	const in = `{"a":[1,2],"db":{"hosts":[{"name":"x"},{"name":"y"}],"opts":{}},"m":[[1],[2,3]],"e":[],"s":"v"}`
+	var v interface{}
+	if err := readOrdered(strings.NewReader(in), func(d interface{}) error {
+		v = d
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		style, want string
+	}{
+		{"index", `{"a.0":1,"a.1":2,"db.hosts.0.name":"x","db.hosts.1.name":"y","db.opts":{},"m.0.0":1,"m.1.0":2,"m.1.1":3,"e":[],"s":"v"}`},
+		{"brackets", `{"a[0]":1,"a[1]":2,"db.hosts[0].name":"x","db.hosts[1].name":"y","db.opts":{},"m[0][0]":1,"m[1][0]":2,"m[1][1]":3,"e":[],"s":"v"}`},
+		{"repeat", `{"a":1,"a":2,"db.hosts.name":"x","db.hosts.name":"y","db.opts":{},"m":1,"m":2,"m":3,"e":[],"s":"v"}`},
+	}
+	for _, tt := range tests {
+		flat, err := flatArrays(v, tt.style, ".")
+		if err != nil {
+			t.Fatalf("flatArrays(%s) = %v", tt.style, err)
+		}
+		p, err := marshalJSON(flat, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(p); got != tt.want {
+			t.Errorf("-flat-arrays %s:\ngot  %s\nwant %s", tt.style, got, tt.want)
+		}
+	}
+
+	flat, err := flatArrays([]interface{}{1}, "index", ".")
+	if p, _ := marshalJSON(flat, false); err != nil || string(p) != "[1]" {
+		t.Errorf("flatArrays([1]) = %s, %v; want [1]", p, err)
+	}
+}
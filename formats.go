@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// generic returns v as it would be decoded from its JSON encoding, with
+// numbers kept as json.Number.
+func generic(v interface{}) (interface{}, error) {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	var g interface{}
+	err = dec.Decode(&g)
+	return g, err
+}
+
+// quoteString returns s as a double-quoted string using JSON escapes,
+// which YAML and TOML basic strings also accept.
+func quoteString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// yamlEncoder encodes values as YAML documents.
+type yamlEncoder struct {
+	w    io.Writer
+	docs int
+}
+
+func (e *yamlEncoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if e.docs > 0 {
+		buf.WriteString("---\n")
+	}
+	e.docs++
+	switch g.(type) {
+	case *object, []interface{}:
+		writeYAML(&buf, g, 0)
+	default:
+		buf.WriteString(yamlScalar(g))
+		buf.WriteByte('\n')
+	}
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// writeYAML writes the block form of the object or array v at the given
+// indentation level.
+func writeYAML(buf *bytes.Buffer, v interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case *object:
+		for _, k := range v.Keys() {
+			elem, _ := v.Get(k)
+			buf.WriteString(pad + yamlString(k) + ":")
+			writeYAMLValue(buf, elem, indent+1)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			buf.WriteString(pad + "-")
+			writeYAMLValue(buf, elem, indent+1)
+		}
+	}
+}
+
+// writeYAMLValue writes v following a key or list marker.
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent int) {
+	switch c := v.(type) {
+	case *object:
+		if c.Len() == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+	case []interface{}:
+		if len(c) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+	default:
+		buf.WriteString(" " + yamlScalar(v) + "\n")
+		return
+	}
+	buf.WriteByte('\n')
+	writeYAML(buf, v, indent)
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return yamlNumber(v.String())
+	case string:
+		return yamlString(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// yamlNumber returns the JSON number s in a form YAML 1.1 also reads as a
+// number, which needs a '.' in the mantissa and a sign on the exponent of
+// floats written with one.
+func yamlNumber(s string) string {
+	i := strings.IndexAny(s, "eE")
+	if i < 0 {
+		return s
+	}
+	mant, exp := s[:i], s[i+1:]
+	if !strings.Contains(mant, ".") {
+		mant += ".0"
+	}
+	if exp[0] != '+' && exp[0] != '-' {
+		exp = "+" + exp
+	}
+	return mant + "e" + exp
+}
+
+// yamlString returns s as a plain YAML scalar if it cannot be read as
+// anything other than a string, and quoted otherwise.
+func yamlString(s string) string {
+	if s == "" || s != strings.TrimSpace(s) {
+		return quoteString(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return quoteString(s)
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case i > 0 && (r >= '0' && r <= '9' || r == ' ' || r == '.' || r == '/' || r == '-'):
+		default:
+			return quoteString(s)
+		}
+	}
+	return s
+}
+
+// tomlEncoder encodes objects as TOML documents. TOML has no null and
+// no multiple-document form, so null values are an error and multiple
+// documents are only separated by a blank line.
+type tomlEncoder struct {
+	w    io.Writer
+	docs int
+}
+
+func (e *tomlEncoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+	obj, ok := g.(*object)
+	if !ok {
+		return fmt.Errorf("cannot encode %T as TOML: must be an object", g)
+	}
+	var buf bytes.Buffer
+	if e.docs > 0 {
+		buf.WriteByte('\n')
+	}
+	e.docs++
+	if err := writeTOMLTable(&buf, nil, obj); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// writeTOMLTable writes the key/value pairs of obj followed by its
+// sub-tables. path is the list of keys naming obj.
+func writeTOMLTable(buf *bytes.Buffer, path []string, obj *object) error {
+	var tables []string
+	wrote := false
+	for _, k := range obj.Keys() {
+		v, _ := obj.Get(k)
+		if _, ok := v.(*object); ok {
+			tables = append(tables, k)
+			continue
+		}
+		text, err := tomlValue(v)
+		if err != nil {
+			return fmt.Errorf("cannot encode %s: %v", tomlPath(append(path, k)), err)
+		}
+		if !wrote && len(path) > 0 {
+			fmt.Fprintf(buf, "[%s]\n", tomlPath(path))
+		}
+		wrote = true
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(k), text)
+	}
+	if !wrote && len(path) > 0 && len(tables) == 0 {
+		fmt.Fprintf(buf, "[%s]\n", tomlPath(path))
+		wrote = true
+	}
+
+	for _, k := range tables {
+		// A table holding only tables writes no header, so the blank
+		// line before its first table has already been written.
+		if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n\n")) {
+			buf.WriteByte('\n')
+		}
+		sub := append(append([]string(nil), path...), k)
+		table, _ := obj.Get(k)
+		if err := writeTOMLTable(buf, sub, table.(*object)); err != nil {
+			return err
+		}
+		wrote = true
+	}
+	return nil
+}
+
+// tomlValue returns the inline TOML form of v.
+func tomlValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", fmt.Errorf("TOML has no null value")
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	case json.Number:
+		s := v.String()
+		if strings.ContainsAny(s, ".eE") {
+			return s, nil
+		}
+		if _, err := v.Int64(); err != nil {
+			if _, ok := new(big.Int).SetString(s, 10); ok {
+				return "", fmt.Errorf("integer %s is out of range for TOML", s)
+			}
+		}
+		return s, nil
+	case string:
+		return quoteString(v), nil
+	case []interface{}:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			text, err := tomlValue(elem)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = text
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case *object:
+		pairs := make([]string, 0, v.Len())
+		for _, k := range v.Keys() {
+			elem, _ := v.Get(k)
+			text, err := tomlValue(elem)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, tomlKey(k)+" = "+text)
+		}
+		if len(pairs) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("unsupported value %T", v)
+}
+
+// tomlKey returns k as a bare TOML key if possible, and quoted otherwise.
+func tomlKey(k string) string {
+	if k == "" {
+		return `""`
+	}
+	for _, r := range k {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return quoteString(k)
+		}
+	}
+	return k
+}
+
+func tomlPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, k := range path {
+		keys[i] = tomlKey(k)
+	}
+	return strings.Join(keys, ".")
+}
+
+// envEncoder encodes objects as KEY=VALUE lines for .env files, or as
+// export statements if export is set. Keys of nested objects are joined
+// by '_', and every key is uppercased with characters other than letters,
+// digits, and '_' (such as the prefix separator) replaced by '_'. Arrays
+// of scalars are written as their values separated by ',', and other
+// arrays as a key for each element, suffixed with its index.
+type envEncoder struct {
+	w      io.Writer
+	export bool
+	docs   int
+}
+
+func (e *envEncoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+	if _, ok := g.(*object); !ok {
+		return fmt.Errorf("cannot encode %T as env: must be an object", g)
+	}
+	var buf bytes.Buffer
+	if e.docs > 0 {
+		buf.WriteByte('\n')
+	}
+	e.docs++
+	e.write(&buf, "", g)
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// write writes the lines for v, named by key.
+func (e *envEncoder) write(buf *bytes.Buffer, key string, v interface{}) {
+	switch c := v.(type) {
+	case *object:
+		for _, k := range c.Keys() {
+			elem, _ := c.Get(k)
+			e.write(buf, envJoin(key, envKey(k)), elem)
+		}
+		return
+	case []interface{}:
+		scalars := make([]string, len(c))
+		for i, elem := range c {
+			switch elem.(type) {
+			case *object, []interface{}:
+				for i, elem := range c {
+					e.write(buf, envJoin(key, strconv.Itoa(i)), elem)
+				}
+				return
+			}
+			scalars[i] = envScalar(elem)
+		}
+		v = strings.Join(scalars, ",")
+	}
+	if e.export {
+		buf.WriteString("export ")
+	}
+	buf.WriteString(key + "=" + envQuote(envScalar(v)) + "\n")
+}
+
+func envJoin(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "_" + key
+}
+
+// envKey returns k as an environment variable name.
+func envKey(k string) string {
+	k = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, k)
+	if k == "" || k[0] >= '0' && k[0] <= '9' {
+		k = "_" + k
+	}
+	return k
+}
+
+func envScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// envQuote returns s unquoted if it only contains characters that need
+// no quoting in a shell, and quoted otherwise: in single quotes if it
+// contains none, and in double quotes, with '\', '"', '$', and '`'
+// escaped, if it does.
+func envQuote(s string) string {
+	plain := true
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("_-.,/:@+%=", r):
+		default:
+			plain = false
+		}
+	}
+	switch {
+	case plain:
+		return s
+	case !strings.Contains(s, "'"):
+		return "'" + s + "'"
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if strings.ContainsRune(`\"$`+"`", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
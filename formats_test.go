@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// TestFormats encodes each JSON file in testdata/formats in each output
+// format, and compares the output to the file of the same name with the
+// format as its extension.
+func TestFormats(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "formats", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		for _, format := range []string{"yaml", "toml", "env", "json5"} {
+			name := strings.TrimSuffix(input, ".json") + "." + format
+			t.Run(filepath.Base(name), func(t *testing.T) {
+				newEncoder, err := encoderFor(format, false, false, jsonOptions{}, goOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				in, err := os.Open(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer in.Close()
+
+				var buf bytes.Buffer
+				enc := newEncoder(&buf)
+				if err := readOrdered(in, enc.Encode); err != nil {
+					t.Fatalf("Encode(%s) = %v", input, err)
+				}
+				if *update {
+					if err := ioutil.WriteFile(name, buf.Bytes(), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := ioutil.ReadFile(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := buf.String(); got != string(want) {
+					t.Errorf("%s:\ngot\n%s\nwant\n%s", name, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		in     string
+		want   string
+	}{
+		{"toml", `{"a": {"b": null}}`, "cannot encode a.b: TOML has no null value"},
+		{"toml", `{"a": [1, null]}`, "cannot encode a: TOML has no null value"},
+		{"toml", `{"a b": 18446744073709551616}`, `cannot encode "a b": integer 18446744073709551616 is out of range for TOML`},
+		{"toml", `[1]`, "cannot encode []interface {} as TOML: must be an object"},
+		{"env", `"x"`, "cannot encode string as env: must be an object"},
+	}
+	for _, tt := range tests {
+		newEncoder, err := encoderFor(tt.format, false, false, jsonOptions{}, goOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = readOrdered(strings.NewReader(tt.in), newEncoder(ioutil.Discard).Encode)
+		if got := errString(err); got != tt.want {
+			t.Errorf("-o %s: Encode(%s) = %q; want %q", tt.format, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJSON5Comments(t *testing.T) {
+	const in = `{"db":{"host":"x","ports":[1,2]},"a":1,"_comments":{"db.host":["the host\nname"],"db.ports":["first","second"],"a":[""]}}`
+	const want = `{
+  db: {
+    // the host
+    // name
+    host: "x",
+    ports: [
+      // first
+      1,
+      // second
+      2,
+    ],
+  },
+  a: 1,
+}
+`
+	var buf bytes.Buffer
+	enc := &json5Encoder{w: &buf, notes: true, sep: "."}
+	if err := readOrdered(strings.NewReader(in), enc.Encode); err != nil {
+		t.Fatalf("Encode(%s) = %v", in, err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("-o json5 -comments:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lookupPath returns the value at path in v. A path beginning with '/' is
+// a JSON Pointer. Any other path is a list of keys and array indices
+// separated by '.'; since keys may themselves contain '.', the longest
+// run of path elements naming a key in an object is used at each step,
+// so "db.host" finds both {"db.host": ...} and {"db": {"host": ...}}.
+func lookupPath(v interface{}, path string) (interface{}, error) {
+	g, err := ordered(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(path, "/") {
+		elems := strings.Split(path[1:], "/")
+		for i, e := range elems {
+			e = strings.Replace(e, "~1", "/", -1)
+			elems[i] = strings.Replace(e, "~0", "~", -1)
+		}
+		for _, e := range elems {
+			if g, err = step(g, e); err != nil {
+				return nil, err
+			}
+		}
+		return g, nil
+	}
+
+	elems := strings.Split(path, ".")
+	if path == "" {
+		elems = nil
+	}
+	for len(elems) > 0 {
+		n := 1
+		if obj, ok := g.(*object); ok {
+			for n = len(elems); n > 1; n-- {
+				if _, ok := obj.Get(strings.Join(elems[:n], ".")); ok {
+					break
+				}
+			}
+		}
+		if g, err = step(g, strings.Join(elems[:n], ".")); err != nil {
+			return nil, err
+		}
+		elems = elems[n:]
+	}
+	return g, nil
+}
+
+// step returns the member of the object v named by key, or the element
+// of the array v at the index key.
+func step(v interface{}, key string) (interface{}, error) {
+	switch c := v.(type) {
+	case *object:
+		if elem, ok := c.Get(key); ok {
+			return elem, nil
+		}
+		return nil, fmt.Errorf("no key %+q", key)
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(c) {
+			return nil, fmt.Errorf("no index %+q in array of %d elements", key, len(c))
+		}
+		return c[i], nil
+	}
+	return nil, fmt.Errorf("cannot look up %+q in %s", key, typeName(v))
+}
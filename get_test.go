@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupPath(t *testing.T) {
+	const doc = `{"db.host":"flat","db":{"host":"nested","ports":[80,443],"a/b":1,"m~n":2},"list":[{"k":true}]}`
+	var v interface{}
+	if err := readOrdered(strings.NewReader(doc), func(d interface{}) error {
+		v = d
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, want string
+	}{
+		{"", doc},
+		{"db.host", `"flat"`},
+		{"db.ports.1", `443`},
+		{"list.0.k", `true`},
+		{"/db/host", `"nested"`},
+		{"/db.host", `"flat"`},
+		{"/db/a~1b", `1`},
+		{"/db/m~0n", `2`},
+		{"/db/ports/0", `80`},
+	}
+	for _, tt := range tests {
+		got, err := lookupPath(v, tt.path)
+		if err != nil {
+			t.Errorf("lookupPath(%q) = %v", tt.path, err)
+			continue
+		}
+		p, err := marshalJSON(got, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != tt.want {
+			t.Errorf("lookupPath(%q) = %s, want %s", tt.path, p, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{"nope", `no key "nope"`},
+		{"db.ports.2", `no index "2" in array of 2 elements`},
+		{"db.ports.x", `no index "x" in array of 2 elements`},
+		{"/db/ports/-1", `no index "-1" in array of 2 elements`},
+		{"db.host.x", `cannot look up "x" in string`},
+	} {
+		if _, err := lookupPath(v, tt.path); errString(err) != tt.want {
+			t.Errorf("lookupPath(%q) = %v, want %s", tt.path, err, tt.want)
+		}
+	}
+}
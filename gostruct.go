@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goOptions controls the Go code written by goStructEncoder.
+type goOptions struct {
+	typeName string // Name of the type of each document.
+	defaults bool   // Whether to write a variable holding each document.
+}
+
+// goStructEncoder encodes objects as Go struct type definitions with
+// json tags, and optionally as variables of those types. Each document
+// after the first has its names suffixed with its number.
+type goStructEncoder struct {
+	w    io.Writer
+	opts goOptions
+	docs int
+}
+
+func (e *goStructEncoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+	if _, ok := g.(*object); !ok {
+		return fmt.Errorf("cannot encode %s as a Go struct: must be an object", typeName(g))
+	}
+
+	e.docs++
+	suffix := ""
+	if e.docs > 1 {
+		suffix = strconv.Itoa(e.docs)
+	}
+	gen := &goGenerator{names: map[string]bool{}}
+	root := gen.infer(g, e.opts.typeName+suffix)
+
+	var buf bytes.Buffer
+	if e.docs > 1 {
+		buf.WriteByte('\n')
+	}
+	for i, t := range gen.structs {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "type %s struct {\n", t.name)
+		for _, f := range t.fields {
+			tag := f.key
+			if tag == "-" {
+				tag = "-,"
+			}
+			fmt.Fprintf(&buf, "%s %s `json:%s`\n", f.name, f.typ, strconv.Quote(tag))
+		}
+		buf.WriteString("}\n")
+	}
+	if e.opts.defaults {
+		fmt.Fprintf(&buf, "\nvar Default%s = %s\n", suffix, goLiteral(root, g))
+	}
+
+	p, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(p)
+	return err
+}
+
+// goType is a Go type inferred from JSON values.
+type goType struct {
+	kind   string    // A Go type name, or "[]" for slices and "struct" for structs.
+	elem   *goType   // Element type of a slice.
+	name   string    // Name of a struct.
+	fields []goField // Fields of a struct.
+}
+
+type goField struct {
+	name string
+	key  string
+	typ  *goType
+}
+
+func (t *goType) String() string {
+	switch t.kind {
+	case "[]":
+		return "[]" + t.elem.String()
+	case "struct":
+		return t.name
+	}
+	return t.kind
+}
+
+// goGenerator infers the Go types of a document, keeping the structs it
+// defines in order.
+type goGenerator struct {
+	structs []*goType
+	names   map[string]bool
+}
+
+// infer returns the type of v. Objects become structs named name.
+func (gen *goGenerator) infer(v interface{}, name string) *goType {
+	switch v := v.(type) {
+	case bool:
+		return &goType{kind: "bool"}
+	case string:
+		return &goType{kind: "string"}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &goType{kind: "int64"}
+		}
+		return &goType{kind: "float64"}
+	case []interface{}:
+		var elem *goType
+		for _, e := range v {
+			elem = gen.unify(elem, gen.infer(e, name))
+		}
+		if elem == nil {
+			elem = &goType{kind: "interface{}"}
+		}
+		return &goType{kind: "[]", elem: elem}
+	case *object:
+		t := &goType{kind: "struct", name: gen.structName(name)}
+		gen.structs = append(gen.structs, t)
+		used := map[string]bool{}
+		for _, k := range v.Keys() {
+			if !validTag(k) {
+				continue
+			}
+			field := goName(k)
+			for n := 2; used[field]; n++ {
+				field = goName(k) + strconv.Itoa(n)
+			}
+			used[field] = true
+			elem, _ := v.Get(k)
+			t.fields = append(t.fields, goField{name: field, key: k, typ: gen.infer(elem, t.name+field)})
+		}
+		return t
+	}
+	return &goType{kind: "interface{}"}
+}
+
+// unify returns a type that can hold values of both a and b, merging the
+// fields of structs. a may be nil.
+func (gen *goGenerator) unify(a, b *goType) *goType {
+	switch {
+	case a == nil:
+		return b
+	case a.kind != b.kind:
+		if a.kind == "int64" && b.kind == "float64" || a.kind == "float64" && b.kind == "int64" {
+			return &goType{kind: "float64"}
+		}
+		return &goType{kind: "interface{}"}
+	case a.kind == "[]":
+		return &goType{kind: "[]", elem: gen.unify(a.elem, b.elem)}
+	case a.kind == "struct":
+		for _, bf := range b.fields {
+			merged := false
+			for i, af := range a.fields {
+				if af.key == bf.key {
+					a.fields[i].typ = gen.unify(af.typ, bf.typ)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				a.fields = append(a.fields, bf)
+			}
+		}
+		gen.drop(b)
+	}
+	return a
+}
+
+// drop removes the struct t, merged into another, from the structs to
+// define.
+func (gen *goGenerator) drop(t *goType) {
+	for i, s := range gen.structs {
+		if s == t {
+			gen.structs = append(gen.structs[:i], gen.structs[i+1:]...)
+			return
+		}
+	}
+}
+
+// structName returns name, or name with a number appended if it is
+// already the name of a struct.
+func (gen *goGenerator) structName(name string) string {
+	unique := name
+	for n := 2; gen.names[unique]; n++ {
+		unique = name + strconv.Itoa(n)
+	}
+	gen.names[unique] = true
+	return unique
+}
+
+// validTag returns whether encoding/json accepts k as the name in a json
+// tag. Keys it doesn't, such as empty keys and keys with commas or
+// quotes, can't be given a field and are skipped.
+func validTag(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, r := range k {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// goName returns an exported Go identifier for the key k, made by joining
+// its runs of letters and digits with their first letters in upper case.
+func goName(k string) string {
+	var buf strings.Builder
+	for _, word := range strings.FieldsFunc(k, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		rs := []rune(word)
+		rs[0] = unicode.ToUpper(rs[0])
+		buf.WriteString(string(rs))
+	}
+	name := buf.String()
+	if name == "" {
+		return "Field"
+	}
+	if r := []rune(name)[0]; !unicode.IsLetter(r) || !unicode.IsUpper(r) {
+		name = "X" + name
+	}
+	return name
+}
+
+// goLiteral returns a Go expression of type t for the value v.
+func goLiteral(t *goType, v interface{}) string {
+	switch t.kind {
+	case "struct":
+		obj, _ := v.(*object)
+		var fields []string
+		for _, f := range t.fields {
+			if elem, ok := obj.Get(f.key); ok {
+				fields = append(fields, f.name+": "+goLiteral(f.typ, elem))
+			}
+		}
+		return t.name + "{\n" + joinLines(fields) + "}"
+	case "[]":
+		list, _ := v.([]interface{})
+		elems := make([]string, len(list))
+		for i, e := range list {
+			// Elements of a composite literal may omit their type.
+			elems[i] = strings.TrimPrefix(goLiteral(t.elem, e), t.elem.name)
+		}
+		return t.String() + "{" + strings.Join(elems, ", ") + "}"
+	case "interface{}":
+		return goDynamic(v)
+	}
+	return goScalar(v)
+}
+
+// goDynamic returns a Go expression for v as an interface{} value.
+func goDynamic(v interface{}) string {
+	switch v := v.(type) {
+	case []interface{}:
+		elems := make([]string, len(v))
+		for i, e := range v {
+			elems[i] = goDynamic(e)
+		}
+		return "[]interface{}{" + strings.Join(elems, ", ") + "}"
+	case *object:
+		pairs := make([]string, 0, v.Len())
+		for _, k := range v.Keys() {
+			elem, _ := v.Get(k)
+			pairs = append(pairs, strconv.Quote(k)+": "+goDynamic(elem))
+		}
+		return "map[string]interface{}{\n" + joinLines(pairs) + "}"
+	}
+	return goScalar(v)
+}
+
+func goScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// joinLines returns elems as the lines of a composite literal.
+func joinLines(elems []string) string {
+	var buf strings.Builder
+	for _, e := range elems {
+		buf.WriteString(e + ",\n")
+	}
+	return buf.String()
+}
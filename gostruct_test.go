@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGoStructKeys(t *testing.T) {
+	in := `{"name": "x", "": 1, "a,b": 2, "-": true, "port": 80}`
+	var buf bytes.Buffer
+	enc := &goStructEncoder{w: &buf, opts: goOptions{typeName: "Config", defaults: true}}
+	err := readJSON(strings.NewReader(in), func(v interface{}) error {
+		return enc.Encode(v)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "type Config struct {\n" +
+		"\tField bool   `json:\"-,\"`\n" +
+		"\tName  string `json:\"name\"`\n" +
+		"\tPort  int64  `json:\"port\"`\n" +
+		"}\n\n" +
+		"var Default = Config{\n" +
+		"\tField: true,\n" +
+		"\tName:  \"x\",\n" +
+		"\tPort:  80,\n" +
+		"}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode(%s) =\n%s\nwant\n%s", in, got, want)
+	}
+}
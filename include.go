@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// includeLines rewrites '!include PATH' lines as 'include = PATH'.
+var includeLines = lineFilter(func(line string) string {
+	t := strings.TrimSpace(line)
+	if !strings.HasPrefix(t, "!include ") && !strings.HasPrefix(t, "!include\t") {
+		return line
+	}
+	return "include = " + quoteValue(strings.TrimSpace(t[len("!include"):]))
+})
+
+// includes is a recorder that reads the files named by include keys into
+// dest, in place of recording them. Included files are read as they are
+// seen, with their own sections, so their keys are not prefixed by the
+// section the directive appears in.
+type includes struct {
+	src   *source
+	dest  ini.Recorder
+	dir   string   // Directory of the including file.
+	stack []string // Files being read, outermost first.
+	err   error    // First error encountered reading an included file.
+}
+
+func (s *source) includes(dest ini.Recorder, path string, stack []string) *includes {
+	dir, name := ".", path
+	if path != "-" {
+		dir = filepath.Dir(path)
+		if abs, err := filepath.Abs(path); err == nil {
+			name = abs
+		}
+	}
+	return &includes{
+		src:   s,
+		dest:  dest,
+		dir:   dir,
+		stack: append(stack[:len(stack):len(stack)], name),
+	}
+}
+
+// directive returns whether key is an include directive.
+func (inc *includes) directive(key string) bool {
+	name := key
+	if i := strings.LastIndex(key, inc.src.rd.Separator); i >= 0 && inc.src.rd.Separator != "" {
+		name = key[i+len(inc.src.rd.Separator):]
+	}
+	return strings.EqualFold(name, "include")
+}
+
+func (inc *includes) Add(key, value string) {
+	if !inc.directive(key) {
+		inc.dest.Add(key, value)
+		return
+	}
+	if inc.err != nil {
+		return
+	}
+
+	path, err := inc.resolve(value)
+	if err != nil {
+		inc.err = err
+		return
+	}
+	for _, p := range inc.stack {
+		if p == path {
+			inc.err = fmt.Errorf("include cycle: %s -> %s", strings.Join(inc.stack, " -> "), path)
+			return
+		}
+	}
+	if err := inc.src.readFile(inc.dest, path, inc.stack); err != nil {
+		inc.err = fmt.Errorf("unable to include %v: %v", path, err)
+	}
+}
+
+// addAt passes values other than include directives on to dest with
+// their location, if dest records locations.
+func (inc *includes) addAt(key, value string, loc location) {
+	if d, ok := inc.dest.(locatedAdder); ok && !inc.directive(key) {
+		d.addAt(key, value, loc)
+		return
+	}
+	inc.Add(key, value)
+}
+
+// Err returns the first error encountered reading an included file.
+func (inc *includes) Err() error {
+	return inc.err
+}
+
+// resolve returns the absolute path of the included file named by path.
+func (inc *includes) resolve(path string) (string, error) {
+	var candidates []string
+	if filepath.IsAbs(path) {
+		candidates = []string{path}
+	} else {
+		candidates = append(candidates, filepath.Join(inc.dir, path))
+		for _, dir := range inc.src.includePath {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err == nil {
+			return filepath.Abs(c)
+		}
+	}
+	return "", fmt.Errorf("included file %+q not found", path)
+}
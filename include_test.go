@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// readIncludes reads the first of texts, written by tempFiles, with -I
+// and the directory of the files as the include path.
+func readIncludes(t *testing.T, texts ...string) (string, error) {
+	t.Helper()
+	paths, done := tempFiles(t, texts...)
+	defer done()
+	src := &source{
+		rd:          &ini.Reader{Separator: ".", True: "true"},
+		include:     true,
+		includePath: []string{filepath.Dir(paths[0])},
+		filters:     []filter{includeLines},
+	}
+	values := newParsedValues(&valueParser{})
+	if err := src.read(values, paths[0]); err != nil {
+		return strings.Replace(err.Error(), filepath.Dir(paths[0])+string(filepath.Separator), "", -1), err
+	}
+	p, err := marshalJSON(orderedDocument(values), false)
+	return string(p), err
+}
+
+func TestIncludes(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  string
+	}{
+		{"directive", []string{"a = 1\n!include test2.ini\n[s]\nb = 2\n", "[inc]\nc = 3\n"},
+			`{"a":[1],"inc.c":[3],"s.b":[2]}`},
+		{"key", []string{"[s]\ninclude = test2.ini\nb = 2\n", "c = 3\n"},
+			`{"c":[3],"s.b":[2]}`},
+		{"nested", []string{"!include test2.ini\n", "!include test3.ini\nb = 2\n", "c = 3\n"},
+			`{"c":[3],"b":[2]}`},
+		{"repeated", []string{"!include test2.ini\n!include test2.ini\n", "c = 3\n"},
+			`{"c":[3,3]}`},
+	}
+	for _, tt := range tests {
+		got, err := readIncludes(t, tt.texts...)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %s, %v; want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  string
+	}{
+		{"missing", []string{"!include nope.ini\n"}, `included file "nope.ini" not found`},
+		{"self", []string{"!include test.ini\n"}, "include cycle: test.ini -> test.ini"},
+		{"cycle", []string{"!include test2.ini\n", "!include test.ini\n"},
+			"unable to include test2.ini: include cycle: test.ini -> test2.ini -> test.ini"},
+	}
+	for _, tt := range tests {
+		got, err := readIncludes(t, tt.texts...)
+		if err == nil || !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%s: got %s; want error ending in %q", tt.name, got, tt.want)
+		}
+	}
+}
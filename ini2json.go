@@ -2,73 +2,580 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
-	"math/big"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	ini "go.spiff.io/go-ini"
 )
 
 func usage() {
 	fmt.Fprint(os.Stderr, `USAGE: ini2json [OPTIONS] [FILES]
+       ini2json completion SHELL
 
-Convert INI files to JSON.
+Convert INI files to JSON, or JSON files to INI with -reverse.
 If no files are passed or "-" is passed, it reads from standard input.
+Inputs may be http:// or https:// URLs. Inputs compressed with gzip are
+decompressed, and archives (.zip, .tar, .tar.gz, and .tgz) are read as
+each file in them, named ARCHIVE!NAME.
+
+With completion, write a script completing options and file names for
+SHELL, which is one of bash, zsh, or fish (e.g., add
+'source <(ini2json completion bash)' to ~/.bashrc).
 
 OPTIONS:
--s SEP    Separator for [prefix] and field names. (Default: '.')
--C TFORM  Case transformation.
+Options may be given with one or two dashes (e.g., -merge or --merge).
+Options with a single-letter name also have a long name.
+-s, -separator SEP
+          Separator for [prefix] and field names. (Default: '.')
+-C, -case TFORM
+          Case transformation.
             -  No case transformation.
             l  Lowercase all keys (including prefix).
             u  Uppercase all keys (including prefix).
--t TRUE   Any field without a value is assigned the value 'TRUE'.
+-K, -key-styles LIST
+          Rewrite each segment of keys, including section names, in the
+          comma-separated styles:
+            trim   Remove spaces around segments.
+            snake  Lowercase words joined by '_' (e.g., max_connections).
+            camel  Words joined with each after the first capitalized
+                   (e.g., maxConnections).
+            kebab  Lowercase words joined by '-' (e.g., max-connections).
+          Words are separated by spaces, '_', '-', and changes of case
+          (e.g., 'Max Connections' or 'MaxConnections'). Styles apply
+          after -C and -only and before -map.
+-t, -true TRUE
+          Any field without a value is assigned the value 'TRUE'.
           (Default: 'true')
--m        Merge all input files into a single JSON output.
--c        Print compact JSON output.
--r        Do not parse values (integers, floats, bools, JSON).
+-empty MODE
+          How keys assigned an empty value (e.g., 'key =') are recorded:
+            true          Assign TRUE, as for keys without '='. (Default)
+            null          Assign null. Cannot be used with -r.
+            empty-string  Assign an empty string.
+            omit          Do not record the key.
+-delim CHARS
+          Also accept any of CHARS (e.g., ':') as the delimiter between
+          keys and values. Each assignment is split at the first '=' or
+          delimiter in it.
+-comment-chars CHARS
+          Also accept lines starting with any of CHARS (e.g., '/') as
+          comments. Lines starting with ';' or '#' are always comments.
+-multiline MODE
+          Read values written over more than one line as strings with
+          their lines joined by newlines:
+            quotes  Values starting with """ continue until the next
+                    """. A newline right after the opening quotes or
+                    before the closing quotes is not part of the value.
+            indent  Lines indented more than an assignment continue its
+                    value, up to the next blank or less indented line,
+                    without the indentation of the first of them.
+          The text of such values is kept as written. Cannot be used
+          with -dialect.
+-f, -dialect NAME
+          How inputs are written:
+            default    INI as read by go-ini. (Default)
+            windows    Windows INI files, which may start with a UTF-8 or
+                       UTF-16 byte order mark and have CRLF line endings,
+                       comments after values (e.g., 'key = 1 ; note'),
+                       and double-quoted values with \n, \t, \r, \",
+                       and \\ escapes.
+            gitconfig  git-config files, with [section "subsection"]
+                       headers read as the section 'section SEP
+                       subsection', values continued by a trailing
+                       backslash, and values quoted and escaped as git
+                       does. Keys without a value are assigned TRUE.
+            systemd    systemd unit files, with lines continued by a
+                       trailing backslash and assignments of an empty
+                       value (e.g., 'ExecStart=') clearing the values
+                       the key was assigned before.
+            properties Java properties files, without sections, with
+                       keys separated from values by '=', ':', or
+                       spaces, '#' and '!' comments, lines continued by
+                       a trailing backslash, and \uXXXX escapes. With
+                       -n, keys are split on SEP as for any other input.
+            auto       Detect the dialect of each input from its start:
+                       windows for inputs with a byte order mark or CRLF
+                       line endings, gitconfig for [section "sub"]
+                       headers, systemd for headers such as [Unit] and
+                       [Service], properties for inputs without sections
+                       that have '!' comments or ':' delimiters, and
+                       default otherwise.
+-reverse  Convert JSON to INI. Objects are written as sections named by
+          joining keys with SEP and arrays as repeated keys. Output of
+          -fidelity is written in the layout it records.
+-R, -recursive
+          Convert the files in directories passed as inputs and in their
+          subdirectories, in lexical order, skipping those whose names
+          start with '.'. Inputs that are glob patterns (e.g.,
+          'conf/**/*.ini', where '**' matches any number of directories)
+          are replaced by the files matching them, with or without -R.
+          As with -R, wildcards in patterns with '**' don't match names
+          starting with '.' unless the pattern element starts with one.
+-m, -merge
+          Merge all input files into a single JSON output.
+-spill    With -m, write the values of each file to a temporary file as
+          it is merged instead of holding them in memory, so that merging
+          large inputs only holds the files being read at once. Output
+          must be JSON, without options that need every value at once,
+          such as -n, -schema, and the sidecars.
+-merge-strategy STRATEGY
+          How values of merged files are combined:
+            append    Keep the values of every file. (Default)
+            override  Values of a key in a later file replace those of
+                      earlier files. With -n, a later file replaces
+                      each top-level object it defines entirely.
+            deep      With -n, merge objects key by key, so a later file
+                      only replaces the values of the keys it defines.
+-single   Write keys that have a single value as scalars instead of
+          arrays. Keys with more than one value are still arrays.
+-n, -nested
+          Split keys on SEP into nested objects. Values of a key that is
+          also the prefix of other keys are kept under "_value".
+-flat-arrays STYLE
+          Write each array and object in the output as a key for each of
+          its elements, so that every value is a scalar. Keys of object
+          members are joined by SEP, and array elements are written in
+          STYLE:
+            index     As key.0, key.1, ..., using SEP.
+            brackets  As key[0], key[1], ...
+            repeat    As key, repeated for each element. Requires JSON
+                      output.
+          Cannot be used with -n.
+-j, -jobs N
+          Read up to N files concurrently. Outputs are written in the
+          order of the inputs, as when they are read one at a time.
+          (Default: 1)
+-dup POLICY
+          How keys assigned more than once, in one file or across merged
+          files, are handled:
+            append  Keep every value. (Default)
+            first   Keep the first value.
+            last    Keep the last value.
+            error   Fail, listing the file and line of each duplicate.
+                    Cannot be used with -j.
+-ops      Read assignment operators that say how values of a key are
+          combined: 'key += value' appends value, even with -dup error,
+          and 'key := value' or 'key =! value' replaces the values
+          assigned to key before it in the same input.
+-explain-conflicts
+          When merging, write a JSON report of keys defined by more than
+          one file, with each file's values, to standard error.
+-fail-on-conflict
+          When merging, fail if any key is defined by more than one file.
+-o, -format FORMAT
+          Output format: json, json5, which writes keys unquoted where
+          possible, a comma after every member, and comments recorded
+          by -comments as // comments, yaml, toml, gostruct, which writes a
+          Go struct type with json tags matching the output (skipping
+          keys a json tag can't name, such as empty keys), or env,
+          which writes KEY=VALUE lines for .env files, or export
+          statements with -o env=export. Keys for env are uppercased,
+          nested keys are joined by '_', and characters other than
+          letters, digits, and '_' are replaced by '_'. msgpack and cbor
+          write each output as a MessagePack or CBOR value, with integers
+          in the smallest form that holds them and other numbers as
+          64-bit floats. (Default: json)
+-go-type NAME
+          Name of the struct type written by -o gostruct. Other types are
+          named after it and their fields. (Default: Config)
+-go-default
+          With -o gostruct, also write a Default variable of the type
+          holding the output.
+-template FILE
+          Write each output by executing the Go text/template in FILE
+          with it as the data, instead of encoding it. Keys are looked up
+          with 'index' (e.g., {{ index . "db.host" }}), or as fields with
+          -n (e.g., {{ .db.host }}), and are lists unless written with
+          -single. Besides the standard functions,
+          templates may use lower, upper, title, trim, trimPrefix,
+          trimSuffix, replace, contains, hasPrefix, hasSuffix, repeat,
+          split, join, quote, squote, indent, nindent, default, keys,
+          list, env, and toJSON, which take the value they operate on
+          last, so it can be piped to them. Files written by -d are
+          named with the template's extension, without .tmpl or .tpl.
+-O, -output PATH
+          Write output to PATH instead of standard output.
+-A, -array[=named]
+          Write the outputs for all inputs as the elements of one array
+          instead of one after another. With -A=named, each element is
+          an object with the input's name in "file" and its output in
+          "values".
+-d, -output-dir DIR
+          Write the output for each input to a file in DIR named after
+          the input, with an extension for the output format.
+-w, -watch
+          Watch the input files and convert them again each time one
+          changes, writing each output to standard output or replacing
+          the -O file atomically. Included files are not watched.
+-watch-interval DURATION
+          How often -w checks the inputs for changes. (Default: 500ms)
+-timeout DURATION
+          How long to wait for each URL input. (Default: 30s)
+-H, -header HEADER
+          Add HEADER, given as 'Name: value', to the requests for URL
+          inputs. May be given more than once.
+-cacert FILE
+          Verify the certificates of URL inputs with the PEM certificates
+          in FILE instead of the system's.
+-insecure Do not verify the certificates of URL inputs.
+-c, -compact
+          Print compact JSON output.
+-indent INDENT
+          Indent JSON output with INDENT: a number of spaces, or a string
+          of spaces and tabs, which may be written with \t escapes (e.g.,
+          -indent 4 or -indent '\t'). (Default: 2)
+-escape-html=false
+          Do not escape '<', '>', and '&' in JSON strings as \u003c,
+          \u003e, and \u0026.
+-ascii    Escape every non-ASCII character in JSON and JSON5 strings as
+          \uXXXX.
+-canonical
+          Write canonical JSON, so that equal outputs are always written
+          as the same bytes (e.g., to hash them): compact, with keys
+          sorted, without escaping '<', '>', and '&', and with each
+          output followed by one newline. Numbers are written with all
+          of their significant digits as JavaScript writes them, so 1.0,
+          1, and 10e-1 are all written as 1, and 1e21 as 1e+21.
+-section-lines
+          Print each top-level member compactly on its own line.
+-stream[=kv]
+          Write each section, or each value with -stream=kv, as a line of
+          compact JSON as soon as it is read instead of buffering whole
+          inputs. Cannot be used with -m.
+-r, -raw
+          Do not parse values (integers, floats, bools, JSON). With -c,
+          and without options that change how values are read as types
+          or written (e.g., -T, -single, -n, or -sort), each input is
+          written directly as it is read, which is faster for large
+          inputs.
+-key-tabs MODE
+          How tabs within keys are handled. Tabs around '=' are always
+          trimmed, as spaces are.
+            preserve  Keep tabs in keys. (Default)
+            space     Replace each tab in a key with a space.
+-collapse-spaces
+          Replace runs of spaces and tabs within values with one space.
+-I, -include
+          Follow include directives ('include = PATH' or '!include PATH'),
+          reading the named file into the output at that point. Relative
+          paths are resolved against the including file's directory and
+          then each directory of -include-path.
+-include-path DIRS
+          List of directories, separated by the OS path list separator,
+          to search for included files.
+-E, -expand
+          Expand $VAR and ${VAR} in values from the environment before
+          parsing them. Use \$ for a literal '$'.
+-profile NAME
+          Read sections named SECTION@NAME as SECTION, with the values of
+          each key they assign replacing the values assigned to it in
+          SECTION in the same input, and ignore sections named
+          SECTION@OTHER for other profiles.
+-default-section NAME
+          Record keys assigned before any section header in the section
+          NAME instead of at the top level.
+-require-sections
+          Fail if any key is assigned before any section header.
+-inherit  Record the values of keys in the DEFAULT section, or the
+          -default-section NAME, in every other section with keys that
+          does not assign them itself, after its own values. With -x,
+          references in inherited values are resolved in the sections
+          inheriting them.
+-x, -interpolate
+          Replace %(name)s and ${name} in values with the last value of
+          the key name in the same section, the DEFAULT section, or at
+          the top level, in that order. References are resolved within
+          each file, before -E. Use %% and $$ for a literal '%' and '$'.
+-decrypt CMD
+          Replace values written as ENC[CIPHERTEXT] with the output of the
+          shell command CMD, run with CIPHERTEXT as its input and the key
+          in the environment variable INI2JSON_KEY. A trailing newline
+          is removed from the output.
+-transform CMD
+          Pass each value, after -decrypt, to the shell command CMD, which
+          is started once and written a line of JSON for each value
+          (e.g., {"key": "db.password", "value": "hunter2"}). For each
+          line, it must write a line with an object whose "key" and
+          "value", if given, replace those of the value, or null to drop
+          the value.
+-bare-lines-as KEY
+          Record lines that have no '=' as values of KEY in the current
+          section, in order, instead of as keys assigned TRUE.
+-section-descriptions
+          Record the comment block immediately preceding each section
+          header in a top-level "_descriptions" object, or in a
+          "_description" field of each section with -n. Descriptions
+          are keyed by the section of the keys in the output, after
+          -map, -K, and -prefix, so sections with no keys written are
+          not described.
+-roundtrip-check
+          Fail if converting the output back to INI and reading it again
+          does not produce the same values.
+-strict   Fail on malformed lines, reporting the file, line, and column
+          of each: lines with invalid UTF-8, unterminated or empty section
+          headers, text after section headers, assignments without a key,
+          and lines of more than one word without '=', unless they are
+          recorded by -bare-lines-as.
+-max-errors N
+          Report up to N malformed lines found by -strict in each input,
+          or all of them if N is 0. (Default: 10)
+-limit-bytes N
+          Fail if an input, after decompression, is larger than N bytes.
+-limit-keys N
+          Fail if an input has more than N distinct keys.
+-limit-depth N
+          Fail if a key is split into more than N nested objects (with -n).
+          Limits are applied to each input, including included files, and
+          are not enforced if N is 0. (Default: 0)
+-report FILE
+          Write a JSON report of the conversion to FILE, with the "files"
+          read, the number of values recorded for each of the "keys", the
+          values "coerced" from text to another type, "duplicates" of
+          keys already assigned, and "warnings" about values dropped by
+          -dup first or last, each with its file and line. Cannot be used
+          with -j, -w, or -check.
+-warn[=error]
+          Write a warning to standard error, with its file and line, for
+          each value parsed as a number, bool, null, array, or object
+          instead of a string (e.g., a version '1.10' read as the float
+          1.1), or with -warn=error, fail on them instead. Keys written
+          without a value, and values typed by -T or -typed-keys, are
+          not reported.
+-check    Read and check the inputs, and the output for them, without
+          writing any output. Every problem found is reported with its
+          file and line, where known, and the exit status is the number
+          of problems (at most 125).
+-parse LIST
+          Select comma-separated value parsers. Values are parsed as
+          integers, floats, booleans, and JSON, in that order, unless any
+          of those are listed, in which case only the ones listed are
+          tried (e.g., -parse int,float,bool keeps values such as [1,2]
+          and "a" strings). Other parsers are enabled in addition:
+            int    Parse decimal integers.
+            float  Parse decimal floating point numbers.
+            bool   Parse booleans (true, false, 1, 0, t, f, ...).
+            json   Parse JSON arrays, objects, strings, and null.
+            tuple  Split values such as 10:20 or a:b:c into arrays.
+-typed-keys
+          Parse values of keys with a type suffix (e.g., 'port:int') as
+          that type, and fail if they are invalid. Suffixes are removed
+          from keys. Types are int, float, bool, str, and json.
+-T, -type KEY=TYPE
+          Parse values of keys matching the glob KEY as TYPE, and fail if
+          they are invalid. TYPE is one of string, int, float, bool, or
+          json. May be given more than once; the first match applies.
+          Applies to keys after renaming and works with -r.
+-tuple-sep SEP
+          Separator for tuple values. (Default: ':')
+-array-keys
+          Record values of keys written as 'key[] = value' under 'key',
+          and write them as arrays even with -single.
+-list-sep SEP
+          Split values containing SEP into lists, recording each element,
+          trimmed of spaces, as a value of the key. Keys with lists are
+          written as arrays even with -single.
+-num-formats LIST
+          Also parse numbers in these comma-separated formats:
+            hex          Integers prefixed with 0x.
+            oct          Integers prefixed with 0o.
+            bin          Integers prefixed with 0b.
+            underscores  Numbers with digits separated by '_'.
+-bools LIST
+          Also parse these words as booleans, ignoring case. LIST is a
+          comma-separated list of pairs of true and false words (e.g.,
+          'yes,no,on,off,enabled,disabled').
+-units[=object]
+          Parse durations (e.g., '30s', '1h30m') as a number of
+          nanoseconds and sizes (e.g., '512k', '10Gi') as a number of
+          bytes. Size suffixes are powers of 1000 (k, M, G, ...) or 1024
+          (Ki, Mi, Gi, ...), and m is minutes. With -units=object, they
+          are recorded as {"value": N, "unit": UNIT} as written instead,
+          except compound durations such as '1h30m', which have no single
+          unit and are recorded in nanoseconds ({"value": 5400000000000,
+          "unit": "ns"}).
+-dates[=epoch]
+          Recognize dates and times (RFC 3339, '2006-01-02 15:04:05',
+          '2006-01-02', and RFC 1123) and write them as RFC 3339 strings,
+          or with -dates=epoch, as seconds since the Unix epoch. Times
+          without a zone are in UTC. Zone abbreviations other than UTC,
+          GMT, and those of the local time zone are not recognized, so
+          times written with them are left as strings.
+-date-format LAYOUT
+          Recognize dates in the Go time LAYOUT instead of the default
+          formats. May be given more than once.
+-no-big   Record numbers as 64-bit integers and floats instead of with
+          arbitrary precision. Integers outside the 64-bit range become
+          floats.
+-raw-sidecar
+          Record the original, unparsed text of each value in a top-level
+          "_raw" object, keyed as the value is in the output and nested
+          with -n.
+-fidelity Record the lines of each input in a top-level "__meta__"
+          object: comments, blank lines, section headers, and the key,
+          original text, and value of each assignment, in order. -reverse
+          writes such output back as the input it came from, rewriting
+          only the assignments whose values changed.
+-comments Record the comment block preceding each assignment in a
+          top-level "_comments" object, mapping each key to a list of
+          the comments preceding its values, in order. -reverse writes
+          these back above their assignments.
+-loc      Record each value as an object with the value in "value" and
+          its location in "file", "line", and "column" (e.g.,
+          {"value": 80, "file": "app.ini", "line": 12, "column": 8}).
+          Columns count bytes from 1.
+-root-key NAME
+          Wrap each output object in an object under the key NAME.
+-diff     Convert two inputs and write the differences between their
+          outputs as an object of the keys only in the second input under
+          "added", those only in the first under "removed", and those
+          whose values differ under "changed", each as an object with its
+          "old" and "new" value. Members of nested objects are compared
+          by their keys joined with SEP.
+-get PATH Write only the value at PATH in the output: a JSON Pointer
+          (e.g., '/db/host/0') or a path of keys and array indices
+          separated by '.' (e.g., 'db.host.0'), which finds keys with or
+          without -n.
+-sort     Sort keys alphabetically. By default, keys are written in the
+          order they first appear in the input.
+-prefix NAME
+          Prefix every key with NAME and SEP, after -map.
+-prefix-from-filename
+          Prefix the keys of each input with its file name, without its
+          extension, and SEP (e.g., 'app.' for app.ini), so that keys of
+          merged inputs do not collide. Keys of included files are
+          prefixed by the input that includes them.
+-only SECTION
+          Only convert keys in sections matching the glob SECTION. May be
+          given more than once. Sections match by prefix, so 'db' also
+          selects 'db.replica'.
+-exclude SECTION
+          Do not convert keys in sections matching the glob SECTION. May
+          be given more than once.
+-map OLD=NEW
+          Rename keys matching OLD to NEW. May be given more than once;
+          the first matching rule applies. OLD may be a key or section
+          name (renaming the prefix of its keys), a glob whose '*'s are
+          substituted for '*'s in NEW, or a /regexp/ whose groups NEW
+          may refer to as $1, $2, and so on.
+-map-file FILE
+          Read -map rules from FILE, one per line.
+-schema FILE
+          Fail if the output does not match the JSON Schema in FILE.
+-allowed-keys FILE
+          Fail if any key does not match one of the globs listed, one per
+          line, in FILE. Globs match full keys (e.g., 'db.*').
 `)
 }
 
-func newRawValues() ini.Recorder {
-	return ini.Values{}
-}
-
-func newTypedValues() ini.Recorder {
-	return typedValues{}
-}
-
 func main() {
 	log.SetFlags(0)
 
 	var (
-		newValues = newTypedValues
-		raw       = false
-		casing    = "-"
-		merge     = false
-		compact   = false
-		rd        = &ini.Reader{
-			True: "true",
-		}
+		dupCheck *dupChecker
+		allowed  []string
+		opts     = newOptions()
 	)
 
 	flag.CommandLine.Usage = usage
-	// Reader flags
-	flag.StringVar(&rd.Separator, "s", ".", "prefix separator")
-	flag.StringVar(&casing, "C", casing, "case transformation (l to lowercase keys, u to uppercase, - to do nothing)")
-	flag.StringVar(&rd.True, "t", rd.True, "true value")
-	// Program flags
-	flag.BoolVar(&merge, "m", false, "merge files")
-	flag.BoolVar(&compact, "c", false, "compact output")
-	flag.BoolVar(&raw, "r", false, "do not parse values as integers, floats, bools, or JSON")
+	opts.register(flag.CommandLine)
+
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		if len(os.Args) != 3 {
+			log.Fatal("completion requires a shell: bash, zsh, or fish")
+		}
+		if err := writeCompletion(os.Stdout, os.Args[2], flag.CommandLine); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	flag.Parse()
+	if err := opts.check(); err != nil {
+		log.Fatal(err)
+	}
+
+	parser := &valueParser{raw: opts.raw, tupleSep: opts.tupleSep, typedKeys: opts.typedKeys, overrides: opts.overrides, noBig: opts.noBig, units: opts.units, dates: opts.dates, layouts: opts.layouts, arrayKeys: opts.arrayKeys, listSep: opts.listSep}
+	if len(parser.layouts) == 0 {
+		parser.layouts = dateLayouts
+	}
+	if opts.numFormats != "" {
+		if err := parser.formats.parseNames(opts.numFormats); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if opts.boolWords != "" {
+		if err := parser.parseBools(opts.boolWords); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if opts.parsers != "" {
+		if err := parser.parseNames(opts.parsers); err != nil {
+			log.Fatal(err)
+		}
+	}
+	newValues := func() ini.Recorder {
+		return newParsedValues(parser)
+	}
+
+	if opts.src.include {
+		opts.src.includePath = filepath.SplitList(opts.incPath)
+		opts.src.filters = append(opts.src.filters, includeLines)
+	}
+
+	if opts.expand {
+		opts.src.wrap = append(opts.src.wrap, expandValues)
+	}
+
+	if opts.decryptCmd != "" {
+		opts.src.wrap = append(opts.src.wrap, decryptWith(opts.decryptCmd))
+	}
+
+	if opts.transCmd != "" {
+		t := newTransformer(opts.transCmd)
+		defer t.close()
+		opts.src.wrap = append(opts.src.wrap, t.wrap)
+	}
 
-	if raw {
-		newValues = newRawValues
+	if opts.bareKey != "" {
+		opts.src.filters = append(opts.src.filters, bareLines(opts.bareKey))
+	}
+
+	keyFunc, valueFunc := identity, identity
+	if opts.keyTabs == "space" {
+		keyFunc = spaceTabs
+	}
+	if opts.collapse {
+		valueFunc = collapseSpaces
+	}
+	if opts.keyTabs != "preserve" || opts.collapse {
+		opts.src.filters = append(opts.src.filters, assignments(keyFunc, valueFunc))
+	}
+
+	switch opts.empty {
+	case "null", "empty-string", "omit":
+		opts.src.filters = append(opts.src.filters, emptyValues(opts.empty))
+	case "true":
+		// The reader already assigns TRUE to keys with an empty value.
+	}
+
+	var sch *schema
+	if opts.schemaFile != "" {
+		var err error
+		if sch, err = readSchema(opts.schemaFile); err != nil {
+			log.Fatalf("unable to read schema: %v", err)
+		}
+	}
+
+	if opts.allowFile != "" {
+		var err error
+		if allowed, err = readPatterns(opts.allowFile); err != nil {
+			log.Fatalf("unable to read allowed keys: %v", err)
+		}
 	}
 
 	args := flag.Args()
@@ -76,86 +583,812 @@ func main() {
 		args = []string{"-"}
 	}
 
-	switch casing {
+	args, err := expandPaths(args, opts.recursive)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if opts.watching && os.Getenv(watchedEnv) == "" {
+		for _, path := range args {
+			if path == "-" {
+				log.Fatal("-w cannot watch standard input")
+			} else if isURL(path) {
+				log.Fatal("-w cannot watch URLs")
+			}
+		}
+		log.Fatal(watch(args, opts.outPath, opts.interval))
+	} else if opts.watching {
+		// The watching process writes the output of each conversion.
+		opts.outPath = ""
+	}
+
+	args, err = expandArchives(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := remote.configure(opts.timeout, opts.headers, opts.caFile, opts.insecure); err != nil {
+		log.Fatalf("unable to configure URL inputs: %v", err)
+	}
+
+	var stdout io.Writer = os.Stdout
+	if opts.outPath != "" {
+		f, err := os.Create(opts.outPath)
+		if err != nil {
+			log.Fatalf("unable to create output: %v", err)
+		}
+		defer closeOutput(f)
+		stdout = f
+	}
+
+	if opts.reversed {
+		for _, path := range args {
+			w, done := stdout, func() {}
+			if opts.outDir != "" {
+				f, err := os.Create(outputPath(opts.outDir, path, "ini"))
+				if err != nil {
+					log.Fatalf("unable to create output for %v: %v", path, err)
+				}
+				w, done = f, func() { closeOutput(f) }
+			}
+			if err := reverse(w, path, opts.rd.Separator); err != nil {
+				log.Fatalf("unable to convert %v: %v", path, err)
+			}
+			done()
+		}
+		return
+	}
+
+	switch opts.casing {
 	case "l":
-		rd.Casing = ini.LowerCase
+		opts.rd.Casing = ini.LowerCase
 	case "u":
-		rd.Casing = ini.UpperCase
+		opts.rd.Casing = ini.UpperCase
 	case "-":
-		rd.Casing = ini.CaseSensitive
-	default:
-		log.Fatalf("invalid case value %+q: must be one of l, u, or -", casing)
+		opts.rd.Casing = ini.CaseSensitive
+	}
+
+	for i, o := range opts.overrides {
+		opts.overrides[i].pattern = applyCasing(opts.rd.Casing, o.pattern)
+	}
+
+	if opts.interp {
+		opts.src.wrap = append([]wrapper{interpolate(opts.rd.Separator, opts.rd.Casing)}, opts.src.wrap...)
+	}
+
+	// Default values are inherited before interpolation, so that
+	// references in them are resolved in the sections inheriting them.
+	if opts.inherit {
+		name := opts.defSection
+		if name == "" {
+			name = applyCasing(opts.rd.Casing, "DEFAULT")
+		}
+		opts.src.wrap = append([]wrapper{inheritDefaults(name, opts.rd.Separator)}, opts.src.wrap...)
+		opts.src.locate = true
+	}
+	if opts.defSection != "" || opts.sectioned {
+		opts.src.wrap = append([]wrapper{globalKeys(opts.defSection, opts.rd.Separator, opts.sectioned)}, opts.src.wrap...)
+		opts.src.locate = true
+	}
+	if opts.src.profile != "" {
+		opts.src.wrap = append([]wrapper{overrideProfile}, opts.src.wrap...)
+		opts.src.locate = true
+	}
+	if opts.src.ops {
+		opts.src.wrap = append([]wrapper{applyOperators}, opts.src.wrap...)
+		opts.src.locate = true
+	}
+
+	if len(opts.only.stringList) > 0 || len(opts.exclude.stringList) > 0 {
+		for _, l := range []stringList{opts.only.stringList, opts.exclude.stringList} {
+			for i, p := range l {
+				l[i] = applyCasing(opts.rd.Casing, p)
+			}
+		}
+		opts.src.wrap = append(opts.src.wrap, filterSections(opts.rd.Separator, opts.only.stringList, opts.exclude.stringList))
+	}
+
+	if opts.keyStyles != "" {
+		var style keyStyle
+		if err := style.parseNames(opts.keyStyles); err != nil {
+			log.Fatal(err)
+		}
+		opts.src.wrap = append(opts.src.wrap, styleKeys(style, opts.rd.Separator))
+	}
+
+	if len(opts.renames) > 0 {
+		rules := make([]renameRule, len(opts.renames))
+		for i, rule := range opts.renames {
+			var err error
+			if rules[i], err = parseRenameRule(rule, opts.rd.Separator, opts.rd.Casing); err != nil {
+				log.Fatal(err)
+			}
+		}
+		opts.src.wrap = append(opts.src.wrap, renameKeys(rules))
+	}
+
+	if opts.prefix != "" || opts.prefixFile {
+		opts.src.wrap = append(opts.src.wrap, prefixKeys(opts.prefix, opts.rd.Separator, opts.rd.Casing))
+	}
+
+	switch opts.out.dup {
+	case "append", "first", "last":
+	case "error":
+		dupCheck = newDupChecker()
+		opts.src.wrap = append(opts.src.wrap, dupCheck.wrap)
+		opts.src.locate = true
+	}
+
+	opts.out.sep = opts.rd.Separator
+
+	var mergeFunc func(dest, src ini.Recorder)
+	switch opts.strategy {
+	case "append":
+	case "override":
+		group := identity
+		if opts.out.nested {
+			group = func(key string) string {
+				return strings.SplitN(key, opts.out.sep, 2)[0]
+			}
+		}
+		mergeFunc = replaceValues(group)
+	case "deep":
+		mergeFunc = replaceValues(identity)
+	}
+
+	if opts.rawText {
+		opts.out.raw = newRawText(opts.bareKey)
+		opts.src.wrap = append(opts.src.wrap, opts.out.raw.wrap)
+		opts.src.keep, opts.src.locate = true, true
+	}
+
+	if opts.fidelity {
+		opts.out.layout = newFidelity(opts.rd.Separator, opts.rd.Casing, opts.bareKey)
+		opts.src.filters = append([]filter{opts.out.layout.filter()}, opts.src.filters...)
+	}
+
+	if opts.describe {
+		opts.out.comments = newSectionComments(opts.rd.Separator, opts.rd.Casing, opts.bareKey)
+		opts.src.wrap = append(opts.src.wrap, opts.out.comments.wrap)
+		opts.src.keep, opts.src.locate = true, true
+	}
+
+	if opts.keyNotes {
+		opts.out.notes = newKeyComments()
+		opts.src.wrap = append(opts.src.wrap, opts.out.notes.wrap)
+		opts.src.keep, opts.src.locate = true, true
+	}
+
+	// Dialect filters see the input first, so every other filter reads
+	// inputs in the default dialect.
+	dialectFilter, _ := dialectFilters(opts.dialect, opts.rd.Separator)
+	if opts.delims != "" || opts.comments != "" {
+		dialectFilter = append(dialectFilter, syntax(opts.delims, opts.comments))
+	}
+	if opts.multiline != "" {
+		dialectFilter = append(dialectFilter, multilineValues(opts.multiline))
+	}
+	opts.src.dialect = dialectFilter
+	opts.src.detect = opts.dialect == "auto"
+	if opts.strict {
+		opts.src.validate = strictLines(opts.maxErrors, opts.bareKey != "")
+	}
+	switch opts.dialect {
+	case "systemd":
+		opts.src.wrap = append([]wrapper{resetEmpty}, opts.src.wrap...)
+	case "auto":
+		opts.src.wrap = append([]wrapper{resetDetected}, opts.src.wrap...)
+	}
+
+	opts.src.limit = opts.maxBytes
+	if opts.maxKeys > 0 || opts.maxDepth > 0 {
+		opts.src.wrap = append(opts.src.wrap, limits{keys: opts.maxKeys, depth: opts.maxDepth, sep: opts.out.sep}.wrap)
+		opts.src.locate = true
+	}
+
+	var rep *conversionReport
+	if opts.reportFile != "" {
+		rep = newConversionReport(parser, opts.out.dup)
+		opts.src.opened = rep.read
+		opts.src.wrap = append(opts.src.wrap, rep.wrap)
+		opts.src.locate = true
+		defer writeReport(rep, opts.reportFile)
+	}
+
+	if opts.warnMode != "" {
+		warn := &coercions{parser: parser, w: os.Stderr, fail: opts.warnMode == "error"}
+		opts.src.wrap = append(opts.src.wrap, warn.wrap)
+		opts.src.locate = true
+	}
+
+	if opts.locValues {
+		opts.src.wrap = append(opts.src.wrap, locateValues)
+		opts.src.locate = true
+	}
+
+	var st *stream
+	if opts.streamMode != "" {
+		st = &stream{kv: opts.streamMode == "kv", newValues: newValues}
+		opts.src.wrap = append(opts.src.wrap, st.wrap)
+		opts.src.locate = opts.src.locate || !st.kv
+		opts.compact = true
+	}
+
+	if opts.diffing && len(args) != 2 {
+		log.Fatal("-diff requires two inputs")
+	}
+
+	var chk *checker
+	if opts.checking {
+		chk = &checker{w: os.Stderr, parser: parser}
+		opts.src.wrap = append(opts.src.wrap, chk.wrap)
+		opts.src.locate = true
+	}
+
+	if opts.indent != "" {
+		if opts.jopts.indent, err = parseIndent(opts.indent); err != nil {
+			log.Fatal(err)
+		}
+	}
+	newEncoder, err := encoderFor(opts.format, opts.lines, opts.compact, opts.jopts, opts.gopts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ext := extension(opts.format)
+	if opts.format == "json5" && opts.keyNotes {
+		newEncoder = func(w io.Writer) encoder { return &json5Encoder{w: w, notes: true, sep: opts.out.sep} }
+	}
+	if opts.canonical {
+		newEncoder = func(w io.Writer) encoder { return &canonicalEncoder{w: w} }
+	}
+	if opts.ascii {
+		jsonEncoder := newEncoder
+		newEncoder = func(w io.Writer) encoder { return jsonEncoder(asciiWriter{w: w}) }
+	}
+	if opts.tmplFile != "" {
+		tmpl, err := readTemplate(opts.tmplFile)
+		if err != nil {
+			log.Fatalf("unable to read template: %v", err)
+		}
+		newEncoder = func(w io.Writer) encoder { return &templateEncoder{w: w, tmpl: tmpl} }
+		ext = templateExtension(opts.tmplFile)
 	}
 
-	enc := json.NewEncoder(os.Stdout)
-	if !compact {
-		enc.SetIndent("", "  ")
+	// With -r -c and no option that needs the values recorded by the
+	// parser or the output built from them, values are written as JSON
+	// as they are read instead.
+	fastRaw := opts.raw && opts.compact && opts.format == "json" && !opts.lines && !opts.canonical && opts.tmplFile == "" && !opts.ascii &&
+		!opts.out.nested && !opts.out.single && !opts.out.sorted && opts.out.dup != "first" && opts.out.dup != "last" && opts.out.rootKey == "" &&
+		opts.out.comments == nil && opts.out.notes == nil && opts.out.raw == nil && opts.out.layout == nil && !opts.locValues &&
+		opts.allowFile == "" && !opts.roundtrip && sch == nil && opts.getPath == "" && opts.flatStyle == "" &&
+		!opts.arrayKeys && opts.listSep == "" && !opts.typedKeys && len(opts.overrides) == 0 &&
+		st == nil && opts.arrayMode == "" && !opts.merge && !opts.diffing && !opts.checking
+	if fastRaw {
+		newValues = func() ini.Recorder { return newRawWriter(opts.jopts.escapeHTML) }
+		jsonEncoder := newEncoder
+		newEncoder = func(w io.Writer) encoder { return rawEncoder{w: w, encoder: jsonEncoder(w)} }
 	}
+	enc := newEncoder(stdout)
 
+	var sp *spill
+	if opts.spilling {
+		if sp, err = newSpill(opts.jopts.escapeHTML); err != nil {
+			log.Fatalf("unable to create spill file: %v", err)
+		}
+		defer sp.Close()
+		mergeFunc = sp.merge(opts.strategy == "override")
+	}
+
+	// reset discards the data collected while reading an input, to read
+	// the next.
+	reset := func() {
+		if opts.out.comments != nil {
+			opts.out.comments.reset()
+		}
+		if opts.out.notes != nil {
+			opts.out.notes.reset()
+		}
+		if opts.out.raw != nil {
+			opts.out.raw.reset()
+		}
+		if opts.out.layout != nil {
+			opts.out.layout.reset()
+		}
+		if dupCheck != nil {
+			dupCheck.reset()
+		}
+		if rep != nil {
+			rep.reset()
+		}
+	}
+
+	if chk != nil {
+		// checkOutput reports problems with the output for values read
+		// from name.
+		checkOutput := func(values ini.Recorder, name string) {
+			if opts.allowFile != "" {
+				if err := checkAllowed(values, allowed); err != nil {
+					chk.report(name, err)
+				}
+			}
+			if opts.roundtrip {
+				if err := roundTrip(values, opts.rd, newValues); err != nil {
+					chk.report(name, err)
+				}
+			}
+			v := opts.out.output(values)
+			if sch != nil {
+				if err := sch.validate(v); err != nil {
+					chk.report(name, err)
+				}
+			}
+			if err := newEncoder(ioutil.Discard).Encode(v); err != nil {
+				chk.report(name, err)
+			}
+		}
+
+		merged := newValues()
+		if mergeFunc == nil {
+			mergeFunc = mergeValues
+		}
+		for _, path := range args {
+			values := newValues()
+			if err := opts.src.read(values, path); err != nil {
+				chk.report(path, err)
+			} else if opts.merge {
+				mergeFunc(merged, values)
+				continue
+			} else {
+				checkOutput(values, path)
+			}
+			reset()
+		}
+		if opts.merge {
+			checkOutput(merged, "merged inputs")
+		}
+		if chk.count > 0 {
+			log.Printf("problems found: %d", chk.count)
+		}
+		os.Exit(chk.status())
+	}
+
+	// prepare checks the values read from name and returns the output to
+	// encode for them.
+	prepare := func(values ini.Recorder, name string) (interface{}, error) {
+		if v, ok := values.(*rawWriter); ok {
+			return v.bytes(), nil
+		}
+		if opts.allowFile != "" {
+			if err := checkAllowed(values, allowed); err != nil {
+				return nil, fmt.Errorf("invalid keys in %v: %v", name, err)
+			}
+		}
+		if opts.roundtrip {
+			if err := roundTrip(values, opts.rd, newValues); err != nil {
+				return nil, fmt.Errorf("round trip of %v failed: %v", name, err)
+			}
+		}
+		v := opts.out.output(values)
+		if sch != nil {
+			if err := sch.validate(v); err != nil {
+				return nil, fmt.Errorf("invalid output for %v: %v", name, err)
+			}
+		}
+		if opts.getPath != "" {
+			var err error
+			if v, err = lookupPath(v, opts.getPath); err != nil {
+				return nil, fmt.Errorf("unable to get %v from %v: %v", opts.getPath, name, err)
+			}
+		}
+		if opts.flatStyle != "" {
+			var err error
+			if v, err = flatArrays(v, opts.flatStyle, opts.out.sep); err != nil {
+				return nil, fmt.Errorf("unable to flatten %v: %v", name, err)
+			}
+		}
+		return v, nil
+	}
+
+	if opts.diffing {
+		var outputs []interface{}
+		for _, path := range args {
+			values := newValues()
+			if err := opts.src.read(values, path); err != nil {
+				log.Fatalf("unable to parse %v: %v", path, err)
+			}
+			v, err := prepare(values, path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			outputs = append(outputs, v)
+			reset()
+		}
+		report, err := diff(outputs[0], outputs[1], opts.out.sep)
+		if err != nil {
+			log.Fatalf("unable to compare %v and %v: %v", args[0], args[1], err)
+		}
+		if err := enc.Encode(report); err != nil {
+			log.Fatalf("unable to encode differences: %v", err)
+		}
+		return
+	}
+
+	var docs []interface{} // Outputs collected for -A.
 	values := newValues()
-	for _, path := range args {
-		if err := read(values, rd, path); err != nil {
+	if opts.merge {
+		var dups *conflicts
+		var seen func(string, ini.Recorder)
+		if opts.explain || opts.failDup {
+			dups = newConflicts()
+			seen = dups.add
+		}
+		if path, err := readMerged(values, newValues, opts.src.read, args, opts.jobs, seen, mergeFunc); err != nil {
 			log.Fatalf("unable to parse %v: %v", path, err)
 		}
+		if opts.explain {
+			if err := dups.report(os.Stderr); err != nil {
+				log.Fatalf("unable to write conflict report: %v", err)
+			}
+		}
+		if opts.failDup {
+			if n := len(dups.list()); n > 0 {
+				log.Fatalf("keys defined by more than one file: %d", n)
+			}
+		}
+	}
 
-		if merge {
-			continue
+	if st != nil {
+		for _, path := range args {
+			e, done := enc, func() {}
+			if opts.outDir != "" {
+				f, err := os.Create(outputPath(opts.outDir, path, ext))
+				if err != nil {
+					log.Fatalf("unable to create output for %v: %v", path, err)
+				}
+				e, done = newEncoder(f), func() { closeOutput(f) }
+			}
+			st.emit = func(values ini.Recorder) {
+				v, err := prepare(values, path)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if err := e.Encode(v); err != nil {
+					log.Fatalf("unable to encode values from %v: %v", path, err)
+				}
+			}
+			err := opts.src.read(st, path)
+			if err == nil {
+				err = st.flush()
+			}
+			if err != nil {
+				log.Fatalf("unable to parse %v: %v", path, err)
+			}
+			done()
+			if dupCheck != nil {
+				dupCheck.reset()
+			}
+			if rep != nil {
+				rep.reset()
+			}
+		}
+	} else if !opts.merge {
+		// convert reads the input named by path and returns its output,
+		// writing it to a file in outDir if one is set.
+		convert := func(path string) (interface{}, error) {
+			values := newValues()
+			if err := opts.src.read(values, path); err != nil {
+				return nil, fmt.Errorf("unable to parse %v: %v", path, err)
+			}
+			defer reset()
+			v, err := prepare(values, path)
+			if err != nil || opts.outDir == "" {
+				return v, err
+			}
+			f, err := os.Create(outputPath(opts.outDir, path, ext))
+			if err != nil {
+				return nil, fmt.Errorf("unable to create output for %v: %v", path, err)
+			}
+			if err := newEncoder(f).Encode(v); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("unable to encode values from %v: %v", path, err)
+			}
+			if err := f.Close(); err != nil {
+				return nil, fmt.Errorf("unable to write %v: %v", f.Name(), err)
+			}
+			return v, nil
 		}
+		err := convertFiles(args, opts.jobs, convert, func(path string, v interface{}) error {
+			switch {
+			case opts.outDir != "":
+			case opts.arrayMode != "":
+				docs = append(docs, arrayElement(opts.arrayMode, path, v))
+			default:
+				if err := enc.Encode(v); err != nil {
+					return fmt.Errorf("unable to encode values from %v: %v", path, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
-		if err := enc.Encode(values); err != nil {
-			log.Fatalf("unable to encode values from %v: %v", path, err)
+	if opts.arrayMode != "" {
+		if docs == nil {
+			docs = []interface{}{}
 		}
-		values = newValues()
+		if err := enc.Encode(docs); err != nil {
+			log.Fatalf("unable to encode values: %v", err)
+		}
+	}
+
+	if !opts.merge {
+		return
 	}
 
-	if !merge {
+	if sp != nil {
+		w, indent := stdout, opts.jopts.indent
+		if opts.ascii {
+			w = asciiWriter{w: w}
+		}
+		if opts.compact {
+			indent = ""
+		}
+		if err := sp.writeJSON(w, &opts.out, indent); err != nil {
+			log.Fatalf("unable to encode final values: %v", err)
+		}
 		return
 	}
 
-	if err := enc.Encode(values); err != nil {
+	v, err := prepare(values, "merged inputs")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := enc.Encode(v); err != nil {
 		log.Fatalf("unable to encode final values: %v", err)
 	}
 }
 
-func read(dest ini.Recorder, rd *ini.Reader, path string) error {
-	var r io.Reader
-	switch path {
-	case "-":
-		r = os.Stdin
-	default:
-		f, err := os.Open(path)
-		if err != nil {
-			return err
+// outputOptions controls how recorded values are transformed before
+// they are encoded.
+type outputOptions struct {
+	dup      string           // Duplicate key policy: append, first, or last.
+	single   bool             // Whether keys with one value are scalars.
+	nested   bool             // Whether to split keys into nested objects.
+	sep      string           // Separator to split nested keys on.
+	comments *sectionComments // Section descriptions, if recorded.
+	notes    *keyComments     // Key comments, if recorded.
+	raw      *rawText         // Original value text, if recorded.
+	layout   *fidelity        // Lines of the input, if recorded.
+	rootKey  string           // If set, wrap output in an object under this key.
+	sorted   bool             // Whether to sort keys instead of keeping source order.
+}
+
+// output returns the value to encode for values and any other data
+// collected while reading them.
+func (o *outputOptions) output(values ini.Recorder) interface{} {
+	root := orderedDocument(values)
+	var meta *object
+	if o.layout != nil {
+		meta = o.layout.meta(root)
+	}
+	if o.dup == "first" || o.dup == "last" {
+		keepOne(root.values, o.dup == "last")
+	}
+	if o.single {
+		var arrays map[string]bool
+		if v, ok := values.(parsedValues); ok {
+			arrays = v.arrays
 		}
-		defer f.Close()
-		r = f
+		unwrapSingle(root.values, arrays)
 	}
-	return rd.Read(r, dest)
+	if o.nested {
+		root = nest(root, o.sep)
+		if o.comments != nil {
+			for section, desc := range o.comments.desc {
+				objectAt(root, strings.Split(section, o.sep)).Set("_description", desc)
+			}
+		}
+	} else if o.comments != nil {
+		root.Set("_descriptions", o.comments.desc)
+	}
+	if o.notes != nil {
+		root.Set("_comments", o.notes.comments)
+	}
+	if o.raw != nil {
+		root.Set("_raw", o.raw.object(o.nested, o.sep))
+	}
+	if meta != nil {
+		root.Set(metaKey, meta)
+	}
+	if o.sorted {
+		root.Sort()
+	}
+	return o.wrap(root)
 }
 
-type typedValues map[string][]interface{}
+// wrap returns out wrapped under the root key, if one is set.
+func (o *outputOptions) wrap(out *object) *object {
+	if o.rootKey == "" {
+		return out
+	}
+	root := newObject()
+	root.Set(o.rootKey, out)
+	return root
+}
+
+// arrayElement returns the element of the -A array for the output v of
+// the input named by path: v itself or, if mode is named, an object
+// holding the input's name and v.
+func arrayElement(mode, path string, v interface{}) interface{} {
+	if mode != "named" {
+		return v
+	}
+	doc := newObject()
+	doc.Set("file", path)
+	doc.Set("values", v)
+	return doc
+}
 
-type bigFloat big.Float
+// outputPath returns the path of the output file in dir for the input
+// named by path, with its extension replaced by ext.
+func outputPath(dir, path, ext string) string {
+	return filepath.Join(dir, inputName(path)+"."+ext)
+}
+
+// inputName returns the name of the input named by path: its file name
+// without its extension, or stdin for standard input.
+func inputName(path string) string {
+	if _, member, ok := splitMember(path); ok {
+		path = member
+	}
+	if path == "-" {
+		return "stdin"
+	}
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
 
-func (b *bigFloat) Float() *big.Float {
-	return (*big.Float)(b)
+// extension returns the file extension of output in format.
+func extension(format string) string {
+	switch format {
+	case "gostruct":
+		return "go"
+	case "env", "env=export":
+		return "env"
+	}
+	return format
 }
 
-func (b *bigFloat) MarshalJSON() ([]byte, error) {
-	return b.Float().MarshalText()
+// writeReport writes the conversion report rep to the file at path,
+// exiting if it fails.
+func writeReport(rep *conversionReport, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("unable to create report: %v", err)
+	}
+	defer closeOutput(f)
+	if err := rep.write(f); err != nil {
+		log.Fatalf("unable to write report: %v", err)
+	}
 }
 
-func (v typedValues) Add(key, value string) {
-	var jsval interface{}
-	if ival, ok := new(big.Int).SetString(value, 10); ok {
-		jsval = ival
-	} else if fval, _, err := big.ParseFloat(value, 10, 256, big.ToNearestEven); err == nil {
-		jsval = (*bigFloat)(fval)
-	} else if bval, err := strconv.ParseBool(value); err == nil {
-		jsval = bval
-	} else if json.Unmarshal([]byte(value), &jsval) == nil {
+// parseIndent returns the indentation named by s: s spaces if it is a
+// number, or s with \t and \s escapes replaced by tabs and spaces.
+func parseIndent(s string) (string, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 16 {
+			return "", fmt.Errorf("invalid indent %d: must be from 0 to 16 spaces", n)
+		}
+		return strings.Repeat(" ", n), nil
+	}
+	indent := strings.NewReplacer(`\t`, "\t", `\s`, " ").Replace(s)
+	if strings.Trim(indent, " \t") != "" {
+		return "", fmt.Errorf("invalid indent %+q: must be a number or spaces and tabs", s)
+	}
+	return indent, nil
+}
+
+// closeOutput closes the output file f, exiting if it fails.
+func closeOutput(f *os.File) {
+	if err := f.Close(); err != nil {
+		log.Fatalf("unable to write %v: %v", f.Name(), err)
+	}
+}
+
+// openInput opens the input named by path. The path "-" is standard input,
+// paths of the form ARCHIVE!NAME name a file in an archive, and http://
+// and https:// URLs are fetched. Inputs compressed with gzip are
+// decompressed.
+func openInput(path string) (io.ReadCloser, error) {
+	var (
+		r   io.Reader
+		c   io.Closer = ioutil.NopCloser(nil)
+		err error
+	)
+	if archive, member, ok := splitMember(path); ok {
+		var rc io.ReadCloser
+		rc, err = openMember(archive, member)
+		r, c = rc, rc
+	} else if path == "-" {
+		r = os.Stdin
+	} else if isURL(path) {
+		var body io.ReadCloser
+		body, err = remote.open(path)
+		r, c = body, body
 	} else {
-		jsval = value
+		var f *os.File
+		f, err = os.Open(path)
+		r, c = f, f
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r, err = decompress(r); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, c}, nil
+}
+
+// reverse writes the JSON values in the input named by path to w as INI.
+func reverse(w io.Writer, path, sep string) error {
+	in, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return readOrdered(in, func(v interface{}) error {
+		return writeINI(w, v, sep)
+	})
+}
+
+// document returns the values recorded in values as a generic JSON object.
+func document(values ini.Recorder) map[string]interface{} {
+	doc := map[string]interface{}{}
+	if v, ok := values.(parsedValues); ok {
+		for k, vs := range v.Values {
+			doc[k] = vs
+		}
+	}
+	return doc
+}
+
+// orderedDocument returns the values recorded in values as an object with
+// keys in the order they were first recorded.
+func orderedDocument(values ini.Recorder) *object {
+	obj := newObject()
+	if v, ok := values.(parsedValues); ok {
+		// order holds each key once, so the object can be built
+		// without checking for keys already set.
+		obj.keys = append([]string(nil), *v.order...)
+		obj.values = make(map[string]interface{}, len(obj.keys))
+		for _, k := range obj.keys {
+			obj.values[k] = v.Values[k]
+		}
+	}
+	return obj
+}
+
+// unwrapSingle replaces each key in doc that has exactly one value with
+// that value, unless the key is in arrays.
+func unwrapSingle(doc map[string]interface{}, arrays map[string]bool) {
+	for k, v := range doc {
+		if arrays[k] {
+			continue
+		}
+		switch vs := v.(type) {
+		case []interface{}:
+			if len(vs) == 1 {
+				doc[k] = vs[0]
+			}
+		case []string:
+			if len(vs) == 1 {
+				doc[k] = vs[0]
+			}
+		}
 	}
-	v[key] = append(v[key], jsval)
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestRootKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		nested bool
+		texts  []string
+		want   string
+	}{
+		{"object", false, []string{"[db]\nhost = a\n"}, `{"config":{"db.host":["a"]}}`},
+		{"nested", true, []string{"[db]\nhost = a\n"}, `{"config":{"db":{"host":["a"]}}}`},
+		{"empty", false, []string{""}, `{"config":{}}`},
+		{"merged", false, []string{"[db]\nhost = a\n", "[db]\nhost = b\nport = 1\n"},
+			`{"config":{"db.host":["a","b"],"db.port":[1]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, done := tempFiles(t, tt.texts...)
+			defer done()
+			src := &source{rd: &ini.Reader{Separator: ".", True: "true"}}
+			values := newParsedValues(&valueParser{})
+			newValues := func() ini.Recorder { return newParsedValues(&valueParser{}) }
+			if path, err := readMerged(values, newValues, src.read, paths, 1, nil, nil); err != nil {
+				t.Fatalf("readMerged: %s: %v", path, err)
+			}
+
+			out := &outputOptions{dup: "append", nested: tt.nested, sep: ".", rootKey: "config"}
+			p, err := marshalJSON(out.output(values), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(p); got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSingle(t *testing.T) {
+	const in = "a = 1\nb = 1\nb = 2\n[s]\nc = x\n"
+	tests := []struct {
+		name   string
+		parser *valueParser
+		nested bool
+		want   string
+	}{
+		{"typed", &valueParser{}, false, `{"a":1,"b":[1,2],"s.c":"x"}`},
+		{"raw", &valueParser{raw: true}, false, `{"a":"1","b":["1","2"],"s.c":"x"}`},
+		{"nested", &valueParser{}, true, `{"a":1,"b":[1,2],"s":{"c":"x"}}`},
+	}
+	for _, tt := range tests {
+		values := readValues(t, &source{}, tt.parser, in)
+		got := outputString(t, &outputOptions{single: true, nested: tt.nested, sep: "."}, values)
+		if got != tt.want {
+			t.Errorf("%s: output = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	tests := []struct {
+		path, ext, want string
+	}{
+		{"conf/app.ini", "json", filepath.Join("out", "app.json")},
+		{"app", "yaml", filepath.Join("out", "app.yaml")},
+		{"a.b.ini", "toml", filepath.Join("out", "a.b.toml")},
+		{"-", "json", filepath.Join("out", "stdin.json")},
+		{"conf.zip" + memberSep + "dir/db.ini", "json", filepath.Join("out", "db.json")},
+	}
+	for _, tt := range tests {
+		if got := outputPath("out", tt.path, tt.ext); got != tt.want {
+			t.Errorf("outputPath(out, %q, %q) = %q, want %q", tt.path, tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestArrayElement(t *testing.T) {
+	v := newObject()
+	v.Set("a", 1)
+	for _, tt := range []struct {
+		mode, want string
+	}{
+		{"plain", `{"a":1}`},
+		{"named", `{"file":"in/test.ini","values":{"a":1}}`},
+	} {
+		p, err := marshalJSON(arrayElement(tt.mode, "in/test.ini", v), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(p); got != tt.want {
+			t.Errorf("arrayElement(%s) = %s, want %s", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestParseIndent(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"0", ""},
+		{"4", "    "},
+		{`\t`, "\t"},
+		{`\s\s`, "  "},
+		{" \t", " \t"},
+	} {
+		if got, err := parseIndent(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseIndent(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"-1", "17", "x", `\n`} {
+		if _, err := parseIndent(in); err == nil {
+			t.Errorf("parseIndent(%q) = nil error, want an error", in)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// interpolate returns a wrapper that replaces %(name)s and ${name}
+// references in values with the last value of the key name. Names are
+// looked up in the section of the key being recorded, then in the
+// DEFAULT section, then as full keys. %% and $$ are replaced with % and $.
+// Since a value may refer to a key assigned after it, values are held
+// until the input has been read and then passed on in order.
+func interpolate(sep string, casing ini.Casing) wrapper {
+	return func(dest ini.Recorder, at *cursor) ini.Recorder {
+		return &interpolator{
+			dest:     dest,
+			at:       at,
+			sep:      sep,
+			casing:   casing,
+			last:     map[string]int{},
+			resolved: map[int]string{},
+		}
+	}
+}
+
+// heldValue is a value held by an interpolator, with the position of the
+// cursor when it was recorded.
+type heldValue struct {
+	key, value string
+	loc        location
+	section    int
+}
+
+type interpolator struct {
+	dest   ini.Recorder
+	at     *cursor
+	sep    string
+	casing ini.Casing
+
+	held     []heldValue
+	last     map[string]int // Index in held of the last value of each key.
+	resolved map[int]string
+	done     bool
+}
+
+func (in *interpolator) Add(key, value string) {
+	in.last[key] = len(in.held)
+	in.held = append(in.held, heldValue{
+		key:     key,
+		value:   value,
+		loc:     in.at.loc,
+		section: in.at.section,
+	})
+}
+
+// Err passes each held value, interpolated, on to dest and returns an
+// error listing every value that could not be interpolated. It must be
+// called once the input has been read.
+func (in *interpolator) Err() error {
+	if in.done {
+		return nil
+	}
+	in.done = true
+
+	var errs []string
+	for i, h := range in.held {
+		value, err := in.resolve(i, nil)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", h.key, err))
+			continue
+		}
+		in.at.loc, in.at.section = h.loc, h.section
+		in.dest.Add(h.key, value)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid interpolation:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// resolve returns the interpolated text of the held value i. stack is the
+// list of values being resolved that refer to i, used to detect cycles.
+func (in *interpolator) resolve(i int, stack []int) (string, error) {
+	if s, ok := in.resolved[i]; ok {
+		return s, nil
+	}
+	for _, j := range stack {
+		if j == i {
+			var keys []string
+			for _, j := range append(stack, i) {
+				keys = append(keys, in.held[j].key)
+			}
+			return "", fmt.Errorf("reference cycle: %s", strings.Join(keys, " -> "))
+		}
+	}
+
+	h := in.held[i]
+	s := h.value
+	var buf strings.Builder
+	for len(s) > 0 {
+		var name string
+		switch {
+		case strings.HasPrefix(s, "%%"), strings.HasPrefix(s, "$$"):
+			buf.WriteByte(s[0])
+			s = s[2:]
+			continue
+		case strings.HasPrefix(s, "%("):
+			end := strings.Index(s, ")s")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference in %+q", h.value)
+			}
+			name, s = s[2:end], s[end+2:]
+		case strings.HasPrefix(s, "${"):
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference in %+q", h.value)
+			}
+			name, s = s[2:end], s[end+1:]
+		default:
+			buf.WriteByte(s[0])
+			s = s[1:]
+			continue
+		}
+
+		j, ok := in.lookup(h.key, applyCasing(in.casing, name))
+		if !ok {
+			return "", fmt.Errorf("undefined reference to %+q", name)
+		}
+		text, err := in.resolve(j, append(stack, i))
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(text)
+	}
+	in.resolved[i] = buf.String()
+	return in.resolved[i], nil
+}
+
+// lookup returns the index of the last held value of the key that name
+// refers to from key.
+func (in *interpolator) lookup(key, name string) (int, bool) {
+	var candidates []string
+	if i := strings.LastIndex(key, in.sep); in.sep != "" && i >= 0 {
+		candidates = append(candidates, key[:i]+in.sep+name)
+	}
+	candidates = append(candidates, applyCasing(in.casing, "DEFAULT")+in.sep+name, name)
+	for _, c := range candidates {
+		if i, ok := in.last[c]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
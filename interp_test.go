@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestInterpolate(t *testing.T) {
+	const in = "root = /srv\n" +
+		"[DEFAULT]\nuser = app\n" +
+		"[paths]\ndata = %(root)s/data\nlogs = ${data}/logs\nhome = /home/${user}\nroot = /opt\n" +
+		"[other]\ncost = 100%% or $$5\nfull = ${paths.logs}\n"
+	src := &source{wrap: []wrapper{interpolate(".", ini.CaseSensitive)}}
+	got := readString(t, src, &valueParser{}, in)
+	want := `{"root":["/srv"],"DEFAULT.user":["app"],` +
+		`"paths.data":["/opt/data"],"paths.logs":["/opt/data/logs"],"paths.home":["/home/app"],"paths.root":["/opt"],` +
+		`"other.cost":["100% or $5"],"other.full":["/opt/data/logs"]}`
+	if got != want {
+		t.Errorf("interpolate(%q) = %s, want %s", in, got, want)
+	}
+
+	// Names are looked up with the casing of keys.
+	src = &source{
+		rd:   &ini.Reader{Separator: ".", True: "true", Casing: ini.LowerCase},
+		wrap: []wrapper{interpolate(".", ini.LowerCase)},
+	}
+	got = readString(t, src, &valueParser{}, "[default]\nuser = app\n[s]\nhome = /home/${USER}\n")
+	if want := `{"default.user":["app"],"s.home":["/home/app"]}`; got != want {
+		t.Errorf("interpolate with lower case = %s, want %s", got, want)
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a = ${b\n", `a: unterminated reference in "${b"`},
+		{"a = %(b)\n", `a: unterminated reference in "%(b)"`},
+		{"a = ${b}\n", `a: undefined reference to "b"`},
+		{"a = ${b}\nb = ${c}\nc = ${a}\n", "a: reference cycle: a -> b -> c -> a"},
+	}
+	for _, tt := range tests {
+		paths, done := tempFiles(t, tt.in)
+		src := &source{
+			rd:   &ini.Reader{Separator: ".", True: "true"},
+			wrap: []wrapper{interpolate(".", ini.CaseSensitive)},
+		}
+		err := src.read(newParsedValues(&valueParser{}), paths[0])
+		done()
+		if err == nil {
+			t.Errorf("read(%q) = nil, want an error", tt.in)
+		} else if !strings.Contains(err.Error(), "\n  "+tt.want+"\n") && !strings.HasSuffix(err.Error(), "\n  "+tt.want) {
+			t.Errorf("read(%q) = %q, want it to list %q", tt.in, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// json5Encoder encodes values as JSON5: indented, with keys unquoted where
+// they are identifiers and a comma after every member and element. If
+// notes is true, the "_comments" object recorded by -comments is written
+// as // comments above the members it describes instead of as a member,
+// with the keys of nested members joined by sep to look them up.
+type json5Encoder struct {
+	w     io.Writer
+	notes bool
+	sep   string
+}
+
+func (e *json5Encoder) Encode(v interface{}) error {
+	g, err := ordered(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	switch g := g.(type) {
+	case *object:
+		e.writeDocument(&buf, g, 0)
+	case []interface{}:
+		// Documents collected by -A carry their own comments.
+		if len(g) == 0 {
+			buf.WriteString("[]")
+			break
+		}
+		buf.WriteString("[\n")
+		for _, elem := range g {
+			buf.WriteString("  ")
+			if doc, ok := elem.(*object); ok {
+				e.writeDocument(&buf, doc, 1)
+			} else {
+				writeJSON5(&buf, elem, 1, nil, nil)
+			}
+			buf.WriteString(",\n")
+		}
+		buf.WriteByte(']')
+	default:
+		writeJSON5(&buf, g, 0, nil, nil)
+	}
+	buf.WriteByte('\n')
+	_, err = buf.WriteTo(e.w)
+	return err
+}
+
+// writeDocument writes the document doc, taking its comments from it if
+// notes is set.
+func (e *json5Encoder) writeDocument(buf *bytes.Buffer, doc *object, indent int) {
+	if !e.notes {
+		writeJSON5(buf, doc, indent, nil, nil)
+		return
+	}
+	notes, _ := doc.Get("_comments")
+	c := &json5Comments{sep: e.sep}
+	c.notes, _ = notes.(*object)
+	writeJSON5(buf, doc, indent, nil, c)
+}
+
+// json5Comments holds the comments written by writeJSON5.
+type json5Comments struct {
+	notes *object // Lists of comments by key.
+	sep   string
+}
+
+// lookup returns the list of comments for the member at path.
+func (c *json5Comments) lookup(path []string) []string {
+	if c == nil || c.notes == nil {
+		return nil
+	}
+	v, _ := c.notes.Get(strings.Join(path, c.sep))
+	list, _ := v.([]interface{})
+	comments := make([]string, len(list))
+	for i, s := range list {
+		comments[i], _ = s.(string)
+	}
+	return comments
+}
+
+// writeJSON5 writes the JSON5 encoding of the ordered value v at the given
+// indentation level. path is the list of keys of v in its document, used
+// to look up comments in c, which may be nil.
+func writeJSON5(buf *bytes.Buffer, v interface{}, indent int, path []string, c *json5Comments) {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case *object:
+		if v.Len() == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteString("{\n")
+		for _, k := range v.Keys() {
+			if c != nil && len(path) == 0 && k == "_comments" {
+				continue
+			}
+			elem, _ := v.Get(k)
+			at := append(path[:len(path):len(path)], k)
+			comments := c.lookup(at)
+			list, ok := elem.([]interface{})
+			if ok && len(comments) > 1 && len(comments) == len(list) {
+				// Each comment precedes the value it was written above.
+				buf.WriteString(pad + "  " + json5Key(k) + ": [\n")
+				for i, elem := range list {
+					writeJSON5Comment(buf, comments[i], indent+2)
+					buf.WriteString(pad + "    ")
+					writeJSON5(buf, elem, indent+2, at, c)
+					buf.WriteString(",\n")
+				}
+				buf.WriteString(pad + "  ],\n")
+				continue
+			}
+			for _, comment := range comments {
+				writeJSON5Comment(buf, comment, indent+1)
+			}
+			buf.WriteString(pad + "  " + json5Key(k) + ": ")
+			writeJSON5(buf, elem, indent+1, at, c)
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(pad + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteString("[\n")
+		for _, elem := range v {
+			buf.WriteString(pad + "  ")
+			writeJSON5(buf, elem, indent+1, path, c)
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(pad + "]")
+	case json.Number:
+		buf.WriteString(string(v))
+	case string:
+		buf.WriteString(quoteString(v))
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case nil:
+		buf.WriteString("null")
+	}
+}
+
+// writeJSON5Comment writes each line of comment as a // comment at the
+// given indentation level. Empty comments are not written.
+func writeJSON5Comment(buf *bytes.Buffer, comment string, indent int) {
+	if comment == "" {
+		return
+	}
+	pad := strings.Repeat("  ", indent)
+	for _, line := range strings.Split(comment, "\n") {
+		buf.WriteString(strings.TrimRight(pad+"// "+line, " ") + "\n")
+	}
+}
+
+// json5Key returns k unquoted if it is an identifier, and quoted otherwise.
+func json5Key(k string) string {
+	for i, r := range k {
+		switch {
+		case r == '_' || r == '$' || unicode.IsLetter(r):
+		case i > 0 && unicode.IsDigit(r):
+		default:
+			return quoteString(k)
+		}
+	}
+	if k == "" {
+		return `""`
+	}
+	return k
+}
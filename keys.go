@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// keyStyle is a canonical form for the segments of keys.
+type keyStyle struct {
+	trim  bool   // Whether to trim spaces around segments.
+	words string // How words are joined: snake, camel, kebab, or "".
+}
+
+// parseNames sets the styles named in the comma-separated list names.
+func (k *keyStyle) parseNames(names string) error {
+	for _, name := range strings.Split(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "trim":
+			k.trim = true
+		case "snake", "camel", "kebab":
+			if k.words != "" && k.words != name {
+				return fmt.Errorf("key styles %s and %s cannot be used together", k.words, name)
+			}
+			k.words = name
+		default:
+			return fmt.Errorf("invalid key style %+q: must be one of trim, snake, camel, or kebab", name)
+		}
+	}
+	return nil
+}
+
+// apply returns key with each segment, separated by sep, in the style.
+func (k keyStyle) apply(key, sep string) string {
+	segments := []string{key}
+	if sep != "" {
+		segments = strings.Split(key, sep)
+	}
+	for i, s := range segments {
+		if k.trim {
+			s = strings.TrimSpace(s)
+		}
+		if k.words != "" {
+			s = joinWords(splitWords(s), k.words)
+		}
+		segments[i] = s
+	}
+	return strings.Join(segments, sep)
+}
+
+// splitWords returns the words of s, which are separated by spaces, '_',
+// or '-', or begin at an uppercase letter following a lowercase letter or
+// digit, or preceding a lowercase letter in a run of uppercase letters
+// (e.g., "HTTPServer" is "HTTP" and "Server").
+func splitWords(s string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	rs := []rune(s)
+	for i, r := range rs {
+		if r == ' ' || r == '_' || r == '-' || r == '\t' {
+			if len(word) > 0 {
+				words, word = append(words, string(word)), nil
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := word[len(word)-1]
+			next := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && next {
+				words, word = append(words, string(word)), nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// joinWords joins words in the style: lowercase and separated by '_' for
+// snake or '-' for kebab, or for camel, joined with each word after the
+// first capitalized.
+func joinWords(words []string, style string) string {
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if style == "camel" && i > 0 {
+			rs := []rune(w)
+			rs[0] = unicode.ToUpper(rs[0])
+			w = string(rs)
+		}
+		words[i] = w
+	}
+	switch style {
+	case "snake":
+		return strings.Join(words, "_")
+	case "kebab":
+		return strings.Join(words, "-")
+	}
+	return strings.Join(words, "")
+}
+
+// styleKeys returns a wrapper that records keys in the style, with their
+// segments separated by sep.
+func styleKeys(style keyStyle, sep string) wrapper {
+	return func(dest ini.Recorder, _ *cursor) ini.Recorder {
+		return styledKeys{Recorder: dest, style: style, sep: sep}
+	}
+}
+
+type styledKeys struct {
+	ini.Recorder
+	style keyStyle
+	sep   string
+}
+
+func (s styledKeys) Add(key, value string) {
+	s.Recorder.Add(s.style.apply(key, s.sep), value)
+}
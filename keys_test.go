@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestKeyStyles(t *testing.T) {
+	tests := []struct {
+		names, key, want string
+	}{
+		{"trim", " db . Host Name ", "db.Host Name"},
+		{"snake", "HTTPServer.maxConns", "http_server.max_conns"},
+		{"snake", "user-ID2Name", "user_id2_name"},
+		{"camel", "max_conn-count.Log Level", "maxConnCount.logLevel"},
+		{"kebab", "ServerURL.api_Key", "server-url.api-key"},
+		{"trim,kebab", " a b . c_d ", "a-b.c-d"},
+		{"snake", "ÄrgerÜber.x", "ärger_über.x"},
+		{"camel", "__", ""},
+	}
+	for _, tt := range tests {
+		var k keyStyle
+		if err := k.parseNames(tt.names); err != nil {
+			t.Fatalf("parseNames(%q) = %v", tt.names, err)
+		}
+		if got := k.apply(tt.key, "."); got != tt.want {
+			t.Errorf("-K %s: apply(%q) = %q, want %q", tt.names, tt.key, got, tt.want)
+		}
+	}
+
+	for _, names := range []string{"snake,camel", "upper"} {
+		var k keyStyle
+		if err := k.parseNames(names); err == nil {
+			t.Errorf("parseNames(%q) = nil, want an error", names)
+		}
+	}
+	var k keyStyle
+	if err := k.parseNames("snake, snake"); err != nil {
+		t.Errorf("parseNames(snake, snake) = %v", err)
+	}
+
+	style := keyStyle{words: "snake"}
+	src := &source{wrap: []wrapper{styleKeys(style, ".")}}
+	got := readString(t, src, &valueParser{}, "[HttpServer]\nMaxConns = 1\nmax-conns = 2\n")
+	if want := `{"http_server.max_conns":[1,2]}`; got != want {
+		t.Errorf("read with -K snake = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// limitBytes returns a filter that fails if its input, after
+// decompression, is larger than max bytes.
+func limitBytes(max int64) filter {
+	return func(w io.Writer, r io.Reader) error {
+		n, err := io.Copy(w, io.LimitReader(r, max+1))
+		if err != nil {
+			return err
+		} else if n > max {
+			return fmt.Errorf("input is larger than the limit of %d bytes", max)
+		}
+		return nil
+	}
+}
+
+// limits bounds the values recorded for each input.
+type limits struct {
+	keys  int    // If positive, the maximum number of distinct keys.
+	depth int    // If positive, the maximum number of elements of a key.
+	sep   string // Separator of key elements.
+}
+
+// wrap returns a recorder that passes values on to dest until a limit is
+// exceeded. Values after that are dropped and reported by Err.
+func (l limits) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return &limited{limits: l, dest: dest, at: at, seen: map[string]bool{}}
+}
+
+type limited struct {
+	limits
+	dest ini.Recorder
+	at   *cursor
+	seen map[string]bool
+	err  error
+}
+
+func (l *limited) Add(key, value string) {
+	if l.err != nil {
+		return
+	}
+	if l.depth > 0 && strings.Count(key, l.sep) >= l.depth {
+		l.err = fmt.Errorf("%v: key %+q is nested deeper than the limit of %d", l.at.Location(), key, l.depth)
+		return
+	}
+	if !l.seen[key] {
+		if l.keys > 0 && len(l.seen) >= l.keys {
+			l.err = fmt.Errorf("%v: key %+q exceeds the limit of %d keys", l.at.Location(), key, l.keys)
+			return
+		}
+		l.seen[key] = true
+	}
+	l.dest.Add(key, value)
+}
+
+// Err returns the error for the first value that exceeded a limit, if any.
+func (l *limited) Err() error {
+	return l.err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestLimits(t *testing.T) {
+	const in = "a = 1\na = 2\n[s]\nb = 3\n[s.t]\nc = 4\n"
+	tests := []struct {
+		limits limits
+		want   string
+	}{
+		{limits{}, ""},
+		{limits{keys: 3, depth: 3}, ""},
+		{limits{keys: 2}, `:6: key "s.t.c" exceeds the limit of 2 keys`},
+		{limits{depth: 2}, `:6: key "s.t.c" is nested deeper than the limit of 2`},
+		{limits{depth: 1}, `:4: key "s.b" is nested deeper than the limit of 1`},
+	}
+	paths, done := tempFiles(t, in)
+	defer done()
+	for _, tt := range tests {
+		tt.limits.sep = "."
+		src := &source{
+			rd:     &ini.Reader{Separator: ".", True: "true"},
+			wrap:   []wrapper{tt.limits.wrap},
+			locate: true,
+		}
+		err := src.read(newParsedValues(&valueParser{}), paths[0])
+		want := tt.want
+		if want != "" {
+			want = paths[0] + want
+		}
+		if errString(err) != want {
+			t.Errorf("-limit-keys %d -limit-depth %d = %v, want %s", tt.limits.keys, tt.limits.depth, err, want)
+		}
+	}
+}
+
+func TestLimitBytes(t *testing.T) {
+	in := strings.Repeat("k = v\n", 10)
+	if got := runFilter(t, limitBytes(int64(len(in))), in); got != in {
+		t.Errorf("limitBytes(%d) changed its input", len(in))
+	}
+	rc := limitBytes(int64(len(in) - 1)).apply(strings.NewReader(in))
+	defer rc.Close()
+	buf := make([]byte, 2*len(in))
+	var err error
+	for err == nil {
+		_, err = rc.Read(buf)
+	}
+	if want := "input is larger than the limit of 59 bytes"; errString(err) != want {
+		t.Errorf("limitBytes(59) = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// location is the position of a value in an input.
+type location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (l location) String() string {
+	if l.Line == 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// cursor tracks the location of the value being recorded from an input.
+// Its filter queues the line of each line that records a value, and the
+// located recorder advances to the next queued line for each value, since
+// the reader records values in the order their lines appear.
+type cursor struct {
+	mu      sync.Mutex
+	pending []mark
+	cols    map[int]int    // Column of the value of each line, by line.
+	empty   map[int]bool   // Lines of keys written without a value.
+	ops     map[int]string // Operators other than '=', by line.
+	profile map[int]bool   // Lines of values in profile variants.
+	text    map[int]string // Text of each line that is not a comment, by line, if kept.
+	notes   map[int]string // Comment block preceding each line, by line, if kept.
+	heads   map[int]int    // Line of each section header, by section number, if kept.
+	dialect string         // Dialect detected for the input, with -f auto.
+	loc     location
+	section int    // The number of section headers preceding the value.
+	input   string // The input that includes the file, or the file itself.
+}
+
+// mark is the position of a line that records a value.
+type mark struct {
+	line    int
+	section int
+}
+
+// filter returns a filter that queues the line number of each line that
+// records a value, without modifying its input.
+func (c *cursor) filter() filter {
+	return func(w io.Writer, r io.Reader) error {
+		n, section := 0, 0
+		return lineFilter(func(line string) string {
+			n++
+			t := strings.TrimSpace(line)
+			if isSectionHeader(t) {
+				section++
+			}
+			if t == "" || t[0] == ';' || t[0] == '#' || isSectionHeader(t) {
+				return line
+			}
+			c.mu.Lock()
+			c.pending = append(c.pending, mark{line: n, section: section})
+			c.mu.Unlock()
+			return line
+		})(w, r)
+	}
+}
+
+// columns returns a filter that records the column of the value of each
+// line that may record a value, without modifying its input. Columns
+// count bytes from 1. The filter must see lines as they are written,
+// before filters that rewrite assignments.
+func (c *cursor) columns() filter {
+	return func(w io.Writer, r io.Reader) error {
+		n := 0
+		return lineFilter(func(line string) string {
+			n++
+			t := strings.TrimSpace(line)
+			if t == "" || t[0] == ';' || t[0] == '#' || isSectionHeader(t) {
+				return line
+			}
+			col := len(line) - len(strings.TrimLeft(line, " \t"))
+			empty := true
+			if i := strings.IndexByte(line, '='); i >= 0 {
+				col = len(line) - len(strings.TrimLeft(line[i+1:], " \t"))
+				empty = strings.TrimSpace(line[i+1:]) == ""
+			}
+			c.mu.Lock()
+			if c.cols == nil {
+				c.cols, c.empty = map[int]int{}, map[int]bool{}
+			}
+			c.cols[n] = col + 1
+			if empty {
+				c.empty[n] = true
+			}
+			c.mu.Unlock()
+			return line
+		})(w, r)
+	}
+}
+
+// keep returns a filter that records the text of each line that is not
+// blank or a comment, the comment block immediately preceding it, and the
+// line of each section header, without modifying its input. Like filter,
+// it must see the lines the reader reads, one for one.
+func (c *cursor) keep() filter {
+	return func(w io.Writer, r io.Reader) error {
+		n, section := 0, 0
+		var block []string
+		return lineFilter(func(line string) string {
+			n++
+			t := strings.TrimSpace(line)
+			switch {
+			case t == "":
+				block = nil
+				return line
+			case t[0] == ';' || t[0] == '#':
+				block = append(block, strings.TrimSpace(strings.TrimLeft(t, ";#")))
+				return line
+			}
+			c.mu.Lock()
+			if c.text == nil {
+				c.text, c.notes, c.heads = map[int]string{}, map[int]string{}, map[int]int{}
+			}
+			if isSectionHeader(t) {
+				section++
+				c.heads[section] = n
+			}
+			c.text[n] = line
+			if block != nil {
+				c.notes[n] = strings.Join(block, "\n")
+			}
+			c.mu.Unlock()
+			block = nil
+			return line
+		})(w, r)
+	}
+}
+
+// line returns the text of line n kept by keep.
+func (c *cursor) line(n int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.text[n]
+}
+
+// note returns the comment block kept by keep that precedes line n, if
+// there is one.
+func (c *cursor) note(n int) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	note, ok := c.notes[n]
+	return note, ok
+}
+
+// header returns the line of the section header preceding the value being
+// recorded, or 0 if there is none or lines are not kept.
+func (c *cursor) header() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.heads[c.section]
+}
+
+// observed calls see with the key of each value before passing the value
+// on. Wrappers that collect data about the values recorded use it after
+// the wrappers that rename or discard keys.
+type observed struct {
+	ini.Recorder
+	see func(key string)
+}
+
+func (o observed) Add(key, value string) {
+	o.see(key)
+	o.Recorder.Add(key, value)
+}
+
+// advance moves the cursor to the line of the next value.
+func (c *cursor) advance() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) > 0 {
+		m := c.pending[0]
+		c.loc.Line, c.section, c.pending = m.line, m.section, c.pending[1:]
+		c.loc.Column = c.cols[m.line]
+	}
+}
+
+// valueless returns whether the value being recorded is for a key written
+// without a value, such as 'key' or 'key =', which the reader records
+// with its True value.
+func (c *cursor) valueless() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.empty[c.loc.Line]
+}
+
+// Location returns the location of the value being recorded.
+func (c *cursor) Location() location {
+	if c == nil {
+		return location{}
+	}
+	return c.loc
+}
+
+// located advances its cursor before passing each value on.
+type located struct {
+	ini.Recorder
+	at *cursor
+}
+
+func (l located) Add(key, value string) {
+	l.at.advance()
+	l.Recorder.Add(key, value)
+}
+
+// locatedValue is a value recorded with its location.
+type locatedValue struct {
+	Value interface{} `json:"value"`
+	location
+}
+
+// locatedAdder is implemented by recorders that can record values with
+// their location.
+type locatedAdder interface {
+	addAt(key, value string, loc location)
+}
+
+// locateValues is a wrapper that records each value with its location in
+// dest, if dest supports it.
+func locateValues(dest ini.Recorder, at *cursor) ini.Recorder {
+	return locatedValues{Recorder: dest, at: at}
+}
+
+type locatedValues struct {
+	ini.Recorder
+	at *cursor
+}
+
+func (l locatedValues) Add(key, value string) {
+	if d, ok := l.Recorder.(locatedAdder); ok {
+		d.addAt(key, value, l.at.Location())
+		return
+	}
+	l.Recorder.Add(key, value)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestLocateValues(t *testing.T) {
+	paths, done := tempFiles(t, "a = 1\n\n[s]\n  b =   x\nb=2\n; c = 3\nd = \"q\"\n")
+	defer done()
+	src := &source{
+		rd:     &ini.Reader{Separator: ".", True: "true"},
+		wrap:   []wrapper{locateValues},
+		locate: true,
+	}
+	values := newParsedValues(&valueParser{})
+	if err := src.read(values, paths[0]); err != nil {
+		t.Fatalf("read(%s) = %v", paths[0], err)
+	}
+	got := strings.Replace(outputString(t, &outputOptions{single: true}, values), paths[0], "test.ini", -1)
+	want := `{"a":{"value":1,"file":"test.ini","line":1,"column":5},` +
+		`"s.b":[{"value":"x","file":"test.ini","line":4,"column":9},{"value":2,"file":"test.ini","line":5,"column":3}],` +
+		`"s.d":{"value":"q","file":"test.ini","line":7,"column":5}}`
+	if got != want {
+		t.Errorf("-loc: got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	ini "go.spiff.io/go-ini"
+)
+
+// readMerged reads paths into dest, in order, using readFile. If jobs is
+// greater than 1, up to jobs files are read concurrently into their own
+// recorders, created by newValues, and merged into dest in the order of
+// paths, so the result is the same as reading them one after another.
+// If seen is not nil, files are always read into their own recorders and
+// seen is called with each before it is merged. If merge is not nil,
+// files are always read into their own recorders and merged into dest
+// using merge instead of mergeValues. If reading a file fails, its path
+// and the error are returned.
+func readMerged(dest ini.Recorder, newValues func() ini.Recorder, readFile func(ini.Recorder, string) error, paths []string, jobs int, seen func(string, ini.Recorder), merge func(dest, src ini.Recorder)) (string, error) {
+	if jobs <= 1 && seen == nil && merge == nil {
+		for _, path := range paths {
+			if err := readFile(dest, path); err != nil {
+				return path, err
+			}
+		}
+		return "", nil
+	}
+
+	type result struct {
+		values ini.Recorder
+		err    error
+	}
+
+	// Each slot in sem is held from the time a file is started until it
+	// is merged, so at most jobs files are held in memory at once.
+	sem := make(chan struct{}, jobs)
+	results := make([]chan result, len(paths))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i, path := range paths {
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func(i int, path string) {
+				values := newValues()
+				err := readFile(values, path)
+				results[i] <- result{values: values, err: err}
+			}(i, path)
+		}
+	}()
+
+	if merge == nil {
+		merge = mergeValues
+	}
+	for i, path := range paths {
+		res := <-results[i]
+		<-sem
+		if res.err != nil {
+			return path, res.err
+		}
+		if seen != nil {
+			seen(path, res.values)
+		}
+		merge(dest, res.values)
+	}
+	return "", nil
+}
+
+// mergeValues appends all values recorded in src to dest, adding keys new
+// to dest in the order src recorded them. Both must be recorders created
+// by the same constructor.
+func mergeValues(dest, src ini.Recorder) {
+	switch d := dest.(type) {
+	case parsedValues:
+		s := src.(parsedValues)
+		for _, k := range *s.order {
+			d.add(k, s.Values[k]...)
+		}
+		for k := range s.arrays {
+			d.arrays[k] = true
+		}
+	}
+}
+
+// replaceValues returns a merge function that replaces values in dest
+// with those in src. For each key in src, all keys in dest in the same
+// group, named by group, are removed before the values in src are added.
+// Keys whose values are replaced stay in their place in key order.
+func replaceValues(group func(key string) string) func(dest, src ini.Recorder) {
+	return func(dest, src ini.Recorder) {
+		d, s := dest.(parsedValues), src.(parsedValues)
+		replaced := map[string]bool{}
+		for _, k := range *s.order {
+			replaced[group(k)] = true
+		}
+		order := (*d.order)[:0]
+		for _, k := range *d.order {
+			if !replaced[group(k)] {
+				order = append(order, k)
+			} else if _, ok := s.Values[k]; ok {
+				d.Values[k] = nil
+				order = append(order, k)
+			} else {
+				delete(d.Values, k)
+			}
+		}
+		*d.order = order
+		for _, k := range *s.order {
+			d.add(k, s.Values[k]...)
+		}
+		for k := range s.arrays {
+			d.arrays[k] = true
+		}
+	}
+}
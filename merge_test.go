@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestReadMergedJobs(t *testing.T) {
+	texts := make([]string, 12)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("shared = %d\n[file%d]\nkey = %d\n[common]\nlist = a%d\nlist = b%d\n", i, i%3, i, i, i)
+	}
+	paths, done := tempFiles(t, texts...)
+	defer done()
+	src := &source{rd: &ini.Reader{Separator: ".", True: "true"}}
+	newValues := func() ini.Recorder { return newParsedValues(&valueParser{}) }
+
+	read := func(jobs int, merge func(dest, src ini.Recorder)) string {
+		values := newParsedValues(&valueParser{})
+		if path, err := readMerged(values, newValues, src.read, paths, jobs, nil, merge); err != nil {
+			t.Fatalf("readMerged: %s: %v", path, err)
+		}
+		p, err := marshalJSON(orderedDocument(values), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(p)
+	}
+
+	for _, merge := range []struct {
+		name string
+		fn   func(dest, src ini.Recorder)
+	}{{"append", nil}, {"override", replaceValues(identity)}} {
+		serial := read(1, merge.fn)
+		for _, jobs := range []int{2, 4, 16} {
+			if got := read(jobs, merge.fn); got != serial {
+				t.Errorf("%s with %d jobs = %s, want %s", merge.name, jobs, got, serial)
+			}
+		}
+	}
+}
+
+func TestMergeStrategies(t *testing.T) {
+	paths, done := tempFiles(t,
+		"name = a\nlist = 1\nlist = 2\n[db]\nhost = x\nport = 1\n[web]\nport = 2\n",
+		"list = 3\n[db]\nhost = y\n[log]\nlevel = debug\n",
+	)
+	defer done()
+	src := &source{rd: &ini.Reader{Separator: ".", True: "true"}}
+	parser := &valueParser{}
+	newValues := func() ini.Recorder { return newParsedValues(parser) }
+	topLevel := func(key string) string {
+		return strings.SplitN(key, ".", 2)[0]
+	}
+
+	tests := []struct {
+		name   string
+		merge  func(dest, src ini.Recorder)
+		nested bool
+		want   string
+	}{
+		{"append", nil, false, `{"name":"a","list":[1,2,3],"db.host":["x","y"],"db.port":1,"web.port":2,"log.level":"debug"}`},
+		{"override", replaceValues(identity), false, `{"name":"a","list":3,"db.host":"y","db.port":1,"web.port":2,"log.level":"debug"}`},
+		{"override", replaceValues(topLevel), true, `{"name":"a","list":3,"db":{"host":"y"},"web":{"port":2},"log":{"level":"debug"}}`},
+		{"deep", replaceValues(identity), true, `{"name":"a","list":3,"db":{"host":"y","port":1},"web":{"port":2},"log":{"level":"debug"}}`},
+	}
+	for _, tt := range tests {
+		values := newParsedValues(parser)
+		if path, err := readMerged(values, newValues, src.read, paths, 1, nil, tt.merge); err != nil {
+			t.Fatalf("readMerged: %s: %v", path, err)
+		}
+		out := &outputOptions{single: true, nested: tt.nested, sep: "."}
+		if got := outputString(t, out, values); got != tt.want {
+			t.Errorf("-merge-strategy %s (nested=%t) = %s, want %s", tt.name, tt.nested, got, tt.want)
+		}
+	}
+}
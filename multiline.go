@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// multilineValues returns a filter that joins values written over more
+// than one line into a single assignment of a quoted value with newlines,
+// in the style named by mode:
+//
+//	quotes  Values starting with """ continue until the next """. A
+//	        newline right after the opening quotes or right before the
+//	        closing quotes is not part of the value.
+//	indent  Lines indented more than an assignment, up to the next blank
+//	        or less indented line, continue its value, without the
+//	        indentation of the first of them. Comment lines among them
+//	        are dropped.
+//
+// Joined lines are replaced with blank lines, so the lines of values are
+// kept. The text of joined values is kept as written, without decoding
+// quotes or escapes.
+func multilineValues(mode string) filter {
+	return func(w io.Writer, r io.Reader) error {
+		sc := lineScanner(r)
+		n := 0 // Number of the line last read.
+		read := func() (string, bool) {
+			if !sc.Scan() {
+				return "", false
+			}
+			n++
+			return sc.Text(), true
+		}
+
+		line, ok := read()
+		for ok {
+			start, joined := n, 0
+			next, more := "", false
+			key, value, assigns := splitAssignment(line)
+			switch {
+			case assigns && mode == "quotes" && strings.HasPrefix(value, `"""`):
+				text, j, err := tripleQuoted(value[3:], read)
+				if err != nil {
+					return fmt.Errorf("line %d: %v", start, err)
+				}
+				line, joined = key+"= "+quoteValue(text), j
+				next, more = read()
+			case assigns && mode == "indent":
+				indent := len(line) - len(strings.TrimLeft(line, " \t"))
+				var lines []string
+				if value != "" {
+					lines = append(lines, value)
+				}
+				prefix := ""
+				for next, more = read(); more && strings.TrimSpace(next) != ""; next, more = read() {
+					t := strings.TrimLeft(next, " \t")
+					if len(next)-len(t) <= indent {
+						break
+					}
+					joined++
+					if t[0] == ';' || t[0] == '#' {
+						continue
+					}
+					if prefix == "" {
+						prefix = next[:len(next)-len(t)]
+					}
+					if strings.HasPrefix(next, prefix) {
+						t = next[len(prefix):]
+					}
+					lines = append(lines, t)
+				}
+				if joined > 0 {
+					line = key + "= " + quoteValue(strings.Join(lines, "\n"))
+				}
+			default:
+				next, more = read()
+			}
+			if _, err := io.WriteString(w, line+"\n"+strings.Repeat("\n", joined)); err != nil {
+				return err
+			}
+			line, ok = next, more
+		}
+		return sc.Err()
+	}
+}
+
+// splitAssignment returns the text of line before its '=' and the value
+// after it, trimmed of spaces, if line is an assignment.
+func splitAssignment(line string) (key, value string, ok bool) {
+	t := strings.TrimSpace(line)
+	if t == "" || t[0] == ';' || t[0] == '#' || isSectionHeader(t) {
+		return "", "", false
+	}
+	i := strings.IndexByte(line, '=')
+	if i < 0 {
+		return "", "", false
+	}
+	return line[:i], strings.TrimSpace(line[i+1:]), true
+}
+
+// tripleQuoted returns the text of a triple-quoted value whose text after
+// the opening quotes is rest, reading the lines after it with read until
+// the closing quotes, and the number of lines read.
+func tripleQuoted(rest string, read func() (string, bool)) (string, int, error) {
+	closing := func(line string) (string, bool, error) {
+		i := strings.Index(line, `"""`)
+		if i < 0 {
+			return line, false, nil
+		}
+		if t := strings.TrimSpace(line[i+3:]); t != "" && t[0] != ';' && t[0] != '#' {
+			return "", false, fmt.Errorf("text after closing \"\"\": %q", t)
+		}
+		return line[:i], true, nil
+	}
+
+	text, done, err := closing(rest)
+	if done || err != nil {
+		return text, 0, err
+	}
+	var lines []string
+	if strings.TrimSpace(rest) != "" {
+		lines = append(lines, rest)
+	}
+	for n := 1; ; n++ {
+		line, ok := read()
+		if !ok {
+			return "", n, fmt.Errorf("unterminated \"\"\" value")
+		}
+		text, done, err := closing(line)
+		if err != nil {
+			return "", n, err
+		}
+		if !done {
+			lines = append(lines, text)
+		} else if strings.TrimSpace(text) != "" {
+			lines = append(lines, text)
+		}
+		if done {
+			return strings.Join(lines, "\n"), n, nil
+		}
+	}
+}
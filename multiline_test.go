@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestMultilineValues(t *testing.T) {
+	long := strings.Repeat("x", 100000)
+	tests := []struct {
+		mode, in, want string
+	}{
+		{"quotes", "a = \"\"\"\nline 1\n  line 2\n\"\"\"\nb = 1", "a = \"line 1\\n  line 2\"\n\n\n\nb = 1"},
+		{"quotes", `a = """x"""`, `a = "x"`},
+		{"quotes", `a = """x""" ; note`, `a = "x"`},
+		{"quotes", "a = \"\"\"say \"hi\" \\n\nto \"\"\"", "a = \"say \\\"hi\\\" \\\\n\\nto \"\n"},
+		{"quotes", "a = \"\"\"" + long + "\n" + long + "\"\"\"", "a = \"" + long + "\\n" + long + "\"\n"},
+		{"quotes", "a = \"x\"\n  b", "a = \"x\"\n  b"},
+		{"indent", "a = first\n  second\n  # note\n    third\nb = 2", "a = \"first\\nsecond\\n  third\"\n\n\n\nb = 2"},
+		{"indent", "a =\n  x\n\nb = 1", "a = \"x\"\n\n\nb = 1"},
+		{"indent", "  a = 1\n  b = 2", "  a = 1\n  b = 2"},
+		{"indent", "[s]\n  a = 1", "[s]\n  a = 1"},
+	}
+	for _, tt := range tests {
+		if got := runFilter(t, multilineValues(tt.mode), tt.in+"\n"); got != tt.want+"\n" {
+			t.Errorf("-multiline %s: %.40q = %.40q; want %.40q", tt.mode, tt.in, got, tt.want+"\n")
+		}
+	}
+}
+
+func TestMultilineErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a = 1\nb = \"\"\"x\ny\n", `line 2: unterminated """ value`},
+		{"a = \"\"\"x\n\"\"\" y\n", `line 1: text after closing """: "y"`},
+	}
+	for _, tt := range tests {
+		err := multilineValues("quotes")(ioutil.Discard, strings.NewReader(tt.in))
+		if got := errString(err); got != tt.want {
+			t.Errorf("-multiline quotes: %q: err = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+)
+
+// leafKey is the key under which values are kept when a key is both a
+// leaf and the prefix of another key in nested output. For example,
+// "a = 1" and "a.b = 2" nest as {"a": {"_value": [1], "b": [2]}}.
+const leafKey = "_value"
+
+// nest returns the keys of flat split on sep into nested objects. Objects
+// are ordered by the first key in flat that they contain.
+func nest(flat *object, sep string) *object {
+	root := newObject()
+	for _, k := range flat.Keys() {
+		v, _ := flat.Get(k)
+		parts := strings.Split(k, sep)
+		obj := objectAt(root, parts[:len(parts)-1])
+		leaf := parts[len(parts)-1]
+		if prev, ok := obj.Get(leaf); ok {
+			if sub, isObj := prev.(*object); isObj {
+				sub.Set(leafKey, v)
+				continue
+			}
+		}
+		obj.Set(leaf, v)
+	}
+	return root
+}
+
+// objectAt returns the object at path under root, creating objects as
+// needed. Values found along the path are moved under leafKey.
+func objectAt(root *object, path []string) *object {
+	obj := root
+	for _, p := range path {
+		v, ok := obj.Get(p)
+		if sub, isObj := v.(*object); isObj {
+			obj = sub
+			continue
+		}
+		sub := newObject()
+		if ok {
+			sub.Set(leafKey, v)
+		}
+		obj.Set(p, sub)
+		obj = sub
+	}
+	return obj
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestNest(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"[db]\nhost = a\nport = 1\n", `{"db":{"host":["a"],"port":[1]}}`},
+		{"[a.b]\nc = 1\n[a]\nd = 2\n", `{"a":{"b":{"c":[1]},"d":[2]}}`},
+		{"a = 1\n[a]\nb = 2\n", `{"a":{"_value":[1],"b":[2]}}`},
+		{"a.b = 2\na = 1\n", `{"a":{"b":[2],"_value":[1]}}`},
+		{"x = 2\n[x.y]\nz = 1\n", `{"x":{"_value":[2],"y":{"z":[1]}}}`},
+	}
+	for _, tt := range tests {
+		values := readValues(t, &source{}, &valueParser{}, tt.in)
+		got := outputString(t, &outputOptions{nested: true, sep: "."}, values)
+		if got != tt.want {
+			t.Errorf("nest(%q) = %s; want %s", tt.in, got, tt.want)
+		}
+	}
+}
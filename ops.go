@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// Assignment operators read with -ops.
+const (
+	opAssign  = "="
+	opAppend  = "+="
+	opReplace = ":="
+)
+
+// operators returns a filter that records the operator of each assignment
+// written with += (append), := or =! (replace), and rewrites the
+// assignment to use '=', replacing the rest of the operator with a space
+// so that columns are kept. Lines are otherwise not modified.
+func (c *cursor) operators() filter {
+	return func(w io.Writer, r io.Reader) error {
+		n := 0
+		return lineFilter(func(line string) string {
+			n++
+			t := strings.TrimSpace(line)
+			if t == "" || t[0] == ';' || t[0] == '#' || isSectionHeader(t) {
+				return line
+			}
+			i := strings.IndexByte(line, '=')
+			op := ""
+			switch {
+			case i < 0:
+				return line
+			case strings.HasPrefix(line[i+1:], "!"):
+				op, line = opReplace, line[:i+1]+" "+line[i+2:]
+			case i > 0 && line[i-1] == '+':
+				op, line = opAppend, line[:i-1]+" "+line[i:]
+			case i > 0 && line[i-1] == ':':
+				op, line = opReplace, line[:i-1]+" "+line[i:]
+			default:
+				return line
+			}
+			c.mu.Lock()
+			if c.ops == nil {
+				c.ops = map[int]string{}
+			}
+			c.ops[n] = op
+			c.mu.Unlock()
+			return line
+		})(w, r)
+	}
+}
+
+// operator returns the operator of the assignment of the value being
+// recorded.
+func (c *cursor) operator() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if op, ok := c.ops[c.loc.Line]; ok {
+		return op
+	}
+	return opAssign
+}
+
+// applyOperators is a wrapper for inputs read with -ops, where values
+// assigned with := replace the values assigned to the key before them in
+// the same input. Since values are passed on as recorded, they are held
+// until the input has been read and then passed on in order, without
+// those replaced.
+func applyOperators(dest ini.Recorder, at *cursor) ini.Recorder {
+	return operated{&resetter{dest: dest, at: at}}
+}
+
+type operated struct {
+	*resetter
+}
+
+func (o operated) Add(key, value string) {
+	if o.at.operator() == opReplace {
+		o.clear(key)
+	}
+	o.hold(key, value)
+}
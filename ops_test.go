@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestOperators(t *testing.T) {
+	const in = "[s]\na = 1\na += 2\nb := 3\nc =! 4\n; x += y\nd = e:=f\n"
+	const want = "[s]\na = 1\na  = 2\nb  = 3\nc =  4\n; x += y\nd = e:=f\n"
+	c := &cursor{}
+	if got := runFilter(t, c.operators(), in); got != want {
+		t.Errorf("operators(%q) = %q, want %q", in, got, want)
+	}
+	ops := map[int]string{3: opAppend, 4: opReplace, 5: opReplace}
+	for line := 1; line <= 7; line++ {
+		c.loc.Line = line
+		want := ops[line]
+		if want == "" {
+			want = opAssign
+		}
+		if got := c.operator(); got != want {
+			t.Errorf("operator() at line %d = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestApplyOperators(t *testing.T) {
+	const in = "a = 1\na += 2\nb = 1\nb := 0\nb += 5\n[s]\nb = 2\na = 1\na := 3\na += 4\nc = x\nc =! y\n"
+	src := &source{ops: true, wrap: []wrapper{applyOperators}, locate: true}
+	got := readString(t, src, &valueParser{}, in)
+	if want := `{"a":[1,2],"b":[0,5],"s.b":[2],"s.a":[3,4],"s.c":["y"]}`; got != want {
+		t.Errorf("-ops: got %s, want %s", got, want)
+	}
+}
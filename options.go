@@ -0,0 +1,472 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// options holds the values of the command-line flags.
+type options struct {
+	raw        bool
+	casing     string
+	keyStyles  string
+	merge      bool
+	spilling   bool
+	recursive  bool
+	strategy   string
+	reversed   bool
+	jobs       int
+	explain    bool
+	failDup    bool
+	compact    bool
+	indent     string
+	ascii      bool
+	jopts      jsonOptions
+	canonical  bool
+	lines      bool
+	format     string
+	gopts      goOptions
+	tmplFile   string
+	outPath    string
+	outDir     string
+	watching   bool
+	interval   time.Duration
+	timeout    time.Duration
+	headers    stringList
+	caFile     string
+	insecure   bool
+	bareKey    string
+	expand     bool
+	interp     bool
+	defSection string
+	inherit    bool
+	sectioned  bool
+	decryptCmd string
+	transCmd   string
+	incPath    string
+	keyTabs    string
+	collapse   bool
+	empty      string
+	dialect    string
+	delims     string
+	multiline  string
+	comments   string
+	allowFile  string
+	schemaFile string
+	only       globList
+	renames    []string
+	exclude    globList
+	prefix     string
+	prefixFile bool
+	describe   bool
+	rawText    bool
+	fidelity   bool
+	keyNotes   bool
+	out        outputOptions
+	roundtrip  bool
+	locValues  bool
+	checking   bool
+	reportFile string
+	warnMode   string
+	strict     bool
+	maxErrors  int
+	maxBytes   int64
+	maxKeys    int
+	maxDepth   int
+	parsers    string
+	tupleSep   string
+	numFormats string
+	boolWords  string
+	noBig      bool
+	units      string
+	dates      string
+	layouts    stringList
+	arrayKeys  bool
+	listSep    string
+	typedKeys  bool
+	overrides  []typeOverride
+	streamMode string
+	arrayMode  string
+	getPath    string
+	flatStyle  string
+	diffing    bool
+	rd         *ini.Reader
+	src        *source
+}
+
+// newOptions returns the options set by default.
+func newOptions() *options {
+	rd := &ini.Reader{True: "true"}
+	return &options{
+		casing:    "-",
+		strategy:  "append",
+		jobs:      1,
+		jopts:     jsonOptions{indent: "  ", escapeHTML: true},
+		format:    "json",
+		gopts:     goOptions{typeName: "Config"},
+		interval:  500 * time.Millisecond,
+		timeout:   30 * time.Second,
+		keyTabs:   "preserve",
+		empty:     "true",
+		dialect:   "default",
+		maxErrors: 10,
+		tupleSep:  ":",
+		rd:        rd,
+		src:       &source{rd: rd},
+	}
+}
+
+// register defines the flags setting o in fs.
+func (o *options) register(fs *flag.FlagSet) {
+	both := flagNames{fs}
+	// Reader flags
+	both.StringVar(&o.rd.Separator, "s", "separator", ".", "prefix separator")
+	both.StringVar(&o.casing, "C", "case", o.casing, "case transformation (l to lowercase keys, u to uppercase, - to do nothing)")
+	both.StringVar(&o.keyStyles, "K", "key-styles", "", "comma-separated `LIST` of key styles (trim, snake, camel, or kebab)")
+	both.StringVar(&o.rd.True, "t", "true", o.rd.True, "true value")
+	fs.StringVar(&o.keyTabs, "key-tabs", o.keyTabs, "tab handling in keys (preserve or space)")
+	fs.BoolVar(&o.collapse, "collapse-spaces", false, "collapse runs of whitespace in values")
+	fs.StringVar(&o.empty, "empty", o.empty, "how empty values are recorded (true, null, empty-string, or omit)")
+	fs.StringVar(&o.delims, "delim", "", "additional assignment delimiter `CHARS`")
+	fs.StringVar(&o.multiline, "multiline", "", "read values written over more than one line in `MODE` (quotes or indent)")
+	fs.StringVar(&o.comments, "comment-chars", "", "additional `CHARS` that start comments")
+	both.StringVar(&o.dialect, "f", "dialect", o.dialect, "INI dialect of inputs (default, windows, gitconfig, systemd, properties, or auto)")
+	both.BoolVar(&o.src.include, "I", "include", false, "follow include directives")
+	fs.StringVar(&o.incPath, "include-path", "", "`DIRS` to search for included files")
+	both.BoolVar(&o.expand, "E", "expand", false, "expand environment variables in values")
+	fs.StringVar(&o.defSection, "default-section", "", "record keys outside of sections in the section `NAME`")
+	fs.BoolVar(&o.inherit, "inherit", false, "inherit values of the default section in every section")
+	fs.BoolVar(&o.sectioned, "require-sections", false, "fail on keys outside of sections")
+	both.BoolVar(&o.interp, "x", "interpolate", false, "replace %(key)s and ${key} references in values")
+	fs.StringVar(&o.decryptCmd, "decrypt", "", "decrypt ENC[...] values with the shell command `CMD`")
+	fs.StringVar(&o.transCmd, "transform", "", "rewrite or drop values with the shell command `CMD`")
+	fs.StringVar(&o.bareKey, "bare-lines-as", "", "record key-less lines as values of `KEY`")
+	// Program flags
+	fs.BoolVar(&o.reversed, "reverse", false, "convert JSON to INI")
+	both.BoolVar(&o.recursive, "R", "recursive", false, "convert the files in directories, recursively")
+	both.BoolVar(&o.merge, "m", "merge", false, "merge files")
+	fs.StringVar(&o.strategy, "merge-strategy", o.strategy, "how merged files combine (append, override, or deep)")
+	fs.BoolVar(&o.spilling, "spill", false, "hold merged values in a temporary file instead of in memory")
+	fs.BoolVar(&o.out.single, "single", false, "write keys with one value as scalars")
+	both.BoolVar(&o.out.nested, "n", "nested", false, "split keys into nested objects")
+	both.IntVar(&o.jobs, "j", "jobs", o.jobs, "number of files to read concurrently")
+	fs.StringVar(&o.src.profile, "profile", "", "read [SECTION@`NAME`] variants over their base sections")
+	fs.BoolVar(&o.src.ops, "ops", false, "read += (append) and := or =! (replace) assignments")
+	fs.StringVar(&o.out.dup, "dup", "append", "duplicate key `POLICY` (append, first, last, or error)")
+	fs.BoolVar(&o.explain, "explain-conflicts", false, "report keys defined by more than one merged file")
+	fs.BoolVar(&o.failDup, "fail-on-conflict", false, "fail if merged files define the same key")
+	both.StringVar(&o.format, "o", "format", o.format, "output format (json, yaml, toml, gostruct, env, or env=export)")
+	fs.StringVar(&o.gopts.typeName, "go-type", o.gopts.typeName, "`NAME` of the type written by -o gostruct")
+	fs.BoolVar(&o.gopts.defaults, "go-default", false, "also write a Default variable with -o gostruct")
+	fs.StringVar(&o.tmplFile, "template", "", "write output by executing the Go template in `FILE`")
+	both.StringVar(&o.outPath, "O", "output", "", "write output to `PATH`")
+	both.StringVar(&o.outDir, "d", "output-dir", "", "write output for each input to a file in `DIR`")
+	both.BoolVar(&o.watching, "w", "watch", false, "convert inputs again each time they change")
+	fs.DurationVar(&o.interval, "watch-interval", o.interval, "how often -w checks inputs for changes")
+	fs.DurationVar(&o.timeout, "timeout", o.timeout, "timeout for fetching URL inputs")
+	both.Var(&o.headers, "H", "header", "add the `HEADER` 'Name: value' to requests for URL inputs")
+	fs.StringVar(&o.caFile, "cacert", "", "trust the PEM certificates in `FILE` for URL inputs")
+	fs.BoolVar(&o.insecure, "insecure", false, "do not verify certificates of URL inputs")
+	both.BoolVar(&o.compact, "c", "compact", false, "compact output")
+	fs.StringVar(&o.indent, "indent", "", "indent JSON output with `INDENT`, a number of spaces or a string such as '\\t'")
+	fs.BoolVar(&o.jopts.escapeHTML, "escape-html", true, "escape '<', '>', and '&' in JSON strings")
+	fs.BoolVar(&o.ascii, "ascii", false, "escape non-ASCII characters in JSON strings")
+	fs.BoolVar(&o.canonical, "canonical", false, "write canonical JSON, identical for equal values")
+	fs.BoolVar(&o.lines, "section-lines", false, "print each top-level member on its own line")
+	both.Var(modeFlag{mode: &o.arrayMode, modes: []string{"plain", "named"}}, "A", "array", "write the outputs for all inputs as one array, naming each input with -A=named")
+	fs.Var(modeFlag{mode: &o.streamMode, modes: []string{"section", "kv"}}, "stream", "write each section, or each value with -stream=kv, as a line of JSON while reading")
+	both.BoolVar(&o.raw, "r", "raw", false, "do not parse values as integers, floats, bools, or JSON")
+	fs.BoolVar(&o.describe, "section-descriptions", false, "record comments preceding sections")
+	fs.BoolVar(&o.rawText, "raw-sidecar", false, "record the original text of values")
+	fs.BoolVar(&o.fidelity, "fidelity", false, "record the layout of inputs for -reverse to reproduce")
+	fs.BoolVar(&o.keyNotes, "comments", false, "record comments preceding keys")
+	fs.BoolVar(&o.locValues, "loc", false, "record the file, line, and column of each value")
+	fs.BoolVar(&o.roundtrip, "roundtrip-check", false, "check that output converts back to the same INI values")
+	fs.BoolVar(&o.strict, "strict", false, "reject malformed lines")
+	fs.IntVar(&o.maxErrors, "max-errors", o.maxErrors, "number of malformed lines reported by -strict (0 for all)")
+	fs.Int64Var(&o.maxBytes, "limit-bytes", 0, "maximum size of each input in bytes")
+	fs.IntVar(&o.maxKeys, "limit-keys", 0, "maximum number of keys in each input")
+	fs.IntVar(&o.maxDepth, "limit-depth", 0, "maximum nesting depth of keys (requires -n)")
+	fs.StringVar(&o.reportFile, "report", "", "write a JSON report of the conversion to `FILE`")
+	fs.Var(modeFlag{mode: &o.warnMode, modes: []string{"warn", "error"}}, "warn", "warn about values recorded as types other than strings, or fail with -warn=error")
+	fs.BoolVar(&o.checking, "check", false, "report problems in inputs instead of converting them")
+	fs.StringVar(&o.parsers, "parse", "", "comma-separated `LIST` of value parsers (int, float, bool, json, tuple)")
+	fs.BoolVar(&o.typedKeys, "typed-keys", false, "parse values by key type suffixes")
+	both.Var(typeFlag{overrides: &o.overrides}, "T", "type", "parse keys matching `KEY=TYPE` as TYPE")
+	fs.StringVar(&o.tupleSep, "tuple-sep", o.tupleSep, "tuple value `separator`")
+	fs.StringVar(&o.numFormats, "num-formats", "", "comma-separated `LIST` of additional number formats (hex, oct, bin, underscores)")
+	fs.StringVar(&o.boolWords, "bools", "", "comma-separated `LIST` of additional true and false words")
+	fs.BoolVar(&o.noBig, "no-big", false, "record numbers as 64-bit integers and floats")
+	fs.Var(modeFlag{mode: &o.units, modes: []string{"number", "object"}}, "units", "parse durations and sizes as numbers, or as objects with -units=object")
+	fs.Var(modeFlag{mode: &o.dates, modes: []string{"rfc3339", "epoch"}}, "dates", "normalize dates to RFC 3339, or to epoch seconds with -dates=epoch")
+	fs.Var(&o.layouts, "date-format", "recognize dates in the Go time `LAYOUT`")
+	fs.BoolVar(&o.arrayKeys, "array-keys", false, "always write keys assigned as key[] as arrays")
+	fs.StringVar(&o.listSep, "list-sep", "", "split values on `SEP` into arrays")
+	fs.StringVar(&o.out.rootKey, "root-key", "", "wrap output under the key `NAME`")
+	fs.BoolVar(&o.diffing, "diff", false, "write the differences between the outputs of two inputs")
+	fs.StringVar(&o.flatStyle, "flat-arrays", "", "write arrays and objects as a key for each element, in `STYLE` (index, brackets, or repeat)")
+	fs.StringVar(&o.getPath, "get", "", "write only the value at `PATH` in the output")
+	fs.BoolVar(&o.out.sorted, "sort", false, "sort keys instead of keeping source order")
+	fs.StringVar(&o.prefix, "prefix", "", "prefix keys with `NAME`")
+	fs.BoolVar(&o.prefixFile, "prefix-from-filename", false, "prefix keys with the name of their input")
+	fs.Var(&o.only, "only", "only convert sections matching `SECTION`")
+	fs.Var(&o.exclude, "exclude", "do not convert sections matching `SECTION`")
+	fs.Var(renameFlag{rules: &o.renames}, "map", "rename keys matching `OLD=NEW`")
+	fs.Var(renameFlag{rules: &o.renames, file: true}, "map-file", "read rename rules from `FILE`")
+	fs.StringVar(&o.schemaFile, "schema", "", "validate output against the JSON Schema in `FILE`")
+	fs.StringVar(&o.allowFile, "allowed-keys", "", "fail on keys not matching a glob in `FILE`")
+}
+
+// flagNames registers flags with both a short and a long name, which
+// share a value and usage.
+type flagNames struct {
+	fs *flag.FlagSet
+}
+
+// alias registers short as another name for the flag long.
+func (n flagNames) alias(short, long string) {
+	f := n.fs.Lookup(long)
+	n.fs.Var(f.Value, short, f.Usage)
+}
+
+func (n flagNames) BoolVar(p *bool, short, long string, value bool, usage string) {
+	n.fs.BoolVar(p, long, value, usage)
+	n.alias(short, long)
+}
+
+func (n flagNames) IntVar(p *int, short, long string, value int, usage string) {
+	n.fs.IntVar(p, long, value, usage)
+	n.alias(short, long)
+}
+
+func (n flagNames) StringVar(p *string, short, long string, value string, usage string) {
+	n.fs.StringVar(p, long, value, usage)
+	n.alias(short, long)
+}
+
+func (n flagNames) Var(v flag.Value, short, long string, usage string) {
+	n.fs.Var(v, long, usage)
+	n.alias(short, long)
+}
+
+// flagChoices lists the values allowed for flags that take one of a fixed
+// set, by long name.
+var flagChoices = map[string][]string{
+	"case":           {"l", "u", "-"},
+	"key-tabs":       {"preserve", "space"},
+	"empty":          {"true", "null", "empty-string", "omit"},
+	"multiline":      {"quotes", "indent"},
+	"dialect":        {"default", "windows", "gitconfig", "systemd", "properties", "auto"},
+	"merge-strategy": {"append", "override", "deep"},
+	"dup":            {"append", "first", "last", "error"},
+	"format":         {"json", "json5", "yaml", "toml", "gostruct", "env", "env=export", "msgpack", "cbor"},
+	"flat-arrays":    {"index", "brackets", "repeat"},
+}
+
+// oneOf returns whether value is one of the choices of the flag name.
+func oneOf(value, name string) bool {
+	for _, c := range flagChoices[name] {
+		if value == c {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns an error if a flag of o has an invalid value or o combines
+// flags that cannot be used together. Flags only used to convert INI to
+// JSON are not checked for combinations with -reverse.
+func (o *options) check() error {
+	switch {
+	case !oneOf(o.casing, "case"):
+		return fmt.Errorf("invalid case value %+q: must be one of l, u, or -", o.casing)
+	case !oneOf(o.keyTabs, "key-tabs"):
+		return fmt.Errorf("invalid key tab mode %+q: must be one of preserve or space", o.keyTabs)
+	case !oneOf(o.empty, "empty"):
+		return fmt.Errorf("invalid empty value mode %+q: must be one of true, null, empty-string, or omit", o.empty)
+	case o.multiline != "" && !oneOf(o.multiline, "multiline"):
+		return fmt.Errorf("invalid multiline mode %+q: must be one of quotes or indent", o.multiline)
+	case !oneOf(o.dialect, "dialect"):
+		return fmt.Errorf("invalid dialect %+q: must be one of default, windows, gitconfig, systemd, properties, or auto", o.dialect)
+	case !oneOf(o.strategy, "merge-strategy"):
+		return fmt.Errorf("invalid merge strategy %+q: must be one of append, override, or deep", o.strategy)
+	case !oneOf(o.out.dup, "dup"):
+		return fmt.Errorf("invalid duplicate key policy %+q: must be one of append, first, last, or error", o.out.dup)
+	case !oneOf(o.format, "format"):
+		return fmt.Errorf("invalid output format %+q: must be one of json, json5, yaml, toml, gostruct, env, env=export, msgpack, or cbor", o.format)
+	case o.flatStyle != "" && !oneOf(o.flatStyle, "flat-arrays"):
+		return fmt.Errorf("invalid array style %+q: must be one of index, brackets, or repeat", o.flatStyle)
+	case o.jobs < 1:
+		return fmt.Errorf("invalid job count %d: must be at least 1", o.jobs)
+	case o.maxErrors < 0:
+		return fmt.Errorf("invalid error count %d: must be at least 0", o.maxErrors)
+	case o.interval <= 0:
+		return fmt.Errorf("invalid watch interval %v: must be positive", o.interval)
+	case o.maxBytes < 0 || o.maxKeys < 0 || o.maxDepth < 0:
+		return errors.New("-limit-bytes, -limit-keys, and -limit-depth must be at least 0")
+	}
+
+	switch {
+	case len(o.layouts) > 0 && o.dates == "":
+		return errors.New("-date-format requires -dates")
+	case o.parsers != "" && o.raw:
+		return errors.New("-parse cannot be used with -r")
+	case o.empty == "null" && o.raw:
+		return errors.New("-empty null cannot be used with -r")
+	case o.outPath != "" && o.outDir != "":
+		return errors.New("-O and -d cannot be used together")
+	case o.outDir != "" && o.merge:
+		return errors.New("-d cannot be used with -m: use -O to write merged output to a file")
+	case o.watching && o.outDir != "":
+		return errors.New("-w cannot be used with -d")
+	}
+	if o.reversed {
+		return nil
+	}
+
+	jobs, stream, jsonOut := o.jobs > 1, o.streamMode != "", o.format == "json"
+	dupOrder := o.out.dup == "first" || o.out.dup == "last"
+	switch {
+	case o.defSection != "" && o.sectioned:
+		return errors.New("-default-section and -require-sections cannot be used together")
+	case o.src.ops && dupOrder:
+		return errors.New("-ops cannot be used with -dup first or last")
+	case o.prefix != "" && o.prefixFile:
+		return errors.New("-prefix and -prefix-from-filename cannot be used together")
+	case o.out.dup == "error" && jobs:
+		// Merged files share one checker, which must see them in order.
+		return errors.New("-dup error cannot be used with -j")
+	case o.out.nested && o.rd.Separator == "":
+		return errors.New("-n requires a non-empty separator")
+	case (o.explain || o.failDup) && !o.merge:
+		return errors.New("-explain-conflicts and -fail-on-conflict require -m")
+	case o.strategy == "deep" && !o.out.nested:
+		return errors.New("-merge-strategy deep requires -n")
+	case o.strategy != "append" && !o.merge:
+		return errors.New("-merge-strategy requires -m")
+	case o.rawText && jobs:
+		return errors.New("-raw-sidecar cannot be used with -j")
+	case o.describe && jobs:
+		return errors.New("-section-descriptions cannot be used with -j")
+	case o.keyNotes && jobs:
+		return errors.New("-comments cannot be used with -j")
+	case strings.ContainsAny(o.delims, o.comments) || strings.ContainsAny(o.delims, ";#["):
+		return errors.New("-delim characters cannot start comments or sections")
+	case o.multiline != "" && o.dialect != "default":
+		return errors.New("-multiline cannot be used with -dialect")
+	case o.maxDepth > 0 && !o.out.nested:
+		return errors.New("-limit-depth requires -n")
+	case o.locValues && o.roundtrip:
+		return errors.New("-loc cannot be used with -roundtrip-check")
+	case o.getPath != "" && stream:
+		return errors.New("-get cannot be used with -stream")
+	case o.checking && stream:
+		return errors.New("-check cannot be used with -stream")
+	case !o.jopts.escapeHTML && !jsonOut:
+		return errors.New("-escape-html requires JSON output")
+	case o.tmplFile != "" && (!jsonOut || o.lines):
+		return errors.New("-template cannot be used with -o or -section-lines")
+	}
+
+	if o.fidelity {
+		switch {
+		case jobs || o.merge || o.src.include:
+			return errors.New("-fidelity cannot be used with -j, -m, or -I")
+		case o.dialect != "default" || o.multiline != "" || o.src.ops || o.src.profile != "":
+			return errors.New("-fidelity cannot be used with -dialect, -multiline, -ops, or -profile")
+		case dupOrder:
+			return errors.New("-fidelity cannot be used with -dup first or last")
+		case o.locValues || stream || o.flatStyle != "" || o.getPath != "":
+			return errors.New("-fidelity cannot be used with -loc, -stream, -flat-arrays, or -get")
+		}
+	}
+
+	if o.reportFile != "" {
+		switch {
+		case jobs:
+			return errors.New("-report cannot be used with -j")
+		case o.watching || o.checking:
+			return errors.New("-report cannot be used with -w or -check")
+		}
+	}
+
+	if stream {
+		switch {
+		case o.merge || jobs:
+			return errors.New("-stream cannot be used with -m or -j")
+		case !jsonOut || o.lines:
+			return errors.New("-stream requires JSON output and cannot be used with -section-lines")
+		case o.describe || o.rawText || o.keyNotes || o.locValues:
+			return errors.New("-stream cannot be used with -section-descriptions, -raw-sidecar, -comments, or -loc")
+		}
+	}
+
+	if o.arrayMode != "" {
+		switch {
+		case o.merge:
+			return errors.New("-A cannot be used with -m")
+		case o.outDir != "":
+			return errors.New("-A cannot be used with -d")
+		case stream:
+			return errors.New("-A cannot be used with -stream")
+		}
+	}
+
+	if o.diffing && (o.merge || o.outDir != "" || o.arrayMode != "" || stream || o.checking) {
+		return errors.New("-diff cannot be used with -m, -d, -A, -stream, or -check")
+	}
+
+	if o.indent != "" {
+		switch {
+		case !jsonOut:
+			return errors.New("-indent requires JSON output")
+		case o.compact || o.lines || stream:
+			return errors.New("-indent cannot be used with -c, -section-lines, or -stream")
+		}
+	}
+
+	if o.canonical && (!jsonOut || o.lines || o.tmplFile != "" || o.indent != "" || !o.jopts.escapeHTML) {
+		return errors.New("-canonical cannot be used with -o, -section-lines, -template, -indent, or -escape-html")
+	}
+
+	if o.ascii {
+		switch {
+		case !jsonOut && o.format != "json5":
+			return errors.New("-ascii requires JSON or JSON5 output")
+		case o.canonical || o.tmplFile != "":
+			return errors.New("-ascii cannot be used with -canonical or -template")
+		}
+	}
+
+	if o.flatStyle != "" {
+		switch {
+		case o.out.nested:
+			return errors.New("-flat-arrays cannot be used with -n")
+		case o.flatStyle == "index" && o.rd.Separator == "":
+			return errors.New("-flat-arrays index requires a non-empty separator")
+		case o.flatStyle == "repeat" && (!jsonOut || o.lines || o.canonical || o.tmplFile != ""):
+			return errors.New("-flat-arrays repeat requires JSON output and cannot be used with -section-lines, -canonical, or -template")
+		}
+	}
+
+	if o.spilling {
+		switch {
+		case !o.merge:
+			return errors.New("-spill requires -m")
+		case !jsonOut || o.lines || o.canonical || o.tmplFile != "":
+			return errors.New("-spill requires JSON output and cannot be used with -section-lines, -canonical, or -template")
+		case o.out.nested || o.out.rootKey != "":
+			return errors.New("-spill cannot be used with -n or -root-key")
+		case o.describe || o.rawText || o.keyNotes || o.locValues || o.fidelity:
+			return errors.New("-spill cannot be used with -section-descriptions, -raw-sidecar, -comments, -loc, or -fidelity")
+		case o.allowFile != "" || o.roundtrip || o.schemaFile != "" || o.getPath != "" || o.flatStyle != "":
+			return errors.New("-spill cannot be used with -allowed-keys, -roundtrip-check, -schema, -get, or -flat-arrays")
+		}
+	}
+	return nil
+}
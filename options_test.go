@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+// parseOptions returns the options set by args.
+func parseOptions(t *testing.T, args ...string) *options {
+	t.Helper()
+	opts := newOptions()
+	fs := flag.NewFlagSet("ini2json", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse(%q) = %v", args, err)
+	}
+	return opts
+}
+
+func TestFlagNames(t *testing.T) {
+	for _, args := range [][]string{
+		{"-s", "/", "-n", "-j", "2", "-T", "a=int"},
+		{"-separator", "/", "-nested", "-jobs", "2", "-type", "a=int"},
+	} {
+		opts := parseOptions(t, args...)
+		if opts.rd.Separator != "/" || !opts.out.nested || opts.jobs != 2 || len(opts.overrides) != 1 {
+			t.Errorf("%q: separator = %q, nested = %v, jobs = %d, overrides = %d; want /, true, 2, 1",
+				args, opts.rd.Separator, opts.out.nested, opts.jobs, len(opts.overrides))
+		}
+	}
+
+	fs := flag.NewFlagSet("ini2json", flag.ContinueOnError)
+	newOptions().register(fs)
+	for _, names := range [][2]string{{"s", "separator"}, {"A", "array"}, {"H", "header"}, {"r", "raw"}} {
+		short, long := fs.Lookup(names[0]), fs.Lookup(names[1])
+		if short.Usage != long.Usage || short.DefValue != long.DefValue {
+			t.Errorf("-%s and -%s have different usage or defaults", names[0], names[1])
+		}
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"-m", "-spill", "-c"}, ""},
+		{[]string{"-n", "-s", ""}, "-n requires a non-empty separator"},
+		{[]string{"-O", "x", "-d", "y"}, "-O and -d cannot be used together"},
+		{[]string{"-reverse", "-O", "x", "-d", "y"}, "-O and -d cannot be used together"},
+		{[]string{"-reverse", "-stream", "-m"}, ""},
+		{[]string{"-stream", "-m"}, "-stream cannot be used with -m or -j"},
+		{[]string{"-stream", "-indent", "2"}, "-indent cannot be used with -c, -section-lines, or -stream"},
+		{[]string{"-j", "2", "-dup", "error"}, "-dup error cannot be used with -j"},
+		{[]string{"-merge-strategy", "deep", "-m"}, "-merge-strategy deep requires -n"},
+		{[]string{"-merge-strategy", "override"}, "-merge-strategy requires -m"},
+		{[]string{"-delim", ":", "-comment-chars", "/"}, ""},
+		{[]string{"-delim", ":", "-comment-chars", ":"}, "-delim characters cannot start comments or sections"},
+		{[]string{"-delim", "#"}, "-delim characters cannot start comments or sections"},
+		{[]string{"-fidelity", "-ops"}, "-fidelity cannot be used with -dialect, -multiline, -ops, or -profile"},
+		{[]string{"-spill", "-m", "-schema", "s.json"}, "-spill cannot be used with -allowed-keys, -roundtrip-check, -schema, -get, or -flat-arrays"},
+		{[]string{"-o", "yaml", "-ascii"}, "-ascii requires JSON or JSON5 output"},
+		{[]string{"-diff", "-A"}, "-diff cannot be used with -m, -d, -A, -stream, or -check"},
+		{[]string{"-C", "x"}, `invalid case value "x": must be one of l, u, or -`},
+		{[]string{"-case", "-"}, ""},
+		{[]string{"-key-tabs", "tab"}, `invalid key tab mode "tab": must be one of preserve or space`},
+		{[]string{"-empty", "nil"}, `invalid empty value mode "nil": must be one of true, null, empty-string, or omit`},
+		{[]string{"-multiline", "heredoc"}, `invalid multiline mode "heredoc": must be one of quotes or indent`},
+		{[]string{"-dialect", "win"}, `invalid dialect "win": must be one of default, windows, gitconfig, systemd, properties, or auto`},
+		{[]string{"-merge-strategy", "merge", "-m"}, `invalid merge strategy "merge": must be one of append, override, or deep`},
+		{[]string{"-dup", "keep"}, `invalid duplicate key policy "keep": must be one of append, first, last, or error`},
+		{[]string{"-o", "xml"}, `invalid output format "xml": must be one of json, json5, yaml, toml, gostruct, env, env=export, msgpack, or cbor`},
+		{[]string{"-flat-arrays", "dots"}, `invalid array style "dots": must be one of index, brackets, or repeat`},
+		{[]string{"-j", "0"}, "invalid job count 0: must be at least 1"},
+		{[]string{"-max-errors", "-1"}, "invalid error count -1: must be at least 0"},
+		{[]string{"-watch-interval", "0s"}, "invalid watch interval 0s: must be positive"},
+		{[]string{"-limit-bytes", "-1"}, "-limit-bytes, -limit-keys, and -limit-depth must be at least 0"},
+		{[]string{"-limit-keys", "-1"}, "-limit-bytes, -limit-keys, and -limit-depth must be at least 0"},
+		{[]string{"-limit-depth", "-1", "-n"}, "-limit-bytes, -limit-keys, and -limit-depth must be at least 0"},
+		{[]string{"-reverse", "-dup", "keep"}, `invalid duplicate key policy "keep": must be one of append, first, last, or error`},
+		{[]string{"-reverse", "-j", "0"}, "invalid job count 0: must be at least 1"},
+	}
+	for _, tt := range tests {
+		err := parseOptions(t, tt.args...).check()
+		if got := errString(err); got != tt.want {
+			t.Errorf("check(%q) = %q; want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// object is a JSON object that keeps its keys in the order they were
+// first set. It is distinct from map[string]interface{} so that objects
+// parsed from JSON values are never mistaken for nested sections.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: map[string]interface{}{}}
+}
+
+// Set sets the value of key, adding key after all other keys if it is not
+// already set.
+func (o *object) Set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// Get returns the value of key and whether it is set.
+func (o *object) Get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Keys returns the keys of o in order.
+func (o *object) Keys() []string {
+	return o.keys
+}
+
+// Len returns the number of keys in o.
+func (o *object) Len() int {
+	return len(o.keys)
+}
+
+// Sort sorts the keys of o and of all objects in its values.
+func (o *object) Sort() {
+	sort.Strings(o.keys)
+	for _, v := range o.values {
+		sortValue(v)
+	}
+}
+
+func sortValue(v interface{}) {
+	switch v := v.(type) {
+	case *object:
+		v.Sort()
+	case []interface{}:
+		for _, e := range v {
+			sortValue(e)
+		}
+	}
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		// Encode terminates each value with a newline, which is
+		// overwritten by the byte that follows it.
+		if err := enc.Encode(k); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(':')
+		if err := enc.Encode(o.values[k]); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalJSON returns the compact JSON encoding of v, with '<', '>', and
+// '&' in strings escaped if escapeHTML is true. Values that encode
+// themselves use it without escaping them, so that escaping is left to
+// the encoder that writes the output.
+func marshalJSON(v interface{}, escapeHTML bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(escapeHTML)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// ordered returns v as it would be decoded from its JSON encoding, with
+// objects decoded as *object in the order of their keys and numbers
+// decoded as json.Number.
+func ordered(v interface{}) (interface{}, error) {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	return decodeOrdered(dec)
+}
+
+// decodeOrdered decodes the next JSON value from dec, keeping the order
+// of object keys. It returns io.EOF only if dec has no more values.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	v, err := decodeValue(dec, tok)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+// decodeValue decodes the JSON value starting with tok from dec.
+func decodeValue(dec *json.Decoder, tok json.Token) (interface{}, error) {
+	switch tok {
+	case json.Delim('{'):
+		obj := newObject()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid object key %v", key)
+			}
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(name, v)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err := dec.Token()
+		return list, err
+	case json.Delim('}'), json.Delim(']'):
+		return nil, fmt.Errorf("unexpected %v", tok)
+	}
+	return tok, nil
+}
+
+// readOrdered decodes each JSON value in r, keeping the order of object
+// keys, and passes it to fn.
+func readOrdered(r io.Reader, fn func(v interface{}) error) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	for {
+		v, err := decodeOrdered(dec)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSourceOrder(t *testing.T) {
+	const in = "z = 1\n[b]\ny = 2\nx = 3\n[a]\nw = 4\n"
+	values := readValues(t, &source{}, &valueParser{}, in)
+	tests := []struct {
+		out  outputOptions
+		want string
+	}{
+		{outputOptions{single: true}, `{"z":1,"b.y":2,"b.x":3,"a.w":4}`},
+		{outputOptions{single: true, sorted: true}, `{"a.w":4,"b.x":3,"b.y":2,"z":1}`},
+		{outputOptions{single: true, nested: true, sep: "."}, `{"z":1,"b":{"y":2,"x":3},"a":{"w":4}}`},
+		{outputOptions{single: true, nested: true, sep: ".", sorted: true}, `{"a":{"w":4},"b":{"x":3,"y":2},"z":1}`},
+	}
+	for _, tt := range tests {
+		if got := outputString(t, &tt.out, values); got != tt.want {
+			t.Errorf("output(%+v) = %s, want %s", tt.out, got, tt.want)
+		}
+	}
+}
+
+func TestObject(t *testing.T) {
+	o := newObject()
+	o.Set("b", 1)
+	o.Set("a", "<&>")
+	o.Set("b", 2)
+	if got, want := strings.Join(o.Keys(), ","), "b,a"; got != want {
+		t.Errorf("Keys() = %s, want %s", got, want)
+	}
+	if v, ok := o.Get("b"); !ok || v != 2 {
+		t.Errorf("Get(b) = %v, %t, want 2, true", v, ok)
+	}
+	if _, ok := o.Get("c"); ok {
+		t.Errorf("Get(c) = _, true, want false")
+	}
+
+	for _, tt := range []struct {
+		escape bool
+		want   string
+	}{
+		{false, `{"b":2,"a":"<&>"}`},
+		{true, `{"b":2,"a":"\u003c\u0026\u003e"}`},
+	} {
+		p, err := marshalJSON(o, tt.escape)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(p); got != tt.want {
+			t.Errorf("marshalJSON(o, %t) = %s, want %s", tt.escape, got, tt.want)
+		}
+	}
+}
+
+func TestReadOrdered(t *testing.T) {
+	const in = `{"z":{"b":1,"a":[{"y":true,"x":null}]}} [1.50, "s"]`
+	var got []string
+	err := readOrdered(strings.NewReader(in), func(v interface{}) error {
+		p, err := marshalJSON(v, false)
+		if err != nil {
+			return err
+		}
+		got = append(got, string(p))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readOrdered(%s) = %v", in, err)
+	}
+	want := `{"z":{"b":1,"a":[{"y":true,"x":null}]}}` + " " + `[1.50,"s"]`
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("readOrdered(%s) = %s, want %s", in, s, want)
+	}
+
+	v, err := ordered(map[string]interface{}{"b": map[string]interface{}{"d": 1, "c": 2}, "a": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.(*object).Sort()
+	p, err := marshalJSON(v, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(p), `{"a":3,"b":{"c":2,"d":1}}`; got != want {
+		t.Errorf("ordered(...).Sort() = %s, want %s", got, want)
+	}
+
+	for _, bad := range []string{`{"a":`, `]`, `{"a" 1}`} {
+		if err := readOrdered(strings.NewReader(bad), func(interface{}) error { return nil }); err == nil {
+			t.Errorf("readOrdered(%s) = nil, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+// convertFiles calls convert with each of paths, with up to jobs calls
+// running concurrently, and calls emit with each path and the value
+// converted from it, in the order of paths. If convert or emit fails,
+// the error is returned once the values of the paths before it have been
+// emitted.
+func convertFiles(paths []string, jobs int, convert func(path string) (interface{}, error), emit func(path string, v interface{}) error) error {
+	type result struct {
+		v   interface{}
+		err error
+	}
+
+	// As in readMerged, each slot in sem is held from the time a file is
+	// started until its value is emitted.
+	sem := make(chan struct{}, jobs)
+	results := make([]chan result, len(paths))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i, path := range paths {
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func(i int, path string) {
+				v, err := convert(path)
+				results[i] <- result{v: v, err: err}
+			}(i, path)
+		}
+	}()
+
+	for i, path := range paths {
+		res := <-results[i]
+		<-sem
+		if res.err != nil {
+			return res.err
+		}
+		if err := emit(path, res.v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
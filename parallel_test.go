@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConvertFiles(t *testing.T) {
+	paths := make([]string, 20)
+	delays := map[string]time.Duration{}
+	for i := range paths {
+		paths[i] = fmt.Sprint(i)
+		// Later files finish first, so that out of order results
+		// would be noticed.
+		delays[paths[i]] = time.Duration(len(paths)-i) * 100 * time.Microsecond
+	}
+
+	for _, jobs := range []int{1, 3, 8} {
+		var (
+			mu            sync.Mutex
+			running, most int
+			emitted       []string
+		)
+		convert := func(path string) (interface{}, error) {
+			mu.Lock()
+			if running++; running > most {
+				most = running
+			}
+			mu.Unlock()
+			time.Sleep(delays[path])
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return "v" + path, nil
+		}
+		err := convertFiles(paths, jobs, convert, func(path string, v interface{}) error {
+			if v != "v"+path {
+				t.Errorf("emit(%s, %v), want v%s", path, v, path)
+			}
+			mu.Lock()
+			emitted = append(emitted, path)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("convertFiles with %d jobs = %v", jobs, err)
+		}
+		if got, want := strings.Join(emitted, ","), strings.Join(paths, ","); got != want {
+			t.Errorf("convertFiles with %d jobs emitted %s, want %s", jobs, got, want)
+		}
+		if most > jobs {
+			t.Errorf("convertFiles with %d jobs ran %d conversions at once", jobs, most)
+		}
+	}
+}
+
+func TestConvertFilesErrors(t *testing.T) {
+	paths := []string{"a", "b", "c", "d"}
+	fail := errors.New("fail")
+	tests := []struct {
+		convertErr, emitErr string
+		want                string
+	}{
+		{"c", "", "a,b"},
+		{"", "b", "a,b"},
+		{"a", "", ""},
+	}
+	for _, tt := range tests {
+		var emitted []string
+		// Conversions may still be running once convertFiles returns.
+		convertErr := tt.convertErr
+		convert := func(path string) (interface{}, error) {
+			if path == convertErr {
+				return nil, fail
+			}
+			return path, nil
+		}
+		err := convertFiles(paths, 2, convert, func(path string, v interface{}) error {
+			emitted = append(emitted, path)
+			if path == tt.emitErr {
+				return fail
+			}
+			return nil
+		})
+		if err != fail {
+			t.Errorf("convertFiles failing at %s%s = %v, want %v", tt.convertErr, tt.emitErr, err, fail)
+		}
+		if got := strings.Join(emitted, ","); got != tt.want {
+			t.Errorf("convertFiles failing at %s%s emitted %s, want %s", tt.convertErr, tt.emitErr, got, tt.want)
+		}
+	}
+}
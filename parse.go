@@ -0,0 +1,508 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"strconv"
+	"strings"
+
+	"go.spiff.io/ini2json/convert"
+)
+
+// parsedValues records values converted by a valueParser. Values that the
+// parser rejects are not recorded and are reported by Err.
+type parsedValues struct {
+	convert.Values
+	parser *valueParser
+	order  *[]string       // Keys in the order they were first recorded.
+	arrays map[string]bool // Keys that are always written as arrays.
+	errs   *[]string
+}
+
+func newParsedValues(p *valueParser) parsedValues {
+	return parsedValues{
+		Values: convert.Values{},
+		parser: p,
+		order:  new([]string),
+		arrays: map[string]bool{},
+		errs:   new([]string),
+	}
+}
+
+func (v parsedValues) Add(key, value string) {
+	v.record(key, value, nil)
+}
+
+// addAt records value as Add does, with each value recorded for it
+// wrapped in a locatedValue with loc.
+func (v parsedValues) addAt(key, value string, loc location) {
+	v.record(key, value, &loc)
+}
+
+func (v parsedValues) record(key, value string, loc *location) {
+	key, vals, array, err := v.parser.parseList(key, value)
+	if err != nil {
+		*v.errs = append(*v.errs, fmt.Sprintf("%s: %v", key, err))
+		return
+	}
+	if array {
+		v.arrays[key] = true
+	}
+	if loc != nil {
+		for i, val := range vals {
+			vals[i] = locatedValue{Value: val, location: *loc}
+		}
+	}
+	v.add(key, vals...)
+}
+
+// add appends vals to the values of key, noting key's order if it is new.
+func (v parsedValues) add(key string, vals ...interface{}) {
+	if _, ok := v.Values[key]; !ok {
+		*v.order = append(*v.order, key)
+	}
+	v.Values[key] = append(v.Values[key], vals...)
+}
+
+// Err returns an error listing every value rejected so far.
+func (v parsedValues) Err() error {
+	if len(*v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid values:\n  %s", strings.Join(*v.errs, "\n  "))
+}
+
+func (v parsedValues) MarshalJSON() ([]byte, error) {
+	return json.Marshal(orderedDocument(v))
+}
+
+// valueParser converts value text to JSON values using optional parsers
+// before falling back to convert.ParseValue.
+type valueParser struct {
+	raw       bool            // Whether to keep values as strings by default.
+	tuples    bool            // Whether to split tuple values.
+	tupleSep  string          // Separator of tuple elements.
+	typedKeys bool            // Whether keys may carry a :type suffix.
+	overrides []typeOverride  // Types of specific keys.
+	formats   numberFormats   // Additional integer literal formats.
+	bools     map[string]bool // Additional boolean words, in lower case.
+	scalars   map[string]bool // If set, the convert.ParseValue parsers to apply.
+	noBig     bool            // Whether to record numbers as int64 and float64.
+	units     string          // How to record durations and sizes, if at all.
+	dates     string          // How to record dates and times, if at all.
+	layouts   []string        // Layouts of dates and times.
+	arrayKeys bool            // Whether keys ending in "[]" are arrays.
+	listSep   string          // Separator to split values into lists on.
+}
+
+// numberFormats selects the integer literal formats recognized in
+// addition to decimal.
+type numberFormats struct {
+	hex, oct, bin bool // Whether 0x, 0o, and 0b prefixes are recognized.
+	underscores   bool // Whether digits may be separated by underscores.
+}
+
+// parseNames enables the comma-separated list of formats named in names.
+func (f *numberFormats) parseNames(names string) error {
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "hex":
+			f.hex = true
+		case "oct":
+			f.oct = true
+		case "bin":
+			f.bin = true
+		case "underscores":
+			f.underscores = true
+		default:
+			return fmt.Errorf("invalid number format %+q: must be one of hex, oct, bin, or underscores", name)
+		}
+	}
+	return nil
+}
+
+// parseBools adds the comma-separated list of pairs of true and false
+// words in words to the boolean words recognized by p.
+func (p *valueParser) parseBools(words string) error {
+	list := strings.Split(words, ",")
+	if len(list)%2 != 0 {
+		return fmt.Errorf("invalid boolean words %+q: must be pairs of true and false words", words)
+	}
+	if p.bools == nil {
+		p.bools = map[string]bool{}
+	}
+	for i, word := range list {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" {
+			return fmt.Errorf("invalid boolean words %+q: words must not be empty", words)
+		} else if b, ok := p.bools[word]; ok && b != (i%2 == 0) {
+			return fmt.Errorf("invalid boolean words %+q: %+q is both true and false", words, word)
+		}
+		p.bools[word] = i%2 == 0
+	}
+	return nil
+}
+
+// bool returns s parsed as a boolean by strconv.ParseBool or as one of the
+// boolean words of p, ignoring case.
+func (p *valueParser) bool(s string) (bool, bool) {
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b, true
+	}
+	b, ok := p.bools[strings.ToLower(s)]
+	return b, ok
+}
+
+// parse returns s parsed as a number in one of the formats enabled in f.
+// It returns false if s is not a number or is a plain decimal number.
+func (f numberFormats) parse(s string) (interface{}, bool) {
+	text := s
+	if f.underscores && strings.Contains(text, "_") {
+		for i := strings.IndexByte(text, '_'); i >= 0; i = strings.IndexByte(text, '_') {
+			if i == 0 || i == len(text)-1 || !isHexDigit(text[i-1]) || !isHexDigit(text[i+1]) {
+				return nil, false
+			}
+			text = text[:i] + text[i+1:]
+		}
+	}
+
+	sign, digits := "", text
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		sign, digits = digits[:1], digits[1:]
+	}
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' {
+		switch {
+		case f.hex && (digits[1] == 'x' || digits[1] == 'X'):
+			base = 16
+		case f.oct && (digits[1] == 'o' || digits[1] == 'O'):
+			base = 8
+		case f.bin && (digits[1] == 'b' || digits[1] == 'B'):
+			base = 2
+		}
+		if base != 10 {
+			digits = digits[2:]
+		}
+	}
+
+	if base == 10 {
+		if text == s {
+			return nil, false
+		}
+		switch v := convert.ParseValue(text).(type) {
+		case *big.Int, *convert.Float:
+			return v, true
+		}
+		return nil, false
+	}
+	ival, ok := new(big.Int).SetString(sign+digits, base)
+	return ival, ok && digits[0] != '+' && digits[0] != '-'
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// value returns the JSON value of s as parsed by convert.ParseValue, as a
+// date, duration, or size if those are enabled, as a number in one of the
+// enabled formats, or as a boolean word.
+func (p *valueParser) value(s string) interface{} {
+	if p.dates != "" {
+		if t, ok := parseDate(s, p.layouts, p.dates == "epoch"); ok {
+			return p.native(t)
+		}
+	}
+	if p.units != "" {
+		if u, ok := parseUnits(s); ok {
+			if p.units == "number" {
+				return p.native(u.canonical)
+			}
+			obj := newObject()
+			obj.Set("value", p.native(u.value))
+			obj.Set("unit", u.unit)
+			return obj
+		}
+	}
+	if v, ok := p.formats.parse(s); ok {
+		return p.native(v)
+	}
+	if b, ok := p.bools[strings.ToLower(s)]; ok {
+		return b
+	}
+	return p.native(p.scalar(s))
+}
+
+// scalar returns s parsed by convert.ParseValue, or, if p.scalars is set,
+// by only the parsers it names, in the same order.
+func (p *valueParser) scalar(s string) interface{} {
+	if p.scalars == nil {
+		return convert.ParseValue(s)
+	}
+	if p.scalars["int"] {
+		if ival, ok := new(big.Int).SetString(s, 10); ok {
+			return ival
+		}
+	}
+	if p.scalars["float"] {
+		if fval, _, err := big.ParseFloat(s, 10, 256, big.ToNearestEven); err == nil && !fval.IsInf() {
+			return (*convert.Float)(fval)
+		}
+	}
+	if p.scalars["bool"] {
+		if bval, err := strconv.ParseBool(s); err == nil {
+			return bval
+		}
+	}
+	if p.scalars["json"] {
+		var jsval interface{}
+		if json.Unmarshal([]byte(s), &jsval) == nil {
+			return jsval
+		}
+	}
+	return s
+}
+
+// native returns v with big numbers converted to int64 or float64 if
+// p.noBig is set. Integers that do not fit in an int64 become float64.
+func (p *valueParser) native(v interface{}) interface{} {
+	if !p.noBig {
+		return v
+	}
+	switch n := v.(type) {
+	case *big.Int:
+		if n.IsInt64() {
+			return n.Int64()
+		}
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f
+	case *convert.Float:
+		f, _ := n.Float().Float64()
+		return f
+	}
+	return v
+}
+
+// typeOverride forces the type of values of keys matching a glob.
+type typeOverride struct {
+	pattern string
+	typ     string
+}
+
+// typeFlag is a flag.Value that appends KEY=TYPE overrides to a list.
+type typeFlag struct {
+	overrides *[]typeOverride
+}
+
+func (f typeFlag) String() string {
+	if f.overrides == nil {
+		return ""
+	}
+	list := make([]string, len(*f.overrides))
+	for i, o := range *f.overrides {
+		list[i] = o.pattern + "=" + o.typ
+	}
+	return strings.Join(list, ",")
+}
+
+func (f typeFlag) Set(s string) error {
+	i := strings.LastIndexByte(s, '=')
+	if i <= 0 {
+		return fmt.Errorf("invalid type override %+q: must be KEY=TYPE", s)
+	}
+	o := typeOverride{pattern: s[:i], typ: strings.ToLower(s[i+1:])}
+	if _, err := path.Match(o.pattern, ""); err != nil {
+		return fmt.Errorf("invalid type override %+q: %v", s, err)
+	}
+	switch o.typ {
+	case "int", "float", "bool", "str", "string", "json":
+	default:
+		return fmt.Errorf("invalid type override %+q: type must be one of string, int, float, bool, or json", s)
+	}
+	*f.overrides = append(*f.overrides, o)
+	return nil
+}
+
+// parseNames enables the comma-separated list of optional parsers named
+// in names. If names includes any of the int, float, bool, and json
+// parsers that are applied by default, only the ones named are applied.
+func (p *valueParser) parseNames(names string) error {
+	for _, name := range strings.Split(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "tuple":
+			if p.tupleSep == "" {
+				return fmt.Errorf("tuple separator must not be empty")
+			}
+			p.tuples = true
+		case "int", "float", "bool", "json":
+			if p.scalars == nil {
+				p.scalars = map[string]bool{}
+			}
+			p.scalars[name] = true
+		default:
+			return fmt.Errorf("invalid parser %+q: must be one of int, float, bool, json, or tuple", name)
+		}
+	}
+	return nil
+}
+
+// parseList returns the key to record value under and the JSON values it
+// records, which are more than one if value is a list. It also returns
+// whether the key is always an array, which is the case for lists and,
+// if array keys are enabled, keys ending in "[]".
+func (p *valueParser) parseList(key, value string) (string, []interface{}, bool, error) {
+	array := false
+	if p.arrayKeys && strings.HasSuffix(key, "[]") {
+		key, array = strings.TrimSuffix(key, "[]"), true
+	}
+	elems := []string{value}
+	if p.listSep != "" && strings.Contains(value, p.listSep) {
+		elems, array = strings.Split(value, p.listSep), true
+	}
+
+	vals := make([]interface{}, len(elems))
+	name := key
+	for i, e := range elems {
+		if len(elems) > 1 {
+			e = strings.TrimSpace(e)
+		}
+		var err error
+		if name, vals[i], err = p.parse(key, e); err != nil {
+			return name, nil, false, err
+		}
+	}
+	return name, vals, array, nil
+}
+
+// parse returns the key to record value under and its JSON value.
+// Type overrides take precedence over key type suffixes, which take
+// precedence over other parsers.
+func (p *valueParser) parse(key, value string) (string, interface{}, error) {
+	key, typ := p.typeOf(key)
+	if typ != "" {
+		jsval, err := p.parseAs(typ, value)
+		return key, p.native(jsval), err
+	}
+	if p.raw {
+		return key, value, nil
+	}
+	if p.tuples {
+		if elems, ok := splitTuple(value, p.tupleSep); ok {
+			tuple := make([]interface{}, len(elems))
+			for i, e := range elems {
+				tuple[i] = p.value(e)
+			}
+			return key, tuple, nil
+		}
+	}
+	return key, p.value(value), nil
+}
+
+// typeOf returns the key to record values of key under and the type that
+// a type override or key type suffix gives them, or "" if neither does.
+func (p *valueParser) typeOf(key string) (string, string) {
+	typ := ""
+	if p.typedKeys {
+		if k, t, ok := splitTypeSuffix(key); ok {
+			key, typ = k, t
+		}
+	}
+	for _, o := range p.overrides {
+		if ok, _ := path.Match(o.pattern, key); ok {
+			return key, o.typ
+		}
+	}
+	return key, typ
+}
+
+// splitTypeSuffix splits a key such as "port:int" into its name and type.
+// It returns false if the key does not end in a known type suffix.
+func splitTypeSuffix(key string) (name, typ string, ok bool) {
+	i := strings.LastIndexByte(key, ':')
+	if i <= 0 {
+		return key, "", false
+	}
+	typ = strings.ToLower(key[i+1:])
+	switch typ {
+	case "int", "float", "bool", "str", "string", "json":
+		return key[:i], typ, true
+	}
+	return key, "", false
+}
+
+// parseAs parses value as the named type: int, float, bool, str (or
+// string), or json.
+func (p *valueParser) parseAs(typ, value string) (interface{}, error) {
+	switch typ {
+	case "int":
+		if ival, ok := new(big.Int).SetString(value, 10); ok {
+			return ival, nil
+		}
+		if ival, ok := p.formats.parse(value); ok {
+			if _, isInt := ival.(*big.Int); isInt {
+				return ival, nil
+			}
+		}
+	case "float":
+		if n, ok := p.formats.parse(value); ok {
+			if ival, isInt := n.(*big.Int); isInt {
+				return (*convert.Float)(new(big.Float).SetInt(ival)), nil
+			}
+			return n, nil
+		}
+		if fval, _, err := big.ParseFloat(value, 10, 256, big.ToNearestEven); err == nil && !fval.IsInf() {
+			return (*convert.Float)(fval), nil
+		}
+	case "bool":
+		if bval, ok := p.bool(value); ok {
+			return bval, nil
+		}
+	case "str", "string":
+		return value, nil
+	case "json":
+		var jsval interface{}
+		if err := json.Unmarshal([]byte(value), &jsval); err == nil {
+			return jsval, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %+q", typ)
+	}
+	return nil, fmt.Errorf("%+q is not a valid %s", value, typ)
+}
+
+// splitTuple splits value on sep if it has the shape of a tuple: two or
+// more non-empty elements made of letters, digits, and the characters
+// '_', '-', '+', and '.'. Values that look like clock times (three or
+// more numeric elements, as in 15:04:05) are not split, and neither are
+// URLs and paths since '/' and '\' are not allowed in elements.
+func splitTuple(value, sep string) ([]string, bool) {
+	if !strings.Contains(value, sep) {
+		return nil, false
+	}
+	elems := strings.Split(value, sep)
+	numeric := 0
+	for _, e := range elems {
+		if e == "" {
+			return nil, false
+		}
+		digits := true
+		for _, r := range e {
+			switch {
+			case r >= '0' && r <= '9':
+			case r == '.':
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == '-', r == '+':
+				digits = false
+			default:
+				return nil, false
+			}
+		}
+		if digits {
+			numeric++
+		}
+	}
+	if len(elems) > 2 && numeric == len(elems) {
+		return nil, false
+	}
+	return elems, true
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitTuple(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"8080:80", []string{"8080", "80"}},
+		{"a:b:c", []string{"a", "b", "c"}},
+		{"1.5:-x", []string{"1.5", "-x"}},
+		{"host:8080", []string{"host", "8080"}},
+		{"10:20", []string{"10", "20"}},
+		{"9:05", []string{"9", "05"}},
+		{"12:5", []string{"12", "5"}},
+		{"10:20:30", nil},
+		{"http://x", nil},
+		{"c:\\dir", nil},
+		{"a::b", nil},
+		{":a", nil},
+		{"plain", nil},
+	}
+	for _, tt := range tests {
+		got, ok := splitTuple(tt.in, ":")
+		if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitTuple(%q) = %q, %v; want %q", tt.in, got, ok, tt.want)
+		}
+	}
+}
+
+func TestTypedKeys(t *testing.T) {
+	const in = "port:int = 8080\nratio:float = 2\nflag:BOOL = true\nid:str = 007\nid:string = 008\nobj:json = {\"a\": [1]}\nurl:http = x\nplain = 1\n"
+	parser := &valueParser{typedKeys: true}
+	got := readString(t, &source{}, parser, in)
+	want := `{"port":[8080],"ratio":[2.0],"flag":[true],"id":["007","008"],"obj":[{"a":[1]}],"url:http":["x"],"plain":[1]}`
+	if got != want {
+		t.Errorf("-typed-keys: got %s\nwant %s", got, want)
+	}
+
+	for _, in := range []string{"n:int = 1.5\n", "f:float = x\n", "b:bool = maybe\n", "j:json = {\n"} {
+		values := newParsedValues(parser)
+		k, v := splitKeyValue(in)
+		values.Add(k, v)
+		if values.Err() == nil {
+			t.Errorf("-typed-keys: %q recorded without an error", in)
+		}
+	}
+}
+
+func TestTypeOverrides(t *testing.T) {
+	var overrides []typeOverride
+	f := typeFlag{overrides: &overrides}
+	for _, s := range []string{"*.port=string", "db.id=INT", "db.typed=float"} {
+		if err := f.Set(s); err != nil {
+			t.Fatalf("Set(%q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{"port", "=int", "port=date", "[=int"} {
+		if err := f.Set(s); err == nil {
+			t.Errorf("Set(%q) = nil, want an error", s)
+		}
+	}
+	if got, want := f.String(), "*.port=string,db.id=int,db.typed=float"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	const in = "[db]\nport = 5432\nid = 42\nname = 1\ntyped:int = 3\n"
+	parser := &valueParser{typedKeys: true, overrides: overrides}
+	got := readString(t, &source{}, parser, in)
+	// Overrides take precedence over type suffixes.
+	want := `{"db.port":["5432"],"db.id":[42],"db.name":[1],"db.typed":[3.0]}`
+	if got != want {
+		t.Errorf("-T: got %s\nwant %s", got, want)
+	}
+}
+
+// splitKeyValue splits the assignment line s at its '='.
+func splitKeyValue(s string) (string, string) {
+	i := strings.IndexByte(s, '=')
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+}
+
+func TestNumberFormats(t *testing.T) {
+	const in = "hex = 0xFF\nneg = -0x10\noct = 0o17\nbin = 0b101\n" +
+		"sep = 1_000_000\nhexsep = 0xff_ff\nfloat = 1_000.5\n" +
+		"lead = _1\ntrail = 1_\ndouble = 1__0\nempty = 0x\nsigned = 0x-1\n" +
+		"big = 123456789012345678901234567890\nexact = 0.1000000000000000000001\n"
+	tests := []struct {
+		formats string
+		noBig   bool
+		want    string
+	}{
+		{"", false, `{"hex":["0xFF"],"neg":["-0x10"],"oct":["0o17"],"bin":["0b101"],` +
+			`"sep":["1_000_000"],"hexsep":["0xff_ff"],"float":["1_000.5"],` +
+			`"lead":["_1"],"trail":["1_"],"double":["1__0"],"empty":["0x"],"signed":["0x-1"],` +
+			`"big":[123456789012345678901234567890],"exact":[0.1000000000000000000001]}`},
+		{"hex, oct,bin,underscores", false, `{"hex":[255],"neg":[-16],"oct":[15],"bin":[5],` +
+			`"sep":[1000000],"hexsep":[65535],"float":[1000.5],` +
+			`"lead":["_1"],"trail":["1_"],"double":["1__0"],"empty":["0x"],"signed":["0x-1"],` +
+			`"big":[123456789012345678901234567890],"exact":[0.1000000000000000000001]}`},
+		{"hex", true, `{"hex":[255],"neg":[-16],"oct":["0o17"],"bin":["0b101"],` +
+			`"sep":["1_000_000"],"hexsep":["0xff_ff"],"float":["1_000.5"],` +
+			`"lead":["_1"],"trail":["1_"],"double":["1__0"],"empty":["0x"],"signed":["0x-1"],` +
+			`"big":[1.2345678901234568e+29],"exact":[0.1]}`},
+	}
+	for _, tt := range tests {
+		parser := &valueParser{noBig: tt.noBig}
+		if tt.formats != "" {
+			if err := parser.formats.parseNames(tt.formats); err != nil {
+				t.Fatalf("parseNames(%q) = %v", tt.formats, err)
+			}
+		}
+		if got := readString(t, &source{}, parser, in); got != tt.want {
+			t.Errorf("-num-formats %q -no-big=%t:\ngot  %s\nwant %s", tt.formats, tt.noBig, got, tt.want)
+		}
+	}
+
+	var f numberFormats
+	if err := f.parseNames("hex,dec"); err == nil {
+		t.Errorf("parseNames(hex,dec) = nil, want an error")
+	}
+
+	// Typed keys accept the enabled formats.
+	parser := &valueParser{typedKeys: true}
+	parser.formats.hex = true
+	if got, want := readString(t, &source{}, parser, "n:int = 0x10\nf:float = 0x10\n"), `{"n":[16],"f":[16.0]}`; got != want {
+		t.Errorf("-typed-keys with hex: got %s, want %s", got, want)
+	}
+}
+
+func TestArrayKeys(t *testing.T) {
+	const in = "hosts[] = a\none = 1\nlist = x, 2 ,true\ntags[] = p;q\n"
+	tests := []struct {
+		arrayKeys bool
+		listSep   string
+		want      string
+	}{
+		{false, "", `{"hosts[]":"a","one":1,"list":"x, 2 ,true","tags[]":"p;q"}`},
+		{true, "", `{"hosts":["a"],"one":1,"list":"x, 2 ,true","tags":["p;q"]}`},
+		{false, ",", `{"hosts[]":"a","one":1,"list":["x",2,true],"tags[]":"p;q"}`},
+		{true, ";", `{"hosts":["a"],"one":1,"list":"x, 2 ,true","tags":["p","q"]}`},
+	}
+	for _, tt := range tests {
+		parser := &valueParser{arrayKeys: tt.arrayKeys, listSep: tt.listSep}
+		values := readValues(t, &source{}, parser, in)
+		if got := outputString(t, &outputOptions{single: true}, values); got != tt.want {
+			t.Errorf("-array-keys=%t -list-sep %q: got %s, want %s", tt.arrayKeys, tt.listSep, got, tt.want)
+		}
+	}
+
+	// Keys are arrays if any merged file made them so.
+	parser := &valueParser{arrayKeys: true}
+	dest := readValues(t, &source{}, parser, "a = 1\n")
+	mergeValues(dest, readValues(t, &source{}, parser, "a[] = 2\nb = 3\n"))
+	mergeValues(dest, readValues(t, &source{}, parser, "c[] = 4\n"))
+	if got, want := outputString(t, &outputOptions{single: true}, dest), `{"a":[1,2],"b":3,"c":[4]}`; got != want {
+		t.Errorf("merged -array-keys: got %s, want %s", got, want)
+	}
+}
+
+func TestBoolWords(t *testing.T) {
+	parser := &valueParser{}
+	if err := parser.parseBools("yes,no, On ,OFF"); err != nil {
+		t.Fatalf("parseBools = %v", err)
+	}
+	const in = "a = yes\nb = NO\nc = on\nd = Off\ne = true\nf = y\nl = 1\n"
+	want := `{"a":[true],"b":[false],"c":[true],"d":[false],"e":[true],"f":["y"],"l":[1]}`
+	if got := readString(t, &source{}, parser, in); got != want {
+		t.Errorf("-bools: got %s, want %s", got, want)
+	}
+
+	parser.typedKeys = true
+	if got, want := readString(t, &source{}, parser, "b:bool = Yes\n"), `{"b":[true]}`; got != want {
+		t.Errorf("-bools with -typed-keys: got %s, want %s", got, want)
+	}
+
+	for _, words := range []string{"yes", "yes,,on,off", "yes,no,no,yes"} {
+		if err := (&valueParser{}).parseBools(words); err == nil {
+			t.Errorf("parseBools(%q) = nil, want an error", words)
+		}
+	}
+}
+
+func TestParserNames(t *testing.T) {
+	const in = "i = 1\nf = 1.5\nb = true\nj = [1, \"x\"]\nt = 8080:80\ns = text\n"
+	tests := []struct {
+		names, want string
+	}{
+		{"tuple", `{"i":[1],"f":[1.5],"b":[true],"j":[[1,"x"]],"t":[[8080,80]],"s":["text"]}`},
+		{"int", `{"i":[1],"f":["1.5"],"b":["true"],"j":["[1, \"x\"]"],"t":["8080:80"],"s":["text"]}`},
+		{"float, bool", `{"i":[1.0],"f":[1.5],"b":[true],"j":["[1, \"x\"]"],"t":["8080:80"],"s":["text"]}`},
+		{"json", `{"i":[1],"f":[1.5],"b":[true],"j":[[1,"x"]],"t":["8080:80"],"s":["text"]}`},
+		{"int,tuple", `{"i":[1],"f":["1.5"],"b":["true"],"j":["[1, \"x\"]"],"t":[[8080,80]],"s":["text"]}`},
+	}
+	for _, tt := range tests {
+		parser := &valueParser{tupleSep: ":"}
+		if err := parser.parseNames(tt.names); err != nil {
+			t.Fatalf("parseNames(%q) = %v", tt.names, err)
+		}
+		if got := readString(t, &source{}, parser, in); got != tt.want {
+			t.Errorf("-parse %s:\ngot  %s\nwant %s", tt.names, got, tt.want)
+		}
+	}
+
+	if err := (&valueParser{tupleSep: ":"}).parseNames("int,date"); err == nil {
+		t.Errorf("parseNames(int,date) = nil, want an error")
+	}
+	if err := (&valueParser{}).parseNames("tuple"); err == nil {
+		t.Errorf("parseNames(tuple) with an empty separator = nil, want an error")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// profiles returns a filter for inputs with profile variants of sections,
+// named SECTION@PROFILE, that rewrites the headers of variants for
+// profile as headers of their base section and records the lines of
+// their values. Variants for other profiles are replaced with blank
+// lines, so the lines of values are kept. Profile names are compared
+// after applying casing.
+func (c *cursor) profiles(profile string, casing ini.Casing) filter {
+	profile = applyCasing(casing, profile)
+	return func(w io.Writer, r io.Reader) error {
+		n, variant, skip := 0, false, false
+		return lineFilter(func(line string) string {
+			n++
+			t := strings.TrimSpace(line)
+			switch {
+			case isSectionHeader(t):
+				name := sectionName(t)
+				i := strings.LastIndexByte(name, '@')
+				variant, skip = i > 0, false
+				if !variant {
+					return line
+				} else if applyCasing(casing, name[i+1:]) != profile {
+					skip = true
+					return ""
+				}
+				return "[" + name[:i] + "]"
+			case skip:
+				return ""
+			case !variant || t == "" || t[0] == ';' || t[0] == '#':
+				return line
+			}
+			c.mu.Lock()
+			if c.profile == nil {
+				c.profile = map[int]bool{}
+			}
+			c.profile[n] = true
+			c.mu.Unlock()
+			return line
+		})(w, r)
+	}
+}
+
+// variant returns whether the value being recorded is assigned in a
+// profile variant of its section.
+func (c *cursor) variant() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.profile[c.loc.Line]
+}
+
+// overrideProfile is a wrapper for inputs read with -profile, where the
+// values of a key assigned in a profile variant of a section replace the
+// values assigned to it in the base section. Since a variant may follow
+// its base section, values are held until the input has been read and
+// then passed on in order, with the values of each key that is replaced
+// passed on where the key was first assigned.
+func overrideProfile(dest ini.Recorder, at *cursor) ini.Recorder {
+	return &overrider{dest: dest, at: at}
+}
+
+type overrider struct {
+	dest ini.Recorder
+	at   *cursor
+	held []heldValue
+	// Keys assigned in profile variants, and the indices in held of their
+	// values.
+	variants map[string][]int
+	done     bool
+}
+
+func (o *overrider) Add(key, value string) {
+	if o.at.variant() {
+		if o.variants == nil {
+			o.variants = map[string][]int{}
+		}
+		o.variants[key] = append(o.variants[key], len(o.held))
+	}
+	o.held = append(o.held, heldValue{
+		key:     key,
+		value:   value,
+		loc:     o.at.loc,
+		section: o.at.section,
+	})
+}
+
+// Err passes each held value on to dest. It must be called once the input
+// has been read.
+func (o *overrider) Err() error {
+	if o.done {
+		return nil
+	}
+	o.done = true
+	passed := map[string]bool{}
+	for _, h := range o.held {
+		indices, ok := o.variants[h.key]
+		if !ok {
+			o.pass(h)
+			continue
+		} else if passed[h.key] {
+			continue
+		}
+		passed[h.key] = true
+		for _, i := range indices {
+			o.pass(o.held[i])
+		}
+	}
+	return nil
+}
+
+func (o *overrider) pass(h heldValue) {
+	o.at.loc, o.at.section = h.loc, h.section
+	o.dest.Add(h.key, h.value)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestProfiles(t *testing.T) {
+	const in = "[db@prod]\nhost = prod.db\n\n[db]\nhost = localhost\nport = 5432\nlist = a\n" +
+		"[db@dev]\nhost = dev.db\n[cache@PROD]\n; only in prod\nsize = 10\nlist = x\n[db@prod]\nlist = b\nlist = c\n"
+	tests := []struct {
+		profile string
+		casing  ini.Casing
+		want    string
+	}{
+		{"prod", ini.CaseSensitive, `{"db.host":["prod.db"],"db.port":[5432],"db.list":["b","c"]}`},
+		{"prod", ini.LowerCase, `{"db.host":["prod.db"],"db.port":[5432],"db.list":["b","c"],"cache.size":[10],"cache.list":["x"]}`},
+		{"dev", ini.CaseSensitive, `{"db.host":["dev.db"],"db.port":[5432],"db.list":["a"]}`},
+		{"test", ini.CaseSensitive, `{"db.host":["localhost"],"db.port":[5432],"db.list":["a"]}`},
+	}
+	for _, tt := range tests {
+		src := &source{
+			rd:      &ini.Reader{Separator: ".", True: "true", Casing: tt.casing},
+			profile: tt.profile,
+			wrap:    []wrapper{overrideProfile},
+			locate:  true,
+		}
+		if got := readString(t, src, &valueParser{}, in); got != tt.want {
+			t.Errorf("-profile %s (casing %v):\ngot  %s\nwant %s", tt.profile, tt.casing, got, tt.want)
+		}
+	}
+}
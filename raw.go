@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// rawText records the original text of each value in its input, keyed
+// the way the value is recorded once its key is renamed.
+type rawText struct {
+	bareKey string // Key that bare lines are recorded under, if any.
+	keys    []string
+	values  map[string][]string
+}
+
+func newRawText(bareKey string) *rawText {
+	return &rawText{bareKey: bareKey, values: map[string][]string{}}
+}
+
+// wrap is a wrapper that records the text following the '=' of the line
+// of each value, untrimmed and unparsed. Keys without a value are
+// recorded with empty text. It must follow any wrapper that renames or
+// discards keys, and its source must keep lines.
+func (t *rawText) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return observed{Recorder: dest, see: func(key string) {
+		line := at.line(at.Location().Line)
+		var text string
+		if i := strings.IndexByte(line, '='); i >= 0 {
+			text = line[i+1:]
+		} else if t.bareKey != "" {
+			text = line
+		}
+		if _, ok := t.values[key]; !ok {
+			t.keys = append(t.keys, key)
+		}
+		t.values[key] = append(t.values[key], text)
+	}}
+}
+
+// object returns the recorded text as an object with sorted keys, nested
+// on sep if nested is true.
+func (t *rawText) object(nested bool, sep string) *object {
+	keys := append([]string(nil), t.keys...)
+	sort.Strings(keys)
+	obj := newObject()
+	for _, k := range keys {
+		obj.Set(k, t.values[k])
+	}
+	if nested {
+		return nest(obj, sep)
+	}
+	return obj
+}
+
+// reset discards all recorded text.
+func (t *rawText) reset() {
+	t.keys, t.values = nil, map[string][]string{}
+}
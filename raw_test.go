@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestRawSidecar(t *testing.T) {
+	const in = "top = 1\n[db]\nhost =  \"a b\" \nflag\nhost = x\n[cache]\nsize = 2\n"
+	rule, err := parseRenameRule("db=database", ".", ini.CaseSensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		nested  bool
+		bareKey string
+		wrap    []wrapper
+		want    string
+	}{
+		{"flat", false, "", nil,
+			`{"cache.size":[" 2"],"db.flag":[""],"db.host":["  \"a b\" "," x"],"top":[" 1"]}`},
+		{"bare lines", false, "line", nil,
+			`{"cache.size":[" 2"],"db.host":["  \"a b\" "," x"],"db.line":["flag"],"top":[" 1"]}`},
+		{"renamed and excluded", false, "", []wrapper{filterSections(".", nil, []string{"cache"}), renameKeys([]renameRule{rule})},
+			`{"database.flag":[""],"database.host":["  \"a b\" "," x"],"top":[" 1"]}`},
+		{"nested", true, "", nil,
+			`{"cache":{"size":[" 2"]},"db":{"flag":[""],"host":["  \"a b\" "," x"]},"top":[" 1"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := newRawText(tt.bareKey)
+			src := &source{wrap: append(tt.wrap, raw.wrap), keep: true, locate: true}
+			if tt.bareKey != "" {
+				src.filters = []filter{bareLines(tt.bareKey)}
+			}
+			readValues(t, src, &valueParser{raw: true}, in)
+			p, err := marshalJSON(raw.object(tt.nested, "."), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(p); got != tt.want {
+				t.Errorf("_raw = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
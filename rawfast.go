@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"unicode/utf8"
+)
+
+// rawWriter records values as the strings they were read as, for
+// converting with -r -c without parsing them or encoding them by
+// reflection. Each value is written as JSON to a buffer as it is added,
+// so that the buffer holds the object that encoding the same values
+// recorded by a valueParser would write. Outputs are written in the
+// order of their inputs, so each is held until its input has been read,
+// as an encoder holds its output until it has been encoded.
+//
+// Values of a key are written together as long as they are recorded
+// together. A key recorded again after another key cannot be written where
+// its values are, so its values, and those of every key after the first
+// such key, are then held as strings until the end of the input instead.
+type rawWriter struct {
+	escapeHTML bool
+
+	buf  bytes.Buffer
+	seen map[string]bool // Keys written to buf.
+	last string          // Key of the array being written.
+	open bool            // Whether an array is being written.
+
+	order  []string            // Keys held, in order, once any are held.
+	values map[string][]string // Values of keys held.
+}
+
+func newRawWriter(escapeHTML bool) *rawWriter {
+	w := &rawWriter{escapeHTML: escapeHTML, seen: map[string]bool{}}
+	w.buf.WriteByte('{')
+	return w
+}
+
+func (w *rawWriter) Add(key, value string) {
+	switch {
+	case w.values != nil:
+	case w.open && key == w.last:
+		w.buf.WriteByte(',')
+		w.writeString(value)
+		return
+	case w.seen[key]:
+		w.values = map[string][]string{}
+	default:
+		if w.open {
+			w.buf.WriteString("],")
+		}
+		w.seen[key], w.last, w.open = true, key, true
+		w.writeString(key)
+		w.buf.WriteString(":[")
+		w.writeString(value)
+		return
+	}
+
+	if _, ok := w.values[key]; !ok {
+		w.order = append(w.order, key)
+	}
+	w.values[key] = append(w.values[key], value)
+}
+
+// bytes returns the JSON encoding of the values added, followed by a
+// newline, as an encoder would write it.
+func (w *rawWriter) bytes() rawJSON {
+	if w.values != nil {
+		w.writeHeld()
+	}
+	if w.open {
+		w.buf.WriteByte(']')
+	}
+	w.buf.WriteString("}\n")
+	return rawJSON(w.buf.Bytes())
+}
+
+// writeHeld writes the keys written to buf again with the values held for
+// them after their other values, followed by the keys only held.
+func (w *rawWriter) writeHeld() {
+	if w.open {
+		w.buf.WriteByte(']')
+	}
+	w.buf.WriteByte('}')
+	doc, err := ordered(json.RawMessage(w.buf.Bytes()))
+	if err != nil {
+		// buf only holds JSON written by writeString.
+		panic(err)
+	}
+
+	obj := doc.(*object)
+	w.buf.Reset()
+	w.buf.WriteByte('{')
+	w.open = false
+	for _, k := range obj.Keys() {
+		v, _ := obj.Get(k)
+		var vals []string
+		for _, s := range v.([]interface{}) {
+			vals = append(vals, s.(string))
+		}
+		w.writeArray(k, append(vals, w.values[k]...))
+	}
+	for _, k := range w.order {
+		if !w.seen[k] {
+			w.writeArray(k, w.values[k])
+		}
+	}
+	w.order, w.values = nil, nil
+}
+
+// writeArray writes key and its values to buf as a member of the object.
+func (w *rawWriter) writeArray(key string, vals []string) {
+	if w.buf.Len() > 1 {
+		w.buf.WriteByte(',')
+	}
+	w.writeString(key)
+	w.buf.WriteString(":[")
+	for i, s := range vals {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		w.writeString(s)
+	}
+	w.buf.WriteByte(']')
+}
+
+// writeString writes s to buf as a JSON string, escaped as encoding/json
+// escapes it.
+func (w *rawWriter) writeString(s string) {
+	b := &w.buf
+	b.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && (!w.escapeHTML || c != '<' && c != '>' && c != '&') {
+				i++
+				continue
+			}
+			b.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				if c < 0x20 {
+					// Other control characters are escaped by
+					// encoding/json, which may use a short escape for
+					// them, depending on its version.
+					p, _ := marshalJSON(string(c), false)
+					b.Write(p[1 : len(p)-1])
+				} else {
+					b.WriteString(`\u00`)
+					b.WriteByte(hexDigits[c>>4])
+					b.WriteByte(hexDigits[c&0xf])
+				}
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 || r == '\u2028' || r == '\u2029' {
+			b.WriteString(s[start:i])
+			if r == utf8.RuneError {
+				b.WriteString("\ufffd")
+			} else {
+				b.WriteString(`\u202`)
+				b.WriteByte(hexDigits[r&0xf])
+			}
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b.WriteString(s[start:])
+	b.WriteByte('"')
+}
+
+const hexDigits = "0123456789abcdef"
+
+// rawJSON is the output written by a rawWriter.
+type rawJSON []byte
+
+// rawEncoder writes rawJSON values as they are, and encodes others with
+// the encoder it wraps.
+type rawEncoder struct {
+	w io.Writer
+	encoder
+}
+
+func (e rawEncoder) Encode(v interface{}) error {
+	if p, ok := v.(rawJSON); ok {
+		_, err := e.w.Write(p)
+		return err
+	}
+	return e.encoder.Encode(v)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestRawWriter(t *testing.T) {
+	tests := []struct {
+		name string
+		adds [][2]string
+	}{
+		{"empty", nil},
+		{"single", [][2]string{{"a", "1"}}},
+		{"runs", [][2]string{{"a", "1"}, {"a", "2"}, {"b", "3"}}},
+		{"repeated", [][2]string{{"a", "1"}, {"b", "2"}, {"a", "3"}, {"c", "4"}, {"b", "5"}, {"d", "6"}}},
+		{"empty key", [][2]string{{"", "1"}, {"", "2"}, {"x", ""}}},
+		{"escapes", [][2]string{{"<k>", "a\"b\\c\nd\te\rf\x01"}, {"u", "é  \xff"}, {"html", "<&>"}}},
+	}
+	for _, tt := range tests {
+		for _, escapeHTML := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/%v", tt.name, escapeHTML), func(t *testing.T) {
+				w := newRawWriter(escapeHTML)
+				values := newParsedValues(&valueParser{raw: true})
+				for _, kv := range tt.adds {
+					w.Add(kv[0], kv[1])
+					values.Add(kv[0], kv[1])
+				}
+
+				var want bytes.Buffer
+				enc := json.NewEncoder(&want)
+				enc.SetEscapeHTML(escapeHTML)
+				if err := enc.Encode(orderedDocument(values)); err != nil {
+					t.Fatal(err)
+				}
+				if got := string(w.bytes()); got != want.String() {
+					t.Errorf("rawWriter wrote %s, want %s", got, want.String())
+				}
+			})
+		}
+	}
+}
+
+// benchmarkInput writes an INI file of machine-generated sections to a
+// temporary directory and returns its path.
+func benchmarkInput(b *testing.B) (string, func()) {
+	dir, err := ioutil.TempDir("", "ini2json-bench-")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf strings.Builder
+	for s := 0; s < 2000; s++ {
+		fmt.Fprintf(&buf, "[section%d]\n", s)
+		for k := 0; k < 12; k++ {
+			fmt.Fprintf(&buf, "key%d = value %d of <section %d>\n", k, k*s, s)
+		}
+	}
+	path := filepath.Join(dir, "bench.ini")
+	if err := ioutil.WriteFile(path, []byte(buf.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func BenchmarkConvertRaw(b *testing.B) {
+	path, done := benchmarkInput(b)
+	defer done()
+	src := &source{rd: &ini.Reader{Separator: ".", True: "true"}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := newRawWriter(true)
+		if err := src.read(w, path); err != nil {
+			b.Fatal(err)
+		}
+		if err := (rawEncoder{w: ioutil.Discard}).Encode(w.bytes()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkConvertParsed converts the same input as BenchmarkConvertRaw
+// through the default path, parsing values and encoding the output.
+func BenchmarkConvertParsed(b *testing.B) {
+	path, done := benchmarkInput(b)
+	defer done()
+	src := &source{rd: &ini.Reader{Separator: ".", True: "true"}}
+	out := &outputOptions{dup: "append"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		values := newParsedValues(&valueParser{})
+		if err := src.read(values, path); err != nil {
+			b.Fatal(err)
+		}
+		if err := json.NewEncoder(ioutil.Discard).Encode(out.output(values)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// isURL returns whether path names an HTTP or HTTPS input.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// remoteInputs fetches inputs named by URLs.
+type remoteInputs struct {
+	client  *http.Client
+	headers http.Header // Added to each request.
+}
+
+// remote is used to fetch URL inputs. main configures it from flags.
+var remote = &remoteInputs{client: http.DefaultClient, headers: http.Header{}}
+
+// configure sets the timeout of requests, the headers, given as
+// "Name: value", added to them, and their TLS options: a file of PEM
+// certificates to trust instead of the system's, and whether to skip
+// verifying servers' certificates.
+func (ri *remoteInputs) configure(timeout time.Duration, headers []string, caFile string, insecure bool) error {
+	for _, h := range headers {
+		i := strings.IndexByte(h, ':')
+		if i <= 0 {
+			return fmt.Errorf("invalid header %+q: must be of the form 'Name: value'", h)
+		}
+		ri.headers.Add(strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+1:]))
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %v", caFile)
+		}
+	}
+	ri.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	return nil
+}
+
+// open fetches the input at url. The body must be closed once read.
+func (ri *remoteInputs) open(url string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range ri.headers {
+		req.Header[name] = values
+	}
+	resp, err := ri.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
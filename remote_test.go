@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestRemoteInputs(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t" || r.Header.Get("X-Env") != "a: b" {
+			http.Error(w, "missing headers", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/app.ini" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("[db]\nhost = remote\n"))
+	}))
+	defer ts.Close()
+
+	paths, done := tempFiles(t, "")
+	defer done()
+	caFile := filepath.Join(filepath.Dir(paths[0]), "ca.pem")
+	pemText := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, pemText, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test server's certificate is only trusted when given with
+	// -ca-file, or when verification is skipped.
+	ri := &remoteInputs{headers: http.Header{}}
+	if err := ri.configure(time.Second, []string{"Authorization: Bearer t", " X-Env : a: b"}, "", false); err != nil {
+		t.Fatalf("configure = %v", err)
+	}
+	if _, err := ri.open(ts.URL + "/app.ini"); err == nil {
+		t.Errorf("open with an untrusted certificate = nil, want an error")
+	}
+	for _, insecure := range []bool{false, true} {
+		ca := caFile
+		if insecure {
+			ca = ""
+		}
+		ri := &remoteInputs{headers: http.Header{}}
+		if err := ri.configure(time.Second, []string{"Authorization: Bearer t", " X-Env : a: b"}, ca, insecure); err != nil {
+			t.Fatalf("configure = %v", err)
+		}
+		body, err := ri.open(ts.URL + "/app.ini")
+		if err != nil {
+			t.Fatalf("open(app.ini) with ca %q, insecure %t = %v", ca, insecure, err)
+		}
+		p, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil || string(p) != "[db]\nhost = remote\n" {
+			t.Errorf("open(app.ini) read %q, %v", p, err)
+		}
+
+		_, err = ri.open(ts.URL + "/missing.ini")
+		if want := "GET " + ts.URL + "/missing.ini: 404 Not Found"; errString(err) != want {
+			t.Errorf("open(missing.ini) = %v, want %s", err, want)
+		}
+	}
+
+	ri = &remoteInputs{headers: http.Header{}}
+	if err := ri.configure(time.Second, []string{"no-colon"}, "", false); err == nil {
+		t.Errorf("configure with an invalid header = nil, want an error")
+	}
+	if err := ri.configure(time.Second, nil, paths[0], false); err == nil || !strings.HasPrefix(err.Error(), "no certificates found") {
+		t.Errorf("configure with an empty CA file = %v, want no certificates found", err)
+	}
+}
+
+func TestReadURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a = 1\n[s]\nb = x\n"))
+	}))
+	defer ts.Close()
+
+	src := &source{rd: &ini.Reader{Separator: ".", True: "true"}}
+	values := newParsedValues(&valueParser{})
+	if err := src.read(values, ts.URL+"/x.ini"); err != nil {
+		t.Fatalf("read(%s) = %v", ts.URL, err)
+	}
+	if got, want := outputString(t, &outputOptions{single: true}, values), `{"a":1,"s.b":"x"}`; got != want {
+		t.Errorf("read(%s) = %s, want %s", ts.URL, got, want)
+	}
+}
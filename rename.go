@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// renameFlag is a flag.Value that appends key renaming rules to a list,
+// either directly or, if file is true, from the file named by the flag.
+type renameFlag struct {
+	rules *[]string
+	file  bool
+}
+
+func (f renameFlag) String() string {
+	if f.rules == nil {
+		return ""
+	}
+	return strings.Join(*f.rules, ",")
+}
+
+func (f renameFlag) Set(s string) error {
+	if !f.file {
+		*f.rules = append(*f.rules, s)
+		return nil
+	}
+
+	fi, err := os.Open(s)
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+	sc := bufio.NewScanner(fi)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		*f.rules = append(*f.rules, line)
+	}
+	return sc.Err()
+}
+
+// renameRule renames keys matching re to the expansion of repl.
+type renameRule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// parseRenameRule parses a rule of the form OLD=NEW. OLD may be:
+//
+//   - A regular expression between slashes, matched against the whole key.
+//     NEW may refer to its capture groups as $1, ${name}, and so on.
+//   - A glob, if it contains any of '*', '?', or '['. Each '*' in NEW is
+//     replaced by the text matched by the corresponding '*' in OLD.
+//   - Otherwise, a key or section name. Keys equal to OLD or starting with
+//     OLD and sep are renamed by replacing OLD with NEW.
+//
+// casing is applied to globs and names, since keys are compared after the
+// reader has changed their case.
+func parseRenameRule(rule, sep string, casing ini.Casing) (renameRule, error) {
+	i := strings.IndexByte(rule, '=')
+	if i <= 0 {
+		return renameRule{}, fmt.Errorf("invalid rename rule %+q: must be OLD=NEW", rule)
+	}
+	old, repl := rule[:i], rule[i+1:]
+
+	var expr string
+	switch {
+	case len(old) > 1 && old[0] == '/' && old[len(old)-1] == '/':
+		expr = "^(?:" + old[1:len(old)-1] + ")$"
+	case strings.ContainsAny(old, "*?["):
+		var stars int
+		expr, stars = globRegexp(applyCasing(casing, old))
+		for n := 1; strings.Contains(repl, "*"); n++ {
+			if n > stars {
+				return renameRule{}, fmt.Errorf("invalid rename rule %+q: NEW has more '*' than OLD", rule)
+			}
+			repl = strings.Replace(repl, "*", "${"+strconv.Itoa(n)+"}", 1)
+		}
+	default:
+		expr = "^" + regexp.QuoteMeta(applyCasing(casing, old)) + "(" + regexp.QuoteMeta(sep) + ".*)?$"
+		repl = strings.Replace(repl, "$", "$$", -1) + "${1}"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return renameRule{}, fmt.Errorf("invalid rename rule %+q: %v", rule, err)
+	}
+	return renameRule{re: re, repl: repl}, nil
+}
+
+// globRegexp returns a regular expression matching the same keys as glob,
+// with a capture group for each '*', and the number of '*' in glob.
+// Capture groups for '?' and character classes are not numbered.
+func globRegexp(glob string) (string, int) {
+	var buf strings.Builder
+	stars := 0
+	buf.WriteByte('^')
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			buf.WriteString("(.*)")
+			stars++
+		case '?':
+			buf.WriteString("(?:.)")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(regexp.QuoteMeta(glob[i:]))
+				i = len(glob)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("(?:[" + class + "])")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			buf.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteByte('$')
+	return buf.String(), stars
+}
+
+// renamer renames keys using the first matching rule before recording
+// them. Keys that match no rule are recorded as is.
+type renamer struct {
+	ini.Recorder
+	rules []renameRule
+}
+
+func renameKeys(rules []renameRule) wrapper {
+	return func(dest ini.Recorder, _ *cursor) ini.Recorder {
+		return renamer{Recorder: dest, rules: rules}
+	}
+}
+
+func (r renamer) Add(key, value string) {
+	r.Recorder.Add(rename(r.rules, key), value)
+}
+
+// rename returns key renamed by the first matching rule in rules.
+func rename(rules []renameRule, key string) string {
+	for _, rule := range rules {
+		if m := rule.re.FindStringSubmatchIndex(key); m != nil {
+			return string(rule.re.ExpandString(nil, rule.repl, key, m))
+		}
+	}
+	return key
+}
+
+// prefixKeys returns a wrapper that records keys prefixed by prefix and
+// sep. If prefix is empty, keys are prefixed by the name of the input
+// being read instead, with casing applied, so keys read from included
+// files are prefixed by the name of the input including them.
+func prefixKeys(prefix, sep string, casing ini.Casing) wrapper {
+	return func(dest ini.Recorder, at *cursor) ini.Recorder {
+		p := prefix
+		if p == "" {
+			p = applyCasing(casing, inputName(at.input))
+		}
+		return prefixed{Recorder: dest, prefix: p + sep}
+	}
+}
+
+type prefixed struct {
+	ini.Recorder
+	prefix string
+}
+
+func (p prefixed) Add(key, value string) {
+	p.Recorder.Add(p.prefix+key, value)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		rule string
+		keys map[string]string
+	}{
+		{"db=database", map[string]string{"db.host": "database.host", "db": "database", "dbx.a": "dbx.a"}},
+		{"db.replica=replica", map[string]string{"db.replica.host": "replica.host", "db.host": "db.host"}},
+		{`/(\w+)\.port/=${1}.listen`, map[string]string{"web.port": "web.listen", "web.port.x": "web.port.x"}},
+		{"*.host=*.hostname", map[string]string{"db.host": "db.hostname", "host": "host"}},
+		{"*.*.port=*.port.*", map[string]string{"a.b.port": "a.port.b"}},
+		{"db.h?st=db.h", map[string]string{"db.host": "db.h", "db.hst": "db.hst"}},
+		{"[ab].x=c.x", map[string]string{"a.x": "c.x", "b.x": "c.x", "d.x": "d.x"}},
+		{"a=b$c", map[string]string{"a.x": "b$c.x"}},
+	}
+	for _, tt := range tests {
+		rules := []renameRule{mustRename(t, tt.rule)}
+		for key, want := range tt.keys {
+			if got := rename(rules, key); got != want {
+				t.Errorf("-map %s: rename(%q) = %q, want %q", tt.rule, key, got, want)
+			}
+		}
+	}
+
+	rules := []renameRule{mustRename(t, "a.b=x"), mustRename(t, "a=y")}
+	if got := rename(rules, "a.b"); got != "x" {
+		t.Errorf("rename(a.b) = %q, want the first matching rule's x", got)
+	}
+
+	r, err := parseRenameRule("DB.*=x.*", ".", ini.LowerCase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rename([]renameRule{r}, "db.host"); got != "x.host" {
+		t.Errorf("lowercase rename(db.host) = %q, want x.host", got)
+	}
+}
+
+func TestRenameRuleErrors(t *testing.T) {
+	for _, rule := range []string{"", "=x", "nothing", "*.a=*.*", "/(/=x"} {
+		if _, err := parseRenameRule(rule, ".", ini.CaseSensitive); err == nil {
+			t.Errorf("parseRenameRule(%q) = nil, want an error", rule)
+		}
+	}
+}
+
+func TestRenameFile(t *testing.T) {
+	paths, done := tempFiles(t, "# rules\n\ndb=database\n  *.port=*.listen  \n")
+	defer done()
+	var rules []string
+	if err := (renameFlag{rules: &rules, file: true}).Set(paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0] != "db=database" || rules[1] != "*.port=*.listen" {
+		t.Errorf("rules = %q, want [db=database *.port=*.listen]", rules)
+	}
+}
+
+func TestPrefixKeys(t *testing.T) {
+	const in = "a = 1\n[s]\nb = 2\n"
+	tests := []struct {
+		prefix string
+		casing ini.Casing
+		want   string
+	}{
+		{"app", ini.CaseSensitive, `{"app.a":[1],"app.s.b":[2]}`},
+		{"", ini.CaseSensitive, `{"test.a":[1],"test.s.b":[2]}`},
+		{"", ini.UpperCase, `{"TEST.a":[1],"TEST.s.b":[2]}`},
+	}
+	for _, tt := range tests {
+		src := &source{wrap: []wrapper{prefixKeys(tt.prefix, ".", tt.casing)}}
+		if got := readString(t, src, &valueParser{}, in); got != tt.want {
+			t.Errorf("prefixKeys(%q, %v) = %s, want %s", tt.prefix, tt.casing, got, tt.want)
+		}
+	}
+
+	// Keys of included files are prefixed by the name of the input
+	// including them.
+	paths, done := tempFiles(t, "a = 1\n!include test2.ini\n", "b = 2\n")
+	defer done()
+	src := &source{
+		rd:          &ini.Reader{Separator: ".", True: "true"},
+		include:     true,
+		includePath: []string{filepath.Dir(paths[0])},
+		filters:     []filter{includeLines},
+		wrap:        []wrapper{prefixKeys("", ".", ini.CaseSensitive)},
+	}
+	values := newParsedValues(&valueParser{})
+	if err := src.read(values, paths[0]); err != nil {
+		t.Fatalf("read(%s) = %v", paths[0], err)
+	}
+	if got, want := outputString(t, &outputOptions{single: true}, values), `{"test.a":1,"test.b":2}`; got != want {
+		t.Errorf("-prefix-from-filename with includes = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+
+	ini "go.spiff.io/go-ini"
+	"go.spiff.io/ini2json/convert"
+)
+
+// conversionReport records what was read and recorded while converting
+// inputs, for -report.
+type conversionReport struct {
+	parser *valueParser
+	dup    string // Duplicate key policy.
+
+	Files      []string      `json:"files"`
+	Keys       *object       `json:"keys"` // Number of values of each key.
+	Coerced    []reportEntry `json:"coerced"`
+	Duplicates []reportEntry `json:"duplicates"`
+	Warnings   []reportEntry `json:"warnings"`
+
+	seen map[string]location // First location of each key.
+}
+
+// reportEntry is an entry in a list of a conversionReport.
+type reportEntry struct {
+	Key string `json:"key"`
+	location
+	Type    string    `json:"type,omitempty"` // Type a value was parsed as.
+	Text    string    `json:"text,omitempty"` // Text of a value.
+	First   *location `json:"first,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+func newConversionReport(parser *valueParser, dup string) *conversionReport {
+	return &conversionReport{
+		parser:     parser,
+		dup:        dup,
+		Files:      []string{},
+		Keys:       newObject(),
+		Coerced:    []reportEntry{},
+		Duplicates: []reportEntry{},
+		Warnings:   []reportEntry{},
+		seen:       map[string]location{},
+	}
+}
+
+// read records that the file at path was read.
+func (c *conversionReport) read(path string) {
+	c.Files = append(c.Files, path)
+}
+
+// reset forgets assigned keys, so that keys assigned again in the next
+// input are not duplicates.
+func (c *conversionReport) reset() {
+	c.seen = map[string]location{}
+}
+
+// wrap returns a recorder that records each value in c before passing it
+// on to dest.
+func (c *conversionReport) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return reported{Recorder: dest, c: c, at: at}
+}
+
+type reported struct {
+	ini.Recorder
+	c  *conversionReport
+	at *cursor
+}
+
+func (r reported) Add(key, value string) {
+	r.c.add(key, value, r.at.Location())
+	r.Recorder.Add(key, value)
+}
+
+func (c *conversionReport) add(key, value string, loc location) {
+	name, vals, _, err := c.parser.parseList(key, value)
+	if err != nil {
+		// Rejected values are reported when they are recorded.
+		return
+	}
+
+	n, _ := c.Keys.Get(name)
+	count, _ := n.(int)
+	c.Keys.Set(name, count+1)
+
+	typ := "array"
+	if len(vals) == 1 {
+		typ = jsonType(vals[0])
+	}
+	if typ != "string" {
+		c.Coerced = append(c.Coerced, reportEntry{Key: name, location: loc, Type: typ, Text: value})
+	}
+
+	first, dup := c.seen[name]
+	if !dup {
+		c.seen[name] = loc
+		return
+	}
+	c.Duplicates = append(c.Duplicates, reportEntry{Key: name, location: loc, First: &first})
+	switch c.dup {
+	case "first":
+		c.Warnings = append(c.Warnings, reportEntry{Key: name, location: loc, Message: "value ignored: -dup first keeps the first value"})
+	case "last":
+		c.Warnings = append(c.Warnings, reportEntry{Key: name, location: loc, Message: "value replaces earlier values: -dup last keeps the last value"})
+	}
+}
+
+// jsonType returns the JSON type of the parsed value v, with numbers
+// distinguished as int or float.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case *big.Int, int64:
+		return "int"
+	case *convert.Float, float64:
+		return "float"
+	case nil:
+		return "null"
+	case []interface{}:
+		return "array"
+	case locatedValue:
+		return jsonType(v.Value)
+	}
+	return "object"
+}
+
+// write writes c to w as a JSON object.
+func (c *conversionReport) write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
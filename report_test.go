@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestConversionReport(t *testing.T) {
+	paths, done := tempFiles(t, "a = 1\nb = text\nc = 1.5\n", "a = x\nd = [1]\nt = 1:2\n")
+	defer done()
+	parser := &valueParser{tuples: true, tupleSep: ":"}
+	rep := newConversionReport(parser, "last")
+	src := &source{
+		rd:     &ini.Reader{Separator: ".", True: "true"},
+		wrap:   []wrapper{rep.wrap},
+		locate: true,
+		opened: rep.read,
+	}
+	values := newParsedValues(parser)
+	for _, path := range paths {
+		if err := src.read(values, path); err != nil {
+			t.Fatalf("read(%s) = %v", path, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := rep.write(&buf); err != nil {
+		t.Fatalf("write = %v", err)
+	}
+	got := strings.NewReplacer(paths[0], "test.ini", paths[1], "test2.ini").Replace(buf.String())
+	want := `{
+  "files": [
+    "test.ini",
+    "test2.ini"
+  ],
+  "keys": {
+    "a": 2,
+    "b": 1,
+    "c": 1,
+    "d": 1,
+    "t": 1
+  },
+  "coerced": [
+    {
+      "key": "a",
+      "file": "test.ini",
+      "line": 1,
+      "column": 5,
+      "type": "int",
+      "text": "1"
+    },
+    {
+      "key": "c",
+      "file": "test.ini",
+      "line": 3,
+      "column": 5,
+      "type": "float",
+      "text": "1.5"
+    },
+    {
+      "key": "d",
+      "file": "test2.ini",
+      "line": 2,
+      "column": 5,
+      "type": "array",
+      "text": "[1]"
+    },
+    {
+      "key": "t",
+      "file": "test2.ini",
+      "line": 3,
+      "column": 5,
+      "type": "array",
+      "text": "1:2"
+    }
+  ],
+  "duplicates": [
+    {
+      "key": "a",
+      "file": "test2.ini",
+      "line": 1,
+      "column": 5,
+      "first": {
+        "file": "test.ini",
+        "line": 1,
+        "column": 5
+      }
+    }
+  ],
+  "warnings": [
+    {
+      "key": "a",
+      "file": "test2.ini",
+      "line": 1,
+      "column": 5,
+      "message": "value replaces earlier values: -dup last keeps the last value"
+    }
+  ]
+}
+`
+	if got != want {
+		t.Errorf("report:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Keys are not duplicates of those in inputs before a reset.
+	rep.reset()
+	rep.add("a", "1", location{File: "x.ini", Line: 1})
+	if n := len(rep.Duplicates); n != 1 {
+		t.Errorf("after reset, %d duplicates, want 1", n)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// readJSON decodes each JSON value in r and passes it to fn. Numbers are
+// decoded as json.Number to keep their original text.
+func readJSON(r io.Reader, fn func(v interface{}) error) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	for {
+		var v interface{}
+		if err := dec.Decode(&v); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
+
+// writeINI writes the JSON value v, decoded by readOrdered, as INI. v must
+// be an object. Objects become sections named by joining their keys with
+// sep, arrays become repeated assignments of their elements, and other
+// values become a single assignment. Values kept under "_value" in nested
+// output are assigned to their parent key, and "_description" strings are
+// written as comments above their section. A top-level "_comments" object
+// supplies comments to write above assignments, and top-level
+// "_descriptions" and "_raw" objects are ignored. A top-level "__meta__"
+// object, recorded by -fidelity, is written by writeFidelity instead.
+func writeINI(w io.Writer, v interface{}, sep string) error {
+	doc, ok := v.(*object)
+	if !ok {
+		return fmt.Errorf("cannot convert %T to INI: must be an object", v)
+	}
+	if m, ok := doc.Get(metaKey); ok {
+		if meta, ok := m.(*object); ok {
+			return writeFidelity(w, doc, meta, sep)
+		}
+	}
+	iw := &iniWriter{w: w, sep: sep}
+	if c, ok := doc.Get("_comments"); ok {
+		iw.comments, _ = c.(*object)
+	}
+	iw.section("", doc)
+	return iw.err
+}
+
+// iniWriter writes sections and assignments to w, keeping the first
+// error encountered.
+type iniWriter struct {
+	w        io.Writer
+	sep      string
+	comments *object // Comments to write above each value of a key.
+	written  bool
+	err      error
+}
+
+func (iw *iniWriter) printf(format string, args ...interface{}) {
+	if iw.err != nil {
+		return
+	}
+	_, iw.err = fmt.Fprintf(iw.w, format, args...)
+	iw.written = true
+}
+
+func (iw *iniWriter) section(name string, obj *object) {
+	descValue, _ := obj.Get("_description")
+	desc, hasDesc := descValue.(string)
+	started := false
+	start := func() {
+		if started || name == "" {
+			return
+		}
+		started = true
+		if iw.written {
+			iw.printf("\n")
+		}
+		if hasDesc {
+			for _, line := range strings.Split(desc, "\n") {
+				iw.printf("; %s\n", line)
+			}
+		}
+		iw.printf("[%s]\n", name)
+	}
+
+	var subs []string
+	for _, k := range obj.Keys() {
+		v, _ := obj.Get(k)
+		switch {
+		case k == "_description" && hasDesc && name != "":
+			continue
+		case (k == "_descriptions" || k == "_raw" || k == "_comments") && name == "":
+			if _, isObj := v.(*object); isObj {
+				continue
+			}
+		}
+
+		if sub, isObj := v.(*object); isObj {
+			if leaf, ok := sub.Get(leafKey); ok {
+				start()
+				iw.assign(name, k, leaf)
+			}
+			subs = append(subs, k)
+			continue
+		}
+		start()
+		iw.assign(name, k, v)
+	}
+	if hasDesc {
+		start()
+	}
+
+	for _, k := range subs {
+		v, _ := obj.Get(k)
+		sub := v.(*object)
+		if name != "" {
+			k = name + iw.sep + k
+		}
+		iw.section(k, withoutKey(sub, leafKey))
+	}
+}
+
+// assign writes one assignment of key in section for v, or one for each
+// element of v if it is an array, each preceded by its comment, if any.
+func (iw *iniWriter) assign(section, key string, v interface{}) {
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+	var comments []interface{}
+	if iw.comments != nil {
+		name := key
+		if section != "" {
+			name = section + iw.sep + key
+		}
+		c, _ := iw.comments.Get(name)
+		comments, _ = c.([]interface{})
+	}
+	for i, v := range values {
+		if i < len(comments) {
+			if c, ok := comments[i].(string); ok && c != "" {
+				for _, line := range strings.Split(c, "\n") {
+					iw.printf("; %s\n", line)
+				}
+			}
+		}
+		text, err := iniValue(v)
+		if err != nil && iw.err == nil {
+			iw.err = fmt.Errorf("cannot write %s: %v", key, err)
+		}
+		iw.printf("%s = %s\n", key, text)
+	}
+}
+
+// withoutKey returns a copy of obj without key, or obj if it does not
+// contain key.
+func withoutKey(obj *object, key string) *object {
+	if _, ok := obj.Get(key); !ok {
+		return obj
+	}
+	dup := newObject()
+	for _, k := range obj.Keys() {
+		if k != key {
+			dup.Set(k, obj.values[k])
+		}
+	}
+	return dup
+}
+
+// iniValue returns the INI text for a single decoded JSON value. Numbers,
+// booleans, and null are written bare; strings, arrays, and objects are
+// quoted.
+func iniValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return quoteValue(v), nil
+	case json.Number:
+		return v.String(), nil
+	case bool, nil:
+		p, err := json.Marshal(v)
+		return string(p), err
+	}
+	p, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return quoteValue(string(p)), nil
+}
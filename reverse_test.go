@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteINI(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"nested", `{"a": 1, "db": {"host": "x", "port": [1, 2], "tls": {"on": true}}, "s": "q\"t"}`,
+			"a = 1\ns = \"q\\\"t\"\n\n[db]\nhost = \"x\"\nport = 1\nport = 2\n\n[db.tls]\non = true\n"},
+		{"flat", `{"db.host": ["x"], "n": null}`, "db.host = \"x\"\nn = null\n"},
+		{"leaf values", `{"a": {"_value": [1], "b": [2]}}`, "a = 1\n\n[a]\nb = 2\n"},
+		{"descriptions", `{"db": {"_description": "main\ndatabase", "host": "x"}, "_descriptions": {"db": "main"}}`,
+			"; main\n; database\n[db]\nhost = \"x\"\n"},
+		{"comments", `{"db": {"host": ["x", "y"]}, "_comments": {"db.host": ["first", ""]}}`,
+			"[db]\n; first\nhost = \"x\"\nhost = \"y\"\n"},
+		{"composite values", `{"a": [[1, "b"]], "o": [{"k": "v"}]}`, "a = \"[1,\\\"b\\\"]\"\no = \"{\\\"k\\\":\\\"v\\\"}\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := readOrdered(strings.NewReader(tt.in), func(v interface{}) error {
+				return writeINI(&buf, v, ".")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeINI(%s) =\n%s\nwant\n%s", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	err := readOrdered(strings.NewReader(`[1]`), func(v interface{}) error {
+		return writeINI(&bytes.Buffer{}, v, ".")
+	})
+	if want := "cannot convert []interface {} to INI: must be an object"; errString(err) != want {
+		t.Errorf("writeINI([1]) = %v; want %s", err, want)
+	}
+}
+
+func TestWriteINIRoundTrip(t *testing.T) {
+	const in = "top = yes\n[db]\nhost = x y\nport = 1\nport = 2\nratio = 1.5\n[db.tls]\non = true\n"
+	for _, nested := range []bool{false, true} {
+		values := readValues(t, &source{}, &valueParser{}, in)
+		out := &outputOptions{nested: nested, sep: "."}
+		want := outputString(t, out, values)
+
+		var buf bytes.Buffer
+		err := readOrdered(strings.NewReader(want), func(v interface{}) error {
+			return writeINI(&buf, v, ".")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := outputString(t, out, readValues(t, &source{}, &valueParser{}, buf.String())); got != want {
+			t.Errorf("nested = %v: %q read back as %s; want %s", nested, buf.String(), got, want)
+		}
+	}
+}
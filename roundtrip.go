@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// roundTrip converts values to JSON and back to INI, as -reverse would,
+// reads the result with rd into a new recorder, and returns an error
+// describing every key whose values differ between the two.
+func roundTrip(values ini.Recorder, rd *ini.Reader, newValues func() ini.Recorder) error {
+	p, err := json.Marshal(orderedDocument(values))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = readOrdered(bytes.NewReader(p), func(v interface{}) error {
+		return writeINI(&buf, v, rd.Separator)
+	})
+	if err != nil {
+		return err
+	}
+
+	again := newValues()
+	if err := rd.Read(&buf, again); err != nil {
+		return fmt.Errorf("unable to re-read INI: %v", err)
+	}
+
+	before, after := document(values), document(again)
+	keys := map[string]struct{}{}
+	for k := range before {
+		keys[k] = struct{}{}
+	}
+	for k := range after {
+		keys[k] = struct{}{}
+	}
+
+	var diffs []string
+	for k := range keys {
+		b, err := json.Marshal(before[k])
+		if err != nil {
+			return err
+		}
+		a, err := json.Marshal(after[k])
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(a, b) {
+			diffs = append(diffs, fmt.Sprintf("  %s: %s -> %s", k, b, a))
+		}
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	sort.Strings(diffs)
+	return fmt.Errorf("values changed:\n%s", strings.Join(diffs, "\n"))
+}
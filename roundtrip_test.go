@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestRoundTrip(t *testing.T) {
+	const in = "top = 007\n[db]\nhost = \"a ; b\"\nports = 1\nports = 2\nratio = 1.5\non\nlist = [1, \"x\"]\n[db.sub]\nname = \"line\\nbreak\"\n"
+	rd := &ini.Reader{Separator: ".", True: "true"}
+	newValues := func() ini.Recorder { return newParsedValues(&valueParser{}) }
+	values := readValues(t, &source{rd: rd}, &valueParser{}, in)
+	if err := roundTrip(values, rd, newValues); err != nil {
+		t.Errorf("roundTrip(%q) = %v", in, err)
+	}
+}
+
+func TestRoundTripMismatch(t *testing.T) {
+	rd := &ini.Reader{Separator: ".", True: "true"}
+	newValues := func() ini.Recorder { return newParsedValues(&valueParser{}) }
+	// A key renamed to contain '=' is read back as a different key.
+	values := readValues(t, &source{rd: rd, wrap: []wrapper{renameKeys([]renameRule{mustRename(t, "a=b=c")})}}, &valueParser{}, "a = 1\nd = 2\n")
+	err := roundTrip(values, rd, newValues)
+	const want = "values changed:\n  b: null -> [\"c = 1\"]\n  b=c: [1] -> null"
+	if err == nil || err.Error() != want {
+		t.Errorf("roundTrip() = %v, want %q", err, want)
+	}
+}
+
+func mustRename(t *testing.T, rule string) renameRule {
+	t.Helper()
+	r, err := parseRenameRule(rule, ".", ini.CaseSensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
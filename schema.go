@@ -0,0 +1,473 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// schema is a JSON Schema document. It supports the commonly used subset
+// of the specification: type, enum, const, numeric and string bounds,
+// pattern, items, properties, required, additionalProperties,
+// patternProperties, property and item counts, allOf, anyOf, oneOf, not,
+// and local $ref pointers (e.g., "#/definitions/port").
+type schema struct {
+	root interface{}
+
+	// refs holds the $ref pointers being followed at each instance
+	// path during a validation, to detect reference cycles. cycles
+	// holds the errors for cycles found, which are reported even when
+	// they occur under not, anyOf, or oneOf.
+	refs   map[string]bool
+	cycles []string
+}
+
+// readSchema reads the JSON Schema in the file at name.
+func readSchema(name string) (*schema, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var root interface{}
+	err = readJSON(f, func(v interface{}) error {
+		root = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch root.(type) {
+	case bool, map[string]interface{}:
+		return &schema{root: root}, nil
+	}
+	return nil, fmt.Errorf("schema must be an object or boolean")
+}
+
+// validate returns an error listing every path in v that does not match
+// the schema.
+func (s *schema) validate(v interface{}) error {
+	g, err := generic(v)
+	if err != nil {
+		return err
+	}
+	run := &schema{root: s.root, refs: map[string]bool{}}
+	errs := run.check(run.root, g, "")
+	for _, e := range run.cycles {
+		if !containsString(errs, e) {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("schema validation failed:\n  %s", strings.Join(errs, "\n  "))
+}
+
+// check returns the errors from validating inst, at the JSON Pointer ptr,
+// against the schema sch.
+func (s *schema) check(sch, inst interface{}, ptr string) (errs []string) {
+	fail := func(format string, args ...interface{}) {
+		at := ptr
+		if at == "" {
+			at = "/"
+		}
+		errs = append(errs, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	obj, ok := sch.(map[string]interface{})
+	if !ok {
+		if allowed, isBool := sch.(bool); !isBool {
+			fail("invalid schema %v", sch)
+		} else if !allowed {
+			fail("no value is allowed")
+		}
+		return errs
+	}
+
+	if ref, ok := obj["$ref"].(string); ok {
+		// A $ref reached again at the same instance path, without
+		// descending into the instance, would be followed forever.
+		key := ptr + "\x00" + ref
+		if target, err := s.resolve(ref); err != nil {
+			fail("%v", err)
+		} else if s.refs[key] {
+			fail("$ref %+q is part of a reference cycle", ref)
+			s.cycles = append(s.cycles, errs[len(errs)-1])
+		} else {
+			s.refs[key] = true
+			errs = append(errs, s.check(target, inst, ptr)...)
+			delete(s.refs, key)
+		}
+	}
+
+	if t, ok := obj["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []interface{}:
+			for _, e := range t {
+				if name, ok := e.(string); ok {
+					types = append(types, name)
+				}
+			}
+		}
+		matched := false
+		for _, name := range types {
+			if hasType(inst, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("expected %s, got %s", strings.Join(types, " or "), typeName(inst))
+		}
+	}
+
+	if enum, ok := obj["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, inst) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of the allowed values")
+		}
+	}
+	if c, ok := obj["const"]; ok && !jsonEqual(c, inst) {
+		fail("value must be %s", jsonText(c))
+	}
+
+	switch inst := inst.(type) {
+	case json.Number:
+		errs = append(errs, s.checkNumber(obj, inst, fail)...)
+	case string:
+		n := utf8.RuneCountInString(inst)
+		if min, ok := schemaInt(obj, "minLength"); ok && n < min {
+			fail("string is shorter than %d characters", min)
+		}
+		if max, ok := schemaInt(obj, "maxLength"); ok && n > max {
+			fail("string is longer than %d characters", max)
+		}
+		if pat, ok := obj["pattern"].(string); ok {
+			if re, err := regexp.Compile(pat); err != nil {
+				fail("invalid pattern %+q: %v", pat, err)
+			} else if !re.MatchString(inst) {
+				fail("string does not match pattern %+q", pat)
+			}
+		}
+	case []interface{}:
+		errs = append(errs, s.checkArray(obj, inst, ptr, fail)...)
+	case map[string]interface{}:
+		errs = append(errs, s.checkObject(obj, inst, ptr, fail)...)
+	}
+
+	if all, ok := obj["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, s.check(sub, inst, ptr)...)
+		}
+	}
+	if anyOf, ok := obj["anyOf"].([]interface{}); ok {
+		if s.matches(anyOf, inst, ptr) == 0 {
+			fail("value does not match any schema in anyOf")
+		}
+	}
+	if oneOf, ok := obj["oneOf"].([]interface{}); ok {
+		if n := s.matches(oneOf, inst, ptr); n != 1 {
+			fail("value matches %d schemas in oneOf, not exactly one", n)
+		}
+	}
+	if not, ok := obj["not"]; ok && len(s.check(not, inst, ptr)) == 0 {
+		fail("value must not match the schema in not")
+	}
+	return errs
+}
+
+// matches returns the number of schemas in list that inst matches.
+func (s *schema) matches(list []interface{}, inst interface{}, ptr string) int {
+	n := 0
+	for _, sub := range list {
+		if len(s.check(sub, inst, ptr)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *schema) checkNumber(obj map[string]interface{}, n json.Number, fail func(string, ...interface{})) []string {
+	x, ok := new(big.Float).SetString(n.String())
+	if !ok {
+		fail("invalid number %s", n)
+		return nil
+	}
+	bound := func(key string) (*big.Float, bool) {
+		b, ok := obj[key].(json.Number)
+		if !ok {
+			return nil, false
+		}
+		f, ok := new(big.Float).SetString(b.String())
+		return f, ok
+	}
+	if min, ok := bound("minimum"); ok && x.Cmp(min) < 0 {
+		fail("%s is less than the minimum of %v", n, obj["minimum"])
+	}
+	if max, ok := bound("maximum"); ok && x.Cmp(max) > 0 {
+		fail("%s is greater than the maximum of %v", n, obj["maximum"])
+	}
+	if min, ok := bound("exclusiveMinimum"); ok && x.Cmp(min) <= 0 {
+		fail("%s must be greater than %v", n, obj["exclusiveMinimum"])
+	}
+	if max, ok := bound("exclusiveMaximum"); ok && x.Cmp(max) >= 0 {
+		fail("%s must be less than %v", n, obj["exclusiveMaximum"])
+	}
+	if m, ok := bound("multipleOf"); ok && m.Sign() != 0 {
+		if q := new(big.Float).Quo(x, m); !q.IsInt() {
+			fail("%s is not a multiple of %v", n, obj["multipleOf"])
+		}
+	}
+	return nil
+}
+
+func (s *schema) checkArray(obj map[string]interface{}, inst []interface{}, ptr string, fail func(string, ...interface{})) (errs []string) {
+	if min, ok := schemaInt(obj, "minItems"); ok && len(inst) < min {
+		fail("array has fewer than %d items", min)
+	}
+	if max, ok := schemaInt(obj, "maxItems"); ok && len(inst) > max {
+		fail("array has more than %d items", max)
+	}
+	if unique, _ := obj["uniqueItems"].(bool); unique {
+		seen := map[string]bool{}
+		for _, e := range inst {
+			text := jsonText(e)
+			if seen[text] {
+				fail("array items are not unique")
+				break
+			}
+			seen[text] = true
+		}
+	}
+	switch items := obj["items"].(type) {
+	case []interface{}:
+		for i, e := range inst {
+			if i < len(items) {
+				errs = append(errs, s.check(items[i], e, ptr+"/"+strconv.Itoa(i))...)
+			} else if extra, ok := obj["additionalItems"]; ok {
+				errs = append(errs, s.check(extra, e, ptr+"/"+strconv.Itoa(i))...)
+			}
+		}
+	case nil:
+	default:
+		for i, e := range inst {
+			errs = append(errs, s.check(items, e, ptr+"/"+strconv.Itoa(i))...)
+		}
+	}
+	if contains, ok := obj["contains"]; ok {
+		found := false
+		for _, e := range inst {
+			if len(s.check(contains, e, ptr)) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("array does not contain a matching item")
+		}
+	}
+	return errs
+}
+
+func (s *schema) checkObject(obj map[string]interface{}, inst map[string]interface{}, ptr string, fail func(string, ...interface{})) (errs []string) {
+	if min, ok := schemaInt(obj, "minProperties"); ok && len(inst) < min {
+		fail("object has fewer than %d properties", min)
+	}
+	if max, ok := schemaInt(obj, "maxProperties"); ok && len(inst) > max {
+		fail("object has more than %d properties", max)
+	}
+	if required, ok := obj["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, ok := inst[name]; !ok {
+					fail("missing required property %+q", name)
+				}
+			}
+		}
+	}
+
+	props, _ := obj["properties"].(map[string]interface{})
+	patterns, _ := obj["patternProperties"].(map[string]interface{})
+	extra, hasExtra := obj["additionalProperties"]
+	names, hasNames := obj["propertyNames"]
+
+	keys := make([]string, 0, len(inst))
+	for k := range inst {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		at := ptr + "/" + escapePointer(k)
+		if hasNames {
+			errs = append(errs, s.check(names, k, at)...)
+		}
+		matched := false
+		if sub, ok := props[k]; ok {
+			matched = true
+			errs = append(errs, s.check(sub, inst[k], at)...)
+		}
+		for pat, sub := range patterns {
+			if re, err := regexp.Compile(pat); err == nil && re.MatchString(k) {
+				matched = true
+				errs = append(errs, s.check(sub, inst[k], at)...)
+			}
+		}
+		if !matched && hasExtra {
+			if allowed, ok := extra.(bool); ok && !allowed {
+				errs = append(errs, at+": property is not allowed")
+			} else {
+				errs = append(errs, s.check(extra, inst[k], at)...)
+			}
+		}
+	}
+	return errs
+}
+
+// resolve returns the schema referred to by the local JSON Pointer ref.
+func (s *schema) resolve(ref string) (interface{}, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %+q: only local references are supported", ref)
+	}
+	cur := s.root
+	for _, tok := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		tok = strings.Replace(strings.Replace(tok, "~1", "/", -1), "~0", "~", -1)
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if cur, ok = c[tok]; !ok {
+				return nil, fmt.Errorf("$ref %+q not found", ref)
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("$ref %+q not found", ref)
+			}
+			cur = c[i]
+		default:
+			return nil, fmt.Errorf("$ref %+q not found", ref)
+		}
+	}
+	return cur, nil
+}
+
+// hasType returns whether v is of the named JSON Schema type.
+func hasType(v interface{}, name string) bool {
+	switch name {
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, ok := new(big.Float).SetString(n.String())
+		return ok && f.IsInt()
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	}
+	return typeName(v) == name
+}
+
+// typeName returns the JSON Schema type name of v.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonEqual returns whether a and b are equal JSON values. Numbers are
+// compared by value.
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, ok1 := new(big.Float).SetString(a.String())
+		y, ok2 := new(big.Float).SetString(bn.String())
+		return ok1 && ok2 && x.Cmp(y) == 0
+	case []interface{}:
+		bs, ok := b.([]interface{})
+		if !ok || len(a) != len(bs) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], bs[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bm, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(bm) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := bm[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func jsonText(v interface{}) string {
+	p, _ := json.Marshal(v)
+	return string(p)
+}
+
+// schemaInt returns the non-negative integer value of obj[key].
+func schemaInt(obj map[string]interface{}, key string) (int, bool) {
+	n, ok := obj[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	return int(i), err == nil
+}
+
+// escapePointer escapes k for use as a JSON Pointer token.
+func escapePointer(k string) string {
+	return strings.Replace(strings.Replace(k, "~", "~0", -1), "/", "~1", -1)
+}
+
+// containsString returns whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
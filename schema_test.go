@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	const tree = `{
+		"definitions": {
+			"node": {
+				"type": "object",
+				"properties": {"name": {"type": "string"}, "children": {"type": "array", "items": {"$ref": "#/definitions/node"}}},
+				"required": ["name"]
+			}
+		},
+		"$ref": "#/definitions/node"
+	}`
+	tests := []struct {
+		name, schema, inst string
+		want               []string // Substrings of the error, in order, or none if valid.
+	}{
+		{"valid", `{"type":"object","properties":{"port":{"type":"integer","minimum":1}},"required":["port"]}`, `{"port":80}`, nil},
+		{"type", `{"properties":{"port":{"type":"integer"}}}`, `{"port":"x"}`, []string{"/port: "}},
+		{"required", `{"required":["host","port"]}`, `{"host":"a"}`, []string{"/: ", "port"}},
+		{"bounds", `{"properties":{"n":{"maximum":10},"s":{"maxLength":2}}}`, `{"n":11,"s":"abc"}`, []string{"/n: ", "/s: "}},
+		{"enum", `{"properties":{"level":{"enum":["debug","info"]}}}`, `{"level":"trace"}`, []string{"/level: "}},
+		{"additional", `{"properties":{"a":{}},"additionalProperties":false}`, `{"a":1,"b":2}`, []string{"/b: "}},
+		{"ref", `{"definitions":{"port":{"type":"integer"}},"properties":{"p":{"$ref":"#/definitions/port"}}}`, `{"p":1.5}`, []string{"/p: "}},
+		{"missing ref", `{"$ref":"#/definitions/none"}`, `{}`, []string{"not found"}},
+		{"recursive", tree, `{"name":"a","children":[{"name":"b","children":[{"name":"c"}]}]}`, nil},
+		{"recursive invalid", tree, `{"name":"a","children":[{"children":[]}]}`, []string{"/children/0: ", "name"}},
+		{"self cycle", `{"$ref":"#"}`, `{}`, []string{"reference cycle"}},
+		{"cycle", `{"definitions":{"a":{"$ref":"#/definitions/b"},"b":{"allOf":[{"$ref":"#/definitions/a"}]}},"$ref":"#/definitions/a"}`, `1`, []string{"reference cycle"}},
+		{"not cycle", `{"definitions":{"a":{"not":{"$ref":"#/definitions/a"}}},"$ref":"#/definitions/a"}`, `1`, []string{"reference cycle"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root interface{}
+			err := readJSON(strings.NewReader(tt.schema), func(v interface{}) error {
+				root = v
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = (&schema{root: root}).validate(json.RawMessage(tt.inst))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("validate(%s) = %v", tt.inst, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validate(%s) = nil, want an error", tt.inst)
+			}
+			msg := err.Error()
+			for _, w := range tt.want {
+				i := strings.Index(msg, w)
+				if i < 0 {
+					t.Fatalf("validate(%s) = %q, want it to contain %q", tt.inst, err, w)
+				}
+				msg = msg[i+len(w):]
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// stringList is a flag.Value that collects each use of a flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// globList is a stringList of globs that are validated when set.
+type globList struct {
+	stringList
+}
+
+func (l *globList) Set(s string) error {
+	if _, err := path.Match(s, ""); err != nil {
+		return fmt.Errorf("invalid pattern %+q: %v", s, err)
+	}
+	return l.stringList.Set(s)
+}
+
+// sectionFilter drops values whose section is excluded before they are
+// recorded. A key is in a section matching a glob if any prefix of the
+// key ending before a separator matches it, so "db" selects db.host and
+// db.replica.host, and "db.replica" selects only the latter.
+type sectionFilter struct {
+	ini.Recorder
+	sep     string
+	only    []string
+	exclude []string
+}
+
+func filterSections(sep string, only, exclude []string) wrapper {
+	return func(dest ini.Recorder, _ *cursor) ini.Recorder {
+		return sectionFilter{Recorder: dest, sep: sep, only: only, exclude: exclude}
+	}
+}
+
+func (f sectionFilter) Add(key, value string) {
+	if len(f.only) > 0 && !f.inSection(f.only, key) {
+		return
+	}
+	if f.inSection(f.exclude, key) {
+		return
+	}
+	f.Recorder.Add(key, value)
+}
+
+// inSection returns whether key is in a section matching any of patterns.
+func (f sectionFilter) inSection(patterns []string, key string) bool {
+	if f.sep == "" || len(patterns) == 0 {
+		return false
+	}
+	for i := strings.Index(key, f.sep); i >= 0; {
+		if matchAny(patterns, key[:i]) {
+			return true
+		}
+		next := strings.Index(key[i+len(f.sep):], f.sep)
+		if next < 0 {
+			break
+		}
+		i += len(f.sep) + next
+	}
+	return false
+}
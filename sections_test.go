@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestFilterSections(t *testing.T) {
+	const in = "top = 0\n[db]\nhost = a\n[db.replica]\nhost = b\n[web]\nport = 1\n[webapp]\nx = 1\n"
+	tests := []struct {
+		only, exclude []string
+		want          string
+	}{
+		{[]string{"db"}, nil, `{"db.host":["a"],"db.replica.host":["b"]}`},
+		{[]string{"db.replica"}, nil, `{"db.replica.host":["b"]}`},
+		{nil, []string{"db.replica"}, `{"top":[0],"db.host":["a"],"web.port":[1],"webapp.x":[1]}`},
+		{[]string{"web*"}, nil, `{"web.port":[1],"webapp.x":[1]}`},
+		{[]string{"db"}, []string{"db.replica"}, `{"db.host":["a"]}`},
+		{[]string{"nope"}, nil, `{}`},
+	}
+	for _, tt := range tests {
+		src := &source{wrap: []wrapper{filterSections(".", tt.only, tt.exclude)}}
+		if got := readString(t, src, &valueParser{}, in); got != tt.want {
+			t.Errorf("-only %q -exclude %q: got %s, want %s", tt.only, tt.exclude, got, tt.want)
+		}
+	}
+}
+
+func TestGlobList(t *testing.T) {
+	var l globList
+	if err := l.Set("db.*"); err != nil {
+		t.Errorf("Set(db.*) = %v", err)
+	}
+	if err := l.Set("[db"); err == nil {
+		t.Errorf("Set([db) = nil, want an error")
+	}
+	if got := l.String(); got != "db.*" {
+		t.Errorf("String() = %q, want db.*", got)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// wrapper returns a recorder that passes values on to dest. at is the
+// location of the value being recorded.
+type wrapper func(dest ini.Recorder, at *cursor) ini.Recorder
+
+// source reads INI inputs into recorders.
+type source struct {
+	rd      *ini.Reader
+	detect  bool      // Whether to detect the dialect of each input.
+	dialect []filter  // Applied to the input before filters, in order.
+	filters []filter  // Applied to the input, in order.
+	wrap    []wrapper // Values pass through these in order.
+	locate  bool      // Whether to track the line of each value.
+	keep    bool      // Whether to keep the text and comments of lines, with locate.
+	ops     bool      // Whether to read +=, :=, and =! operators.
+	profile string    // If set, the profile of section variants to read.
+	limit   int64     // If positive, the maximum size of each input in bytes.
+
+	// If set, validate returns a filter that checks the input named by
+	// path, applied after dialect filters.
+	validate func(path string) filter
+
+	// If set, opened is called with the path of each file opened.
+	opened func(path string)
+
+	include     bool     // Whether to follow include directives.
+	includePath []string // Directories to search for included files.
+}
+
+// read reads the input named by path into dest.
+func (s *source) read(dest ini.Recorder, path string) error {
+	return s.readFile(dest, path, nil)
+}
+
+// readFile reads the input named by path into dest. stack is the list of
+// files that included path, used to detect include cycles. If dest or any
+// recorder wrapping it has an Err method, its error is returned after
+// reading.
+func (s *source) readFile(dest ini.Recorder, path string, stack []string) error {
+	in, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if s.opened != nil {
+		s.opened(path)
+	}
+
+	at := &cursor{loc: location{File: path}, input: path}
+	if len(stack) > 0 {
+		at.input = stack[0]
+	}
+	var filters []filter
+	if s.limit > 0 {
+		// The limit applies to the input as it is read, before any
+		// filter buffers it.
+		filters = append(filters, limitBytes(s.limit))
+	}
+	if s.detect {
+		filters = append(filters, at.detect(s.rd.Separator))
+	}
+	filters = append(filters, s.dialect...)
+	if s.validate != nil {
+		filters = append(filters, s.validate(path))
+	}
+	if s.ops {
+		filters = append(filters, at.operators())
+	}
+	if s.profile != "" {
+		filters = append(filters, at.profiles(s.profile, s.rd.Casing))
+	}
+	if s.locate {
+		filters = append(filters, at.columns())
+	}
+	if s.keep {
+		filters = append(filters, at.keep())
+	}
+	filters = append(filters, s.filters...)
+	if s.locate {
+		filters = append(filters, at.filter())
+	}
+
+	var r io.Reader = in
+	for _, fn := range filters {
+		rc := fn.apply(r)
+		defer rc.Close()
+		r = rc
+	}
+
+	layers := []ini.Recorder{dest}
+	if s.include {
+		dest = s.includes(dest, path, stack)
+		layers = append(layers, dest)
+	}
+	for i := len(s.wrap) - 1; i >= 0; i-- {
+		dest = s.wrap[i](dest, at)
+		layers = append(layers, dest)
+	}
+	dest = trimmedKeys{dest}
+	if s.locate {
+		dest = located{Recorder: dest, at: at}
+	}
+
+	if err := s.rd.Read(r, dest); err != nil {
+		return err
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		if v, ok := layers[i].(interface{ Err() error }); ok {
+			if err := v.Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
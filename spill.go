@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// spill is the destination of merged inputs for -spill. Values merged
+// into it are encoded as JSON and written to a temporary file as each
+// input is merged, and only their keys and where their encodings are in
+// the file are kept in memory, so that merging large inputs holds at most
+// the inputs being read at once instead of every value read.
+//
+// Inputs are merged in order by readMerged, so its methods are not safe
+// for concurrent use and need not be.
+type spill struct {
+	f          *os.File
+	size       int64
+	escapeHTML bool
+	order      []string          // Keys in the order they were first merged.
+	spans      map[string][]span // Encoded values of each key, in order.
+	arrays     map[string]bool   // Keys that are always written as arrays.
+	err        error
+}
+
+// span is the position of an encoded value in a spill file.
+type span struct {
+	off int64
+	n   int
+}
+
+// newSpill returns a spill writing to a new file in the default directory
+// for temporary files. Where open files can be removed, the file is
+// removed at once, so that it is not left behind if the process exits
+// without closing it.
+func newSpill(escapeHTML bool) (*spill, error) {
+	f, err := ioutil.TempFile("", "ini2json-merge-")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	return &spill{f: f, escapeHTML: escapeHTML, spans: map[string][]span{}, arrays: map[string]bool{}}, nil
+}
+
+// merge returns a merge function, as given to readMerged, that writes the
+// values recorded in src to s and ignores dest. If replace is true, the
+// values of each key in src replace those merged before, as with
+// -merge-strategy override; otherwise they are appended to them.
+func (s *spill) merge(replace bool) func(dest, src ini.Recorder) {
+	return func(_, src ini.Recorder) {
+		v := src.(parsedValues)
+		for _, k := range *v.order {
+			if _, ok := s.spans[k]; !ok {
+				s.order = append(s.order, k)
+				s.spans[k] = nil
+			} else if replace {
+				s.spans[k] = s.spans[k][:0]
+			}
+			for _, val := range v.Values[k] {
+				s.write(k, val)
+			}
+		}
+		for k := range v.arrays {
+			s.arrays[k] = true
+		}
+	}
+}
+
+// write appends the encoding of val to the file and records it as a value
+// of key, keeping the first error encountered.
+func (s *spill) write(key string, val interface{}) {
+	if s.err != nil {
+		return
+	}
+	p, err := marshalJSON(val, s.escapeHTML)
+	if err == nil {
+		_, err = s.f.Write(p)
+	}
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.spans[key] = append(s.spans[key], span{off: s.size, n: len(p)})
+	s.size += int64(len(p))
+}
+
+// writeJSON writes the merged values to w as a JSON object, the same as
+// out.output would produce for them and a JSON encoder indenting by indent
+// would write, reading each key's values back from the file in turn.
+func (s *spill) writeJSON(w io.Writer, out *outputOptions, indent string) error {
+	if s.err != nil {
+		return s.err
+	}
+	keys := s.order
+	if out.sorted {
+		keys = append([]string(nil), keys...)
+		sort.Strings(keys)
+	}
+
+	var buf, values bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range keys {
+		spans := s.spans[k]
+		if len(spans) > 1 && out.dup == "first" {
+			spans = spans[:1]
+		} else if len(spans) > 1 && out.dup == "last" {
+			spans = spans[len(spans)-1:]
+		}
+
+		values.Reset()
+		scalar := out.single && len(spans) == 1 && !s.arrays[k]
+		if !scalar {
+			values.WriteByte('[')
+		}
+		for j, sp := range spans {
+			if j > 0 {
+				values.WriteByte(',')
+			}
+			p := make([]byte, sp.n)
+			if _, err := s.f.ReadAt(p, sp.off); err != nil {
+				return err
+			}
+			values.Write(p)
+		}
+		if !scalar {
+			values.WriteByte(']')
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if indent != "" {
+			buf.WriteString("\n" + indent)
+		}
+		key, err := marshalJSON(k, s.escapeHTML)
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		if indent == "" {
+			buf.Write(values.Bytes())
+		} else {
+			buf.WriteByte(' ')
+			if err := json.Indent(&buf, values.Bytes(), indent, indent); err != nil {
+				return err
+			}
+		}
+
+		// Write each key as it is finished, so that only one key's values
+		// are held at once.
+		if _, err := buf.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	if indent != "" && len(keys) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Close closes the file and removes it, if it was not already removed.
+func (s *spill) Close() error {
+	err := s.f.Close()
+	if rerr := os.Remove(s.f.Name()); err == nil && !os.IsNotExist(rerr) {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestSpill(t *testing.T) {
+	paths, done := tempFiles(t,
+		"z = 1\na = <&>\nlist = 1\nlist = 2\n[s]\nobj = {\"k\": [1, 2]}\n",
+		"a = b\n[s]\nobj = 3\nnew = 1:2\n",
+	)
+	defer done()
+	parser := &valueParser{tuples: true, tupleSep: ":"}
+	newValues := func() ini.Recorder { return newParsedValues(parser) }
+	src := &source{rd: &ini.Reader{Separator: ".", True: "true"}}
+
+	tests := []struct {
+		out     outputOptions
+		indent  string
+		replace bool
+		escape  bool
+	}{
+		{outputOptions{}, "", false, false},
+		{outputOptions{single: true}, "  ", false, true},
+		{outputOptions{single: true, sorted: true}, "\t", false, false},
+		{outputOptions{dup: "first"}, "", false, false},
+		{outputOptions{dup: "last", single: true}, "  ", false, false},
+		{outputOptions{single: true}, "", true, false},
+	}
+	for _, tt := range tests {
+		// The output of the spill must be the same as that of merging in
+		// memory and encoding the result.
+		var merge func(dest, src ini.Recorder)
+		if tt.replace {
+			merge = replaceValues(identity)
+		}
+		values := newValues()
+		if path, err := readMerged(values, newValues, src.read, paths, 1, nil, merge); err != nil {
+			t.Fatalf("readMerged: %s: %v", path, err)
+		}
+		var want bytes.Buffer
+		enc := json.NewEncoder(&want)
+		enc.SetIndent("", tt.indent)
+		enc.SetEscapeHTML(tt.escape)
+		if err := enc.Encode(tt.out.output(values)); err != nil {
+			t.Fatal(err)
+		}
+
+		sp, err := newSpill(tt.escape)
+		if err != nil {
+			t.Fatalf("newSpill = %v", err)
+		}
+		if path, err := readMerged(newValues(), newValues, src.read, paths, 2, nil, sp.merge(tt.replace)); err != nil {
+			t.Fatalf("readMerged: %s: %v", path, err)
+		}
+		var got bytes.Buffer
+		if err := sp.writeJSON(&got, &tt.out, tt.indent); err != nil {
+			t.Fatalf("writeJSON = %v", err)
+		}
+		if err := sp.Close(); err != nil {
+			t.Errorf("Close = %v", err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("-spill with %+v, indent %q, replace %t:\ngot\n%s\nwant\n%s", tt.out, tt.indent, tt.replace, got.String(), want.String())
+		}
+	}
+}
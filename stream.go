@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// modeFlag is a flag.Value that selects one of a list of modes. It may be
+// given without a value to select the first mode.
+type modeFlag struct {
+	mode  *string
+	modes []string
+}
+
+func (f modeFlag) String() string {
+	if f.mode == nil {
+		return ""
+	}
+	return *f.mode
+}
+
+func (f modeFlag) Set(s string) error {
+	switch s {
+	case "true":
+		*f.mode = f.modes[0]
+		return nil
+	case "false":
+		*f.mode = ""
+		return nil
+	}
+	for _, m := range f.modes {
+		if s == m {
+			*f.mode = s
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid mode %+q: must be one of %s", s, strings.Join(f.modes, ", "))
+}
+
+func (f modeFlag) IsBoolFlag() bool {
+	return true
+}
+
+// stream records values in chunks, one per section or one per value, and
+// passes each chunk to emit as soon as it is complete, so that no more
+// than one chunk is held in memory. A stream is the recorder that inputs
+// are read into, and its wrapper tracks the input each value comes from,
+// so that values still pass through includes and any other wrappers
+// first. A section interrupted by an included file is emitted as more
+// than one chunk.
+type stream struct {
+	kv        bool
+	newValues func() ini.Recorder
+	emit      func(ini.Recorder)
+
+	cur     *cursor      // The cursor of the input being recorded.
+	values  ini.Recorder // The current chunk, if any.
+	at      *cursor      // The cursor of the input the chunk is from.
+	section int          // The section of that input the chunk is from.
+	err     error
+}
+
+// wrap returns a recorder that passes values on to dest, noting that they
+// are from the input at. at must track sections, which requires the
+// source to locate values.
+func (s *stream) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return streamed{Recorder: dest, s: s, at: at}
+}
+
+// Add adds a value to the current chunk, first emitting the chunk if the
+// value is from another input or section.
+func (s *stream) Add(key, value string) {
+	if s.err != nil {
+		return
+	}
+	at := s.cur
+	if s.values != nil && (s.at != at || at != nil && s.section != at.section) {
+		if s.flush() != nil {
+			return
+		}
+	}
+	if s.values == nil {
+		s.values, s.at = s.newValues(), at
+		if at != nil {
+			s.section = at.section
+		}
+	}
+	s.values.Add(key, value)
+	if s.kv {
+		s.flush()
+	}
+}
+
+// Err returns the first error encountered by s.
+func (s *stream) Err() error {
+	return s.err
+}
+
+// flush emits the current chunk, if any, and returns the first error
+// encountered by s.
+func (s *stream) flush() error {
+	if s.values == nil || s.err != nil {
+		return s.err
+	}
+	values := s.values
+	s.values = nil
+	if v, ok := values.(interface{ Err() error }); ok {
+		if s.err = v.Err(); s.err != nil {
+			return s.err
+		}
+	}
+	s.emit(values)
+	return nil
+}
+
+// streamed notes the input of each value before passing it on.
+type streamed struct {
+	ini.Recorder
+	s  *stream
+	at *cursor
+}
+
+func (r streamed) Add(key, value string) {
+	r.s.cur = r.at
+	r.Recorder.Add(key, value)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// readStream reads the first of texts with src into a stream, following
+// includes of the others, and returns the chunks emitted as JSON. The
+// stream's wrapper is added before those of src.
+func readStream(t *testing.T, src *source, kv bool, texts ...string) string {
+	t.Helper()
+	paths, done := tempFiles(t, texts...)
+	defer done()
+
+	var got []string
+	parser := &valueParser{}
+	st := &stream{
+		kv:        kv,
+		newValues: func() ini.Recorder { return newParsedValues(parser) },
+	}
+	st.emit = func(values ini.Recorder) {
+		got = append(got, outputString(t, &outputOptions{single: true}, values))
+	}
+	src.rd = &ini.Reader{Separator: ".", True: "true"}
+	src.wrap = append([]wrapper{st.wrap}, src.wrap...)
+	if src.include {
+		src.includePath = []string{filepath.Dir(paths[0])}
+		src.filters = append(src.filters, includeLines)
+	}
+	err := src.read(st, paths[0])
+	if err == nil {
+		err = st.flush()
+	}
+	if err != nil {
+		t.Fatalf("read(%q) = %v", texts[0], err)
+	}
+	return strings.Join(got, " ")
+}
+
+func TestStream(t *testing.T) {
+	const in = "a = 1\n[s]\nb = 2\nc = 3\n[t]\nd = 4\n[s]\ne = 5\n"
+	tests := []struct {
+		kv   bool
+		want []string
+	}{
+		{false, []string{`{"a":1}`, `{"s.b":2,"s.c":3}`, `{"t.d":4}`, `{"s.e":5}`}},
+		{true, []string{`{"a":1}`, `{"s.b":2}`, `{"s.c":3}`, `{"t.d":4}`, `{"s.e":5}`}},
+	}
+	for _, tt := range tests {
+		got := readStream(t, &source{locate: !tt.kv}, tt.kv, in)
+		if want := strings.Join(tt.want, " "); got != want {
+			t.Errorf("-stream kv=%t: got %s, want %s", tt.kv, got, want)
+		}
+	}
+}
+
+func TestStreamIncludes(t *testing.T) {
+	const (
+		in  = "[a]\nx = 1\ninclude = test2.ini\ny = 2\n"
+		inc = "[b]\nz = 3\n[c]\nw = 4\n"
+	)
+	tests := []struct {
+		kv   bool
+		want []string
+	}{
+		{false, []string{`{"a.x":1}`, `{"b.z":3}`, `{"c.w":4}`, `{"a.y":2}`}},
+		{true, []string{`{"a.x":1}`, `{"b.z":3}`, `{"c.w":4}`, `{"a.y":2}`}},
+	}
+	for _, tt := range tests {
+		src := &source{locate: !tt.kv, include: true}
+		got := readStream(t, src, tt.kv, in, inc)
+		if want := strings.Join(tt.want, " "); got != want {
+			t.Errorf("-stream kv=%t -I: got %s, want %s", tt.kv, got, want)
+		}
+	}
+
+	// Values still pass through the wrappers after the stream's.
+	rule, err := parseRenameRule("b=d", ".", ini.CaseSensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &source{locate: true, include: true, wrap: []wrapper{renameKeys([]renameRule{rule})}}
+	if got, want := readStream(t, src, false, in, inc), `{"a.x":1} {"d.z":3} {"c.w":4} {"a.y":2}`; got != want {
+		t.Errorf("-stream -I -rename b=d: got %s, want %s", got, want)
+	}
+}
+
+func TestModeFlag(t *testing.T) {
+	var mode string
+	f := modeFlag{mode: &mode, modes: []string{"section", "kv"}}
+	for _, tt := range []struct {
+		in, want string
+		ok       bool
+	}{
+		{"true", "section", true},
+		{"kv", "kv", true},
+		{"false", "", true},
+		{"section", "section", true},
+		{"lines", "section", false},
+	} {
+		err := f.Set(tt.in)
+		if (err == nil) != tt.ok || mode != tt.want {
+			t.Errorf("Set(%q) = %v, mode %q; want mode %q", tt.in, err, mode, tt.want)
+		}
+	}
+	if got := f.String(); got != "section" {
+		t.Errorf("String() = %q, want section", got)
+	}
+	if got := (modeFlag{}).String(); got != "" {
+		t.Errorf("String() of zero modeFlag = %q, want empty", got)
+	}
+}
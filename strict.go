@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
+
+// strictLines returns a function that returns a filter for the input
+// named by path that rejects malformed lines: lines with invalid UTF-8,
+// section headers that are unterminated, empty, or followed by text,
+// assignments without a key, and, unless bare is true, lines without '='
+// that are not a single word. Up to max problems, or all if max is 0,
+// are reported, each with its line and column. The input is read in full
+// before any of it is written, so that malformed lines are reported
+// instead of whatever error reading them would cause, and is otherwise
+// not modified.
+func strictLines(max int, bare bool) func(path string) filter {
+	return func(path string) filter {
+		return func(w io.Writer, r io.Reader) error {
+			p, err := ioutil.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			var problems []string
+			lines := strings.SplitAfter(string(p), "\n")
+			for n, line := range lines {
+				if max > 0 && len(problems) >= max {
+					break
+				}
+				line = strings.TrimRight(line, "\r\n")
+				if col, msg := malformed(line, bare); msg != "" {
+					problems = append(problems, fmt.Sprintf("%s:%d:%d: %s", path, n+1, col, msg))
+				}
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("malformed lines:\n  %s", strings.Join(problems, "\n  "))
+			}
+			_, err = w.Write(p)
+			return err
+		}
+	}
+}
+
+// malformed returns the column and a description of the problem with
+// line, if it is malformed.
+func malformed(line string, bare bool) (int, string) {
+	if !utf8.ValidString(line) {
+		for i, r := range line {
+			if r == utf8.RuneError {
+				if _, size := utf8.DecodeRuneInString(line[i:]); size == 1 {
+					return i + 1, "invalid UTF-8"
+				}
+			}
+		}
+	}
+
+	t := strings.TrimSpace(line)
+	start := strings.Index(line, t) + 1
+	switch {
+	case t == "" || t[0] == ';' || t[0] == '#':
+		return 0, ""
+	case t[0] == '[':
+		end := strings.IndexByte(t, ']')
+		switch {
+		case end < 0:
+			return start + len(t), "unterminated section header"
+		case strings.TrimSpace(t[1:end]) == "":
+			return start, "empty section name"
+		case end < len(t)-1:
+			return start + end + 1, "text after section header"
+		}
+		return 0, ""
+	}
+
+	i := strings.IndexByte(line, '=')
+	switch {
+	case i >= 0 && strings.TrimSpace(line[:i]) == "":
+		return i + 1, "assignment without a key"
+	case i < 0 && !bare && strings.ContainsAny(t, " \t"):
+		return start, "line is not an assignment, section header, or comment"
+	}
+	return 0, ""
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestStrictLines(t *testing.T) {
+	const good = "; comment\n# comment\n\n[s]\n  [ t.u ]  \nkey = value\nk=\nflag\r\nx = caf\xc3\xa9\n"
+	if got := runFilter(t, strictLines(0, false)("in.ini"), good); got != good {
+		t.Errorf("strictLines changed %q to %q", good, got)
+	}
+
+	const bad = "[s\n[ ]\n[s] x\n = 1\nnot a key\nok = 1\nv = \xff\n"
+	tests := []struct {
+		max  int
+		bare bool
+		want []string
+	}{
+		{0, false, []string{
+			"in.ini:1:3: unterminated section header",
+			"in.ini:2:1: empty section name",
+			"in.ini:3:4: text after section header",
+			"in.ini:4:2: assignment without a key",
+			"in.ini:5:1: line is not an assignment, section header, or comment",
+			"in.ini:7:5: invalid UTF-8",
+		}},
+		{0, true, []string{
+			"in.ini:1:3: unterminated section header",
+			"in.ini:2:1: empty section name",
+			"in.ini:3:4: text after section header",
+			"in.ini:4:2: assignment without a key",
+			"in.ini:7:5: invalid UTF-8",
+		}},
+		{2, false, []string{
+			"in.ini:1:3: unterminated section header",
+			"in.ini:2:1: empty section name",
+		}},
+	}
+	for _, tt := range tests {
+		rc := strictLines(tt.max, tt.bare)("in.ini").apply(strings.NewReader(bad))
+		_, err := ioutil.ReadAll(rc)
+		rc.Close()
+		want := "malformed lines:\n  " + strings.Join(tt.want, "\n  ")
+		if errString(err) != want {
+			t.Errorf("strictLines(%d, %t) = %v, want %s", tt.max, tt.bare, err, want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the functions available to -template templates. As in
+// sprig, the value a function operates on is its last argument, so that
+// it may be piped in (e.g., {{ .name | replace "-" "_" | upper }}). String
+// functions accept any value, using its text.
+var templateFuncs = template.FuncMap{
+	"lower":      func(s interface{}) string { return strings.ToLower(text(s)) },
+	"upper":      func(s interface{}) string { return strings.ToUpper(text(s)) },
+	"title":      func(s interface{}) string { return strings.Title(text(s)) },
+	"trim":       func(s interface{}) string { return strings.TrimSpace(text(s)) },
+	"trimPrefix": func(prefix string, s interface{}) string { return strings.TrimPrefix(text(s), prefix) },
+	"trimSuffix": func(suffix string, s interface{}) string { return strings.TrimSuffix(text(s), suffix) },
+	"replace":    func(old, repl string, s interface{}) string { return strings.Replace(text(s), old, repl, -1) },
+	"contains":   func(substr string, s interface{}) bool { return strings.Contains(text(s), substr) },
+	"hasPrefix":  func(prefix string, s interface{}) bool { return strings.HasPrefix(text(s), prefix) },
+	"hasSuffix":  func(suffix string, s interface{}) bool { return strings.HasSuffix(text(s), suffix) },
+	"repeat":     func(n int, s interface{}) string { return strings.Repeat(text(s), n) },
+	"split":      func(sep string, s interface{}) []string { return strings.Split(text(s), sep) },
+	"join":       templateJoin,
+	"quote":      func(s interface{}) string { return fmt.Sprintf("%q", text(s)) },
+	"squote":     func(s interface{}) string { return "'" + text(s) + "'" },
+	"indent":     func(n int, s interface{}) string { return indent(n, text(s)) },
+	"nindent":    func(n int, s interface{}) string { return "\n" + indent(n, text(s)) },
+	"default":    templateDefault,
+	"keys":       templateKeys,
+	"list":       func(v ...interface{}) []interface{} { return v },
+	"env":        os.Getenv,
+	"toJSON":     templateJSON,
+}
+
+// text returns the text of the template value v. Lists of one value have
+// the text of that value, since keys are recorded as lists without
+// -single.
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) == 1 {
+			return text(v[0])
+		}
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// templateJoin joins the text of each element of list with sep.
+func templateJoin(sep string, list interface{}) string {
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice {
+		return text(list)
+	}
+	elems := make([]string, rv.Len())
+	for i := range elems {
+		elems[i] = text(rv.Index(i).Interface())
+	}
+	return strings.Join(elems, sep)
+}
+
+// templateDefault returns v, or def if v is empty.
+func templateDefault(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	if rv := reflect.ValueOf(v); (rv.Kind() == reflect.String || rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.Len() == 0 {
+		return def
+	}
+	return v
+}
+
+// templateKeys returns the sorted keys of the object m.
+func templateKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// templateJSON returns v encoded as compact JSON.
+func templateJSON(v interface{}) (string, error) {
+	p, err := json.Marshal(v)
+	return string(p), err
+}
+
+// indent returns s with each line indented by n spaces.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+// readTemplate parses the template in the file named by path.
+func readTemplate(path string) (*template.Template, error) {
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(p))
+}
+
+// templateExtension returns the file extension of output written with the
+// template in the file named by path: its own extension, without a
+// trailing .tmpl or .tpl, or txt.
+func templateExtension(path string) string {
+	name := filepath.Base(path)
+	for _, ext := range []string{".tmpl", ".tpl"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if ext := filepath.Ext(name); ext != "" {
+		return ext[1:]
+	}
+	return "txt"
+}
+
+// templateEncoder writes values by executing a template with them as its
+// data. Objects are passed to the template as maps, so their members may
+// be referred to by key (e.g., {{ .db.host }}).
+type templateEncoder struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func (e *templateEncoder) Encode(v interface{}) error {
+	g, err := generic(v)
+	if err != nil {
+		return err
+	}
+	return e.tmpl.Execute(e.w, g)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	const tmpl = `{{ .name | replace "-" "_" | upper }} {{ .db.host }} {{ .db.host | lower }}:{{ .db.port | trim }}
+{{ range keys .db }}{{ . }};{{ end }}
+{{ join "," .tags }} {{ .missing | default "none" }} {{ .db | toJSON }}
+{{ .name | quote }} {{ .name | squote }} {{ .name | title | trimPrefix "My" }}
+{{ "a\nb" | indent 2 }}{{ .name | nindent 1 }}
+{{ if .name | hasPrefix "my" }}yes{{ end }} {{ split "-" .name | len }} {{ repeat 2 "ab" }}
+`
+	const want = `MY_APP [db.local] db.local:5432
+host;port;
+a,b none {"host":["db.local"],"port":[5432]}
+"my-app" 'my-app' -App
+  a
+  b
+ my-app
+yes 2 abab
+`
+	values := readValues(t, &source{}, &valueParser{}, "name = my-app\ntags = a\ntags = b\n[db]\nhost = db.local\nport = 5432\n")
+	out := &outputOptions{nested: true, sep: "."}
+
+	paths, done := tempFiles(t, tmpl)
+	defer done()
+	tp, err := readTemplate(paths[0])
+	if err != nil {
+		t.Fatalf("readTemplate: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := (&templateEncoder{w: &buf, tmpl: tp}).Encode(out.output(values)); err != nil {
+		t.Fatalf("Encode = %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("template output:\n%s\nwant:\n%s", got, want)
+	}
+
+	bad := filepath.Join(filepath.Dir(paths[0]), "bad.tmpl")
+	if err := ioutil.WriteFile(bad, []byte("{{ .x "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readTemplate(bad); err == nil {
+		t.Errorf("readTemplate(%s) = nil, want an error", bad)
+	}
+	if _, err := readTemplate(filepath.Join(os.TempDir(), "ini2json-no-such.tmpl")); err == nil {
+		t.Errorf("readTemplate of a missing file = nil, want an error")
+	}
+}
+
+func TestTemplateExtension(t *testing.T) {
+	for in, want := range map[string]string{
+		"a/nginx.conf.tmpl": "conf",
+		"env.sh.tpl":        "sh",
+		"out.yaml":          "yaml",
+		"plain.tmpl":        "txt",
+		"plain":             "txt",
+	} {
+		if got := templateExtension(in); got != want {
+			t.Errorf("templateExtension(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// transformer passes each value read to a shell command that may rewrite
+// or drop it. The command is started once, when the first value is read,
+// and is written a line of JSON for each value, {"key": KEY, "value":
+// VALUE}, to which it must reply with a line of its own: an object whose
+// "key" and "value", if present, replace those of the value, or null to
+// drop the value. Values are passed to it one at a time, even when inputs
+// are read concurrently.
+type transformer struct {
+	cmd string
+
+	mu     sync.Mutex
+	in     io.WriteCloser
+	out    *bufio.Reader
+	wait   func() error
+	stderr bytes.Buffer
+	err    error // Set once the command fails, for every later value.
+}
+
+func newTransformer(cmd string) *transformer {
+	return &transformer{cmd: cmd}
+}
+
+// wrap is a wrapper passing the values recorded to dest through t.
+func (t *transformer) wrap(dest ini.Recorder, _ *cursor) ini.Recorder {
+	return transformValues{Recorder: dest, t: t, errs: new([]string)}
+}
+
+// start starts the command.
+func (t *transformer) start() error {
+	cmd := shellCommand(t.cmd)
+	cmd.Stderr = &t.stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	t.in, t.out, t.wait = in, bufio.NewReader(out), cmd.Wait
+	return nil
+}
+
+// transform returns the key and value that the command replaces key and
+// value with, and whether it keeps them.
+func (t *transformer) transform(key, value string) (string, string, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err == nil && t.wait == nil {
+		t.err = t.start()
+	}
+	if t.err != nil {
+		return "", "", false, t.err
+	}
+
+	req, err := json.Marshal(struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}{key, value})
+	if err != nil {
+		return "", "", false, err
+	}
+	if _, err = t.in.Write(append(req, '\n')); err == nil {
+		var line []byte
+		if line, err = t.out.ReadBytes('\n'); err == nil {
+			return parseTransform(line, key, value)
+		}
+	}
+
+	// The command has exited or closed its output, so report how it
+	// exited instead of the error writing to or reading from it.
+	t.in.Close()
+	if err = t.wait(); err == nil {
+		err = fmt.Errorf("command exited")
+	}
+	t.wait = func() error { return nil }
+	if msg := strings.TrimSpace(t.stderr.String()); msg != "" {
+		err = fmt.Errorf("%v: %s", err, msg)
+	}
+	t.err = err
+	return "", "", false, err
+}
+
+// parseTransform returns the key and value of a reply from the command to
+// the value of key, and whether the value is kept.
+func parseTransform(line []byte, key, value string) (string, string, bool, error) {
+	var reply *struct {
+		Key   *string `json:"key"`
+		Value *string `json:"value"`
+	}
+	if err := json.Unmarshal(line, &reply); err != nil {
+		return "", "", false, fmt.Errorf("invalid reply %q: must be an object with a string key and value, or null", bytes.TrimSpace(line))
+	}
+	if reply == nil {
+		return "", "", false, nil
+	}
+	if reply.Key != nil {
+		key = *reply.Key
+	}
+	if reply.Value != nil {
+		value = *reply.Value
+	}
+	return key, value, true, nil
+}
+
+// close closes the input of the command, if it was started, and waits for
+// it to exit.
+func (t *transformer) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.wait == nil {
+		return nil
+	}
+	t.in.Close()
+	err := t.wait()
+	t.wait = func() error { return nil }
+	return err
+}
+
+// transformValues passes each value added to it through a transformer
+// before recording it in the Recorder it wraps.
+type transformValues struct {
+	ini.Recorder
+	t    *transformer
+	errs *[]string
+}
+
+func (v transformValues) Add(key, value string) {
+	k, val, keep, err := v.t.transform(key, value)
+	if err != nil {
+		*v.errs = append(*v.errs, fmt.Sprintf("%s: %v", key, err))
+		return
+	}
+	if keep {
+		v.Recorder.Add(k, val)
+	}
+}
+
+// Err returns an error listing every value that could not be transformed.
+func (v transformValues) Err() error {
+	if len(*v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unable to transform values:\n  %s", strings.Join(*v.errs, "\n  "))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	ini "go.spiff.io/go-ini"
+)
+
+func TestTransform(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("transform commands are written for sh")
+	}
+	const script = `while IFS= read -r line; do
+		case "$line" in
+		*'"key":"drop"'*) echo null ;;
+		*'"key":"s.secret"'*) echo '{"value":"***"}' ;;
+		*'"key":"old"'*) echo '{"key":"new","value":"2"}' ;;
+		*) echo '{}' ;;
+		esac
+	done`
+	tr := newTransformer(script)
+	src := &source{wrap: []wrapper{tr.wrap}}
+	got := readString(t, src, &valueParser{}, "a = 1\ndrop = x\nold = 1\n[s]\nsecret = hunter2\n")
+	if want := `{"a":[1],"new":[2],"s.secret":["***"]}`; got != want {
+		t.Errorf("-transform: got %s, want %s", got, want)
+	}
+	if err := tr.close(); err != nil {
+		t.Errorf("close = %v", err)
+	}
+	if err := newTransformer("true").close(); err != nil {
+		t.Errorf("close of an unstarted transformer = %v", err)
+	}
+
+	tests := []struct {
+		cmd, want string
+	}{
+		{`while read -r line; do echo nope; done`, `a: invalid reply "nope": must be an object with a string key and value, or null`},
+		{`while read -r line; do echo '{"value": 1}'; done`, `a: invalid reply "{\"value\": 1}": must be an object with a string key and value, or null`},
+		{`read -r line; echo failed >&2; exit 2`, "a: exit status 2: failed"},
+		{`read -r line`, "a: command exited"},
+	}
+	for _, tt := range tests {
+		paths, done := tempFiles(t, "a = 1\n")
+		tr := newTransformer(tt.cmd)
+		src := &source{rd: &ini.Reader{Separator: ".", True: "true"}, wrap: []wrapper{tr.wrap}}
+		err := src.read(newParsedValues(&valueParser{}), paths[0])
+		done()
+		tr.close()
+		if want := "unable to transform values:\n  " + tt.want; errString(err) != want {
+			t.Errorf("-transform %q = %v, want %s", tt.cmd, err, want)
+		}
+	}
+}
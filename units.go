@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"go.spiff.io/ini2json/convert"
+)
+
+// sizeUnits maps size suffixes to the number of bytes they stand for.
+// Suffixes without an 'i' are powers of 1000 and those with one are
+// powers of 1024.
+var sizeUnits = map[string]int64{
+	"B": 1,
+	"k": 1e3, "K": 1e3, "kB": 1e3, "KB": 1e3,
+	"M": 1e6, "MB": 1e6,
+	"G": 1e9, "GB": 1e9,
+	"T": 1e12, "TB": 1e12,
+	"P": 1e15, "PB": 1e15,
+	"E": 1e18, "EB": 1e18,
+	"Ki": 1 << 10, "KiB": 1 << 10,
+	"Mi": 1 << 20, "MiB": 1 << 20,
+	"Gi": 1 << 30, "GiB": 1 << 30,
+	"Ti": 1 << 40, "TiB": 1 << 40,
+	"Pi": 1 << 50, "PiB": 1 << 50,
+	"Ei": 1 << 60, "EiB": 1 << 60,
+}
+
+// unitValue is a number with a duration or size unit.
+type unitValue struct {
+	value     interface{} // The number as written.
+	unit      string      // The unit as written.
+	canonical interface{} // The number of nanoseconds or bytes.
+}
+
+// parseUnits parses s as a duration, such as 30s or 1h30m, or as a size,
+// such as 512k or 10Gi. Durations use the units of time.ParseDuration,
+// so m is minutes and M is a size. Compound durations are written in
+// nanoseconds.
+func parseUnits(s string) (unitValue, bool) {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.'); i++ {
+		if s[i] != '.' {
+			digits++
+		}
+	}
+	if digits == 0 || i == len(s) {
+		return unitValue{}, false
+	}
+
+	num, unit := s[:i], s[i:]
+	value := convert.ParseValue(strings.TrimPrefix(num, "+"))
+	switch value.(type) {
+	case *big.Int, *convert.Float:
+	default:
+		return unitValue{}, false
+	}
+
+	if mult, ok := sizeUnits[unit]; ok {
+		f, _, err := big.ParseFloat(num, 10, 256, big.ToNearestEven)
+		if err != nil {
+			return unitValue{}, false
+		}
+		f.Mul(f, new(big.Float).SetInt64(mult))
+		var canonical interface{} = (*convert.Float)(f)
+		if f.IsInt() {
+			canonical, _ = f.Int(nil)
+		}
+		return unitValue{value: value, unit: unit, canonical: canonical}, true
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return unitValue{}, false
+	}
+	ns := big.NewInt(int64(d))
+	switch unit {
+	case "ns", "us", "µs", "μs", "ms", "s", "m", "h":
+		return unitValue{value: value, unit: unit, canonical: ns}, true
+	}
+	return unitValue{value: ns, unit: "ns", canonical: ns}, true
+}
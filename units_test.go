@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestUnits(t *testing.T) {
+	in := "a = 30s\nb = 1h30m\nc = 512k\nd = 1.5Gi\ne = 10x\n"
+	tests := []struct {
+		units string
+		want  string
+	}{
+		{"number", `{"a":[30000000000],"b":[5400000000000],"c":[512000],"d":[1610612736],"e":["10x"]}`},
+		{"object", `{"a":[{"value":30,"unit":"s"}],"b":[{"value":5400000000000,"unit":"ns"}],` +
+			`"c":[{"value":512,"unit":"k"}],"d":[{"value":1.5,"unit":"Gi"}],"e":["10x"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.units, func(t *testing.T) {
+			got := readString(t, &source{}, &valueParser{units: tt.units}, in)
+			if got != tt.want {
+				t.Errorf("-units=%s:\ngot  %s\nwant %s", tt.units, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandPaths returns paths with each glob pattern replaced by the files
+// matching it and, if recursive is set, each directory replaced by the
+// files in it and its subdirectories, in lexical order. Files and
+// directories whose names start with '.' are skipped when walking a
+// directory. A file named more than once is only included the first
+// time.
+func expandPaths(paths []string, recursive bool) ([]string, error) {
+	var (
+		expanded []string
+		seen     = map[string]bool{}
+	)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			expanded = append(expanded, path)
+		}
+	}
+	for _, path := range paths {
+		if path == "-" || isURL(path) {
+			add(path)
+			continue
+		}
+
+		var matches []string
+		if _, err := os.Stat(path); err != nil && strings.ContainsAny(path, "*?[") {
+			var err error
+			if matches, err = glob(path); err != nil {
+				return nil, err
+			} else if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %+q", path)
+			}
+		} else {
+			matches = []string{path}
+		}
+
+		for _, match := range matches {
+			fi, err := os.Stat(match)
+			if err != nil || !fi.IsDir() {
+				add(match)
+				continue
+			} else if !recursive {
+				return nil, fmt.Errorf("%v is a directory: use -R to convert the files in it", match)
+			}
+			err = filepath.Walk(match, func(p string, fi os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if p != match && strings.HasPrefix(fi.Name(), ".") {
+					if fi.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if fi.Mode().IsRegular() {
+					add(p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// glob returns the names of the files matching pattern, as for
+// filepath.Glob, except that a "**" element matches any number of
+// directories, including none, and that names starting with '.' are
+// skipped unless the pattern names them.
+func glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	elems := strings.Split(filepath.ToSlash(pattern), "/")
+	root := 0
+	for root < len(elems) && !strings.ContainsAny(elems[root], "*?[") {
+		root++
+	}
+	dir := filepath.FromSlash(strings.Join(elems[:root], "/"))
+	if dir == "" {
+		dir = "."
+	} else if strings.HasPrefix(pattern, "/") && root == 1 {
+		dir = string(filepath.Separator)
+	}
+
+	dotted := false
+	for _, e := range elems[root:] {
+		dotted = dotted || strings.HasPrefix(e, ".")
+	}
+
+	var matches []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if fi.IsDir() && strings.HasPrefix(fi.Name(), ".") && !dotted {
+			return filepath.SkipDir
+		}
+		ok, err := matchElems(elems[root:], strings.Split(filepath.ToSlash(rel), "/"))
+		if ok {
+			matches = append(matches, p)
+		}
+		return err
+	})
+	return matches, err
+}
+
+// matchElems returns whether the path elements name match the pattern
+// elements pat, where a "**" element matches any number of elements. As
+// with -R, elements starting with '.' are only matched by pattern
+// elements that also start with one.
+func matchElems(pat, name []string) (bool, error) {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if i > 0 && strings.HasPrefix(name[i-1], ".") {
+					return false, nil
+				}
+				if ok, err := matchElems(pat[1:], name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		if strings.HasPrefix(name[0], ".") && !strings.HasPrefix(pat[0], ".") {
+			return false, nil
+		}
+		if ok, err := filepath.Match(pat[0], name[0]); !ok || err != nil {
+			return false, err
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandPathsDotNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ini2json-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{
+		"a.ini",
+		"sub/b.ini",
+		"sub/.c.ini",
+		".git/d.ini",
+		"sub/.hidden/e.ini",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{"a.ini", "sub/b.ini"}},
+		{"**/*.ini", []string{"a.ini", "sub/b.ini"}},
+		{"**/.*.ini", []string{"sub/.c.ini"}},
+		{".git/**/*.ini", []string{".git/d.ini"}},
+	}
+	for _, tt := range tests {
+		in, recursive := filepath.Join(dir, filepath.FromSlash(tt.in)), tt.in == ""
+		got, err := expandPaths([]string{in}, recursive)
+		if err != nil {
+			t.Errorf("expandPaths(%q) = %v", tt.in, err)
+			continue
+		}
+		for i, p := range got {
+			got[i], _ = filepath.Rel(dir, p)
+			got[i] = filepath.ToSlash(got[i])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandPaths(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	ini "go.spiff.io/go-ini"
+)
+
+// coercions reports values that the parser records as something other
+// than a string, for -warn. Each is written to w as a warning or, if fail
+// is true, rejected.
+type coercions struct {
+	parser *valueParser
+	w      io.Writer
+	fail   bool
+}
+
+// wrap returns a recorder that checks each value for coercion before
+// passing it on to dest.
+func (c *coercions) wrap(dest ini.Recorder, at *cursor) ini.Recorder {
+	return coerced{Recorder: dest, c: c, at: at, errs: new([]string)}
+}
+
+type coerced struct {
+	ini.Recorder
+	c    *coercions
+	at   *cursor
+	errs *[]string
+}
+
+func (r coerced) Add(key, value string) {
+	// Keys without a value and values given a type with -T or a type
+	// suffix are recorded as asked, so they are not coercions.
+	if _, typ := r.c.parser.typeOf(key); typ != "" || r.at.valueless() {
+		r.Recorder.Add(key, value)
+		return
+	}
+	if msg := r.c.check(key, value); msg != "" {
+		msg = fmt.Sprintf("%v: %s", r.at.Location(), msg)
+		if r.c.fail {
+			*r.errs = append(*r.errs, msg)
+			return
+		}
+		fmt.Fprintf(r.c.w, "warning: %s\n", msg)
+	}
+	r.Recorder.Add(key, value)
+}
+
+// check returns a message describing how value is coerced when recorded
+// for key, or "" if it is recorded as a string or rejected.
+func (c *coercions) check(key, value string) string {
+	name, vals, _, err := c.parser.parseList(key, value)
+	if err != nil {
+		// Rejected values are reported when they are recorded.
+		return ""
+	}
+	typ := "array"
+	if len(vals) == 1 {
+		typ = jsonType(vals[0])
+	}
+	if typ == "string" {
+		return ""
+	}
+	return fmt.Sprintf("%s: %q parsed as %s", name, value, typ)
+}
+
+// Err returns an error listing every value rejected for coercion.
+func (r coerced) Err() error {
+	if len(*r.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("coerced values:\n  %s", strings.Join(*r.errs, "\n  "))
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+// testPath matches the path of the file written by readValues.
+var testPath = regexp.MustCompile(`\S*test\.ini`)
+
+func TestCoercionWarnings(t *testing.T) {
+	const in = "flag\nempty =\nver = 1.10\nport = 80\nsize:int = 2\nname = x\nlist = [1]\n"
+	parser := &valueParser{typedKeys: true, overrides: []typeOverride{{pattern: "port", typ: "int"}}}
+	var buf bytes.Buffer
+	c := &coercions{parser: parser, w: &buf}
+	src := &source{wrap: []wrapper{c.wrap}, locate: true}
+	got := readString(t, src, parser, in)
+	if want := `{"flag":[true],"empty":[""],"ver":[1.1],"port":[80],"size":[2],"name":["x"],"list":[[1]]}`; got != want {
+		t.Errorf("read(%q) = %s, want %s", in, got, want)
+	}
+
+	const want = "warning: test.ini:3: ver: \"1.10\" parsed as float\nwarning: test.ini:7: list: \"[1]\" parsed as array\n"
+	if got := testPath.ReplaceAllString(buf.String(), "test.ini"); got != want {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// watchedEnv is set in the environment of conversions run by watch, so
+// that they convert their inputs once and write to standard output.
+const watchedEnv = "INI2JSON_WATCHED"
+
+// stamp identifies the version of a file seen by watch.
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// watch polls paths every interval and, each time one of them changes,
+// runs the command again to convert them. Running each conversion as its
+// own process means that an invalid edit is reported without stopping
+// the watch. Output is written to outPath, replacing it atomically, or to
+// standard output if outPath is empty. watch only returns if it cannot
+// run the command at all.
+func watch(paths []string, outPath string, interval time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]stamp{}
+	for first := true; ; first = false {
+		if !first {
+			time.Sleep(interval)
+		}
+
+		changed, missing := first, false
+		for _, path := range paths {
+			fi, err := os.Stat(path)
+			if err != nil {
+				missing = true
+				continue
+			}
+			st := stamp{size: fi.Size(), modTime: fi.ModTime()}
+			if seen[path] != st {
+				seen[path], changed = st, true
+			}
+		}
+		if !changed || missing {
+			continue
+		}
+
+		var out bytes.Buffer
+		cmd := exec.Command(exe, os.Args[1:]...)
+		cmd.Env = append(os.Environ(), watchedEnv+"=1")
+		cmd.Stdout, cmd.Stderr = &out, os.Stderr
+		if err := cmd.Run(); err != nil {
+			if _, ok := err.(*exec.ExitError); !ok {
+				return err
+			}
+			log.Printf("conversion failed: %v", err)
+			continue
+		}
+
+		if outPath == "" {
+			if _, err := out.WriteTo(os.Stdout); err != nil {
+				return err
+			}
+		} else if err := replaceFile(outPath, out.Bytes()); err != nil {
+			log.Printf("unable to write %v: %v", outPath, err)
+		}
+	}
+}
+
+// replaceFile atomically replaces the file at path with one containing p.
+func replaceFile(path string, p []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(p); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceFile(t *testing.T) {
+	paths, done := tempFiles(t, "old\n")
+	defer done()
+	path := paths[0]
+
+	if err := replaceFile(path, []byte("new\n")); err != nil {
+		t.Fatalf("replaceFile(%s) = %v", path, err)
+	}
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(p); got != "new\n" {
+		t.Errorf("%s = %q, want %q", path, got, "new\n")
+	}
+
+	// A file that cannot be replaced is left as it was, without
+	// leaving the temporary file behind.
+	dir := filepath.Join(filepath.Dir(path), "dir")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "x"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFile(dir, []byte("new\n")); err == nil {
+		t.Errorf("replaceFile(%s) = nil, want an error", dir)
+	}
+	names, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range names {
+		if fi.Name() != "test.ini" && fi.Name() != "dir" {
+			t.Errorf("replaceFile left %s behind", fi.Name())
+		}
+	}
+}